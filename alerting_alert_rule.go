@@ -63,6 +63,16 @@ type RelativeTimeRange struct {
 	To   time.Duration `json:"to"`
 }
 
+// AlertRules fetches and returns all the alert rules of the org.
+func (c *Client) AlertRules() ([]AlertRule, error) {
+	rules := make([]AlertRule, 0)
+	err := c.request("GET", "/api/v1/provisioning/alert-rules", nil, nil, &rules)
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
 // AlertRule fetches a single alert rule, identified by its UID.
 func (c *Client) AlertRule(uid string) (AlertRule, error) {
 	path := fmt.Sprintf("/api/v1/provisioning/alert-rules/%s", uid)
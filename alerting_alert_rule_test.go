@@ -22,6 +22,19 @@ func TestAlertRules(t *testing.T) {
 		}
 	})
 
+	t.Run("list alert rules succeeds", func(t *testing.T) {
+		client := gapiTestTools(t, 200, "["+getAlertRuleJSON+"]")
+
+		alertRules, err := client.AlertRules()
+
+		if err != nil {
+			t.Error(err)
+		}
+		if len(alertRules) != 1 || alertRules[0].UID != "123abcd" {
+			t.Errorf("unexpected alert rules: %+v", alertRules)
+		}
+	})
+
 	t.Run("get alert rule group succeeds", func(t *testing.T) {
 		client := gapiTestTools(t, 200, getAlertRuleGroupJSON)
 
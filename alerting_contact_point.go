@@ -51,7 +51,7 @@ func (c *Client) ContactPoint(uid string) (ContactPoint, error) {
 			return p, nil
 		}
 	}
-	return ContactPoint{}, fmt.Errorf("contact point with uid %s not found", uid)
+	return ContactPoint{}, fmt.Errorf("contact point with uid %s: %w", uid, ErrNotFound)
 }
 
 // NewContactPoint creates a new contact point.
@@ -58,6 +58,16 @@ func TestContactPoints(t *testing.T) {
 		}
 	})
 
+	t.Run("get missing contact point returns not found", func(t *testing.T) {
+		client := gapiTestTools(t, 200, getContactPointsJSON)
+
+		_, err := client.ContactPoint("does-not-exist")
+
+		if !IsNotFound(err) {
+			t.Errorf("expected not found error, got %v", err)
+		}
+	})
+
 	t.Run("get non-existent contact point fails", func(t *testing.T) {
 		client := gapiTestTools(t, 200, getContactPointsJSON)
 
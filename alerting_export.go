@@ -0,0 +1,100 @@
+package gapi
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// Formats of the alerting exports. Exports are in the format of Grafana's alerting file provisioning,
+// in YAML by default.
+const (
+	ExportFormatYAML = "yaml"
+	ExportFormatJSON = "json"
+	ExportFormatHCL  = "hcl"
+)
+
+// AlertRuleExportOptions selects the alert rules exported by ExportAlertRules. All the rules are exported by default.
+type AlertRuleExportOptions struct {
+	Format     string
+	FolderUIDs []string
+	// Group selects a rule group of the folder, when a single folder is selected.
+	Group   string
+	RuleUID string
+}
+
+// ContactPointExportOptions selects the contact points exported by ExportContactPoints.
+type ContactPointExportOptions struct {
+	Format string
+	// Name selects the contact point with this name. All the contact points are exported by default.
+	Name string
+	// Decrypt exports the secret settings, instead of redacting them. It requires the permission to read secrets.
+	Decrypt bool
+}
+
+func exportQuery(format string) url.Values {
+	query := url.Values{}
+	if format != "" {
+		query.Set("format", format)
+	}
+	return query
+}
+
+func (c *Client) export(path string, query url.Values) ([]byte, error) {
+	var data []byte
+	err := c.request("GET", path, query, nil, &data)
+	return data, err
+}
+
+// ExportAlertRules exports alert rules grouped by rule group, in the alerting file provisioning format.
+func (c *Client) ExportAlertRules(opts AlertRuleExportOptions) ([]byte, error) {
+	query := exportQuery(opts.Format)
+	for _, uid := range opts.FolderUIDs {
+		query.Add("folderUid", uid)
+	}
+	if opts.Group != "" {
+		query.Set("group", opts.Group)
+	}
+	if opts.RuleUID != "" {
+		query.Set("ruleUid", opts.RuleUID)
+	}
+	return c.export("/api/v1/provisioning/alert-rules/export", query)
+}
+
+// ExportAlertRuleGroup exports a rule group, in the alerting file provisioning format.
+func (c *Client) ExportAlertRuleGroup(folderUID, group, format string) ([]byte, error) {
+	path := fmt.Sprintf("/api/v1/provisioning/folder/%s/rule-groups/%s/export", folderUID, group)
+	return c.export(path, exportQuery(format))
+}
+
+// ExportAlertRule exports an alert rule within its rule group, in the alerting file provisioning format.
+func (c *Client) ExportAlertRule(uid, format string) ([]byte, error) {
+	return c.export(fmt.Sprintf("/api/v1/provisioning/alert-rules/%s/export", uid), exportQuery(format))
+}
+
+// ExportContactPoints exports contact points, in the alerting file provisioning format.
+func (c *Client) ExportContactPoints(opts ContactPointExportOptions) ([]byte, error) {
+	query := exportQuery(opts.Format)
+	if opts.Name != "" {
+		query.Set("name", opts.Name)
+	}
+	if opts.Decrypt {
+		query.Set("decrypt", strconv.FormatBool(opts.Decrypt))
+	}
+	return c.export("/api/v1/provisioning/contact-points/export", query)
+}
+
+// ExportNotificationPolicyTree exports the notification policy tree, in the alerting file provisioning format.
+func (c *Client) ExportNotificationPolicyTree(format string) ([]byte, error) {
+	return c.export("/api/v1/provisioning/policies/export", exportQuery(format))
+}
+
+// ExportMuteTimings exports all the mute timings, in the alerting file provisioning format.
+func (c *Client) ExportMuteTimings(format string) ([]byte, error) {
+	return c.export("/api/v1/provisioning/mute-timings/export", exportQuery(format))
+}
+
+// ExportMuteTiming exports a mute timing, in the alerting file provisioning format.
+func (c *Client) ExportMuteTiming(name, format string) ([]byte, error) {
+	return c.export(fmt.Sprintf("/api/v1/provisioning/mute-timings/%s/export", name), exportQuery(format))
+}
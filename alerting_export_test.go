@@ -0,0 +1,78 @@
+package gapi
+
+import (
+	"testing"
+)
+
+const exportedMuteTimingsYAML = `apiVersion: 1
+muteTimes:
+    - orgId: 1
+      name: weekends
+      time_intervals:
+        - weekdays:
+            - saturday
+            - sunday
+`
+
+func TestExportMuteTimings(t *testing.T) {
+	client := gapiTestTools(t, 200, exportedMuteTimingsYAML)
+	requests := recordRequests(t, client)
+
+	data, err := client.ExportMuteTimings("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != exportedMuteTimingsYAML {
+		t.Errorf("expected the export to be returned as is; got:\n%s", data)
+	}
+	if uri := (*requests)[0].uri; uri != "/api/v1/provisioning/mute-timings/export" {
+		t.Errorf("unexpected request: %s", uri)
+	}
+}
+
+func TestExportQueries(t *testing.T) {
+	var client *Client
+	exports := map[string]func() ([]byte, error){
+		"/api/v1/provisioning/alert-rules/export?folderUid=a&folderUid=b&format=json": func() ([]byte, error) {
+			return client.ExportAlertRules(AlertRuleExportOptions{Format: ExportFormatJSON, FolderUIDs: []string{"a", "b"}})
+		},
+		"/api/v1/provisioning/folder/prod/rule-groups/cpu%20usage/export?format=hcl": func() ([]byte, error) {
+			return client.ExportAlertRuleGroup("prod", "cpu usage", ExportFormatHCL)
+		},
+		"/api/v1/provisioning/alert-rules/high-cpu/export": func() ([]byte, error) {
+			return client.ExportAlertRule("high-cpu", "")
+		},
+		"/api/v1/provisioning/contact-points/export?decrypt=true&name=slack": func() ([]byte, error) {
+			return client.ExportContactPoints(ContactPointExportOptions{Name: "slack", Decrypt: true})
+		},
+		"/api/v1/provisioning/policies/export?format=yaml": func() ([]byte, error) {
+			return client.ExportNotificationPolicyTree(ExportFormatYAML)
+		},
+		"/api/v1/provisioning/mute-timings/weekends/export": func() ([]byte, error) {
+			return client.ExportMuteTiming("weekends", "")
+		},
+	}
+	calls := []mockServerCall{}
+	for range exports {
+		calls = append(calls, mockServerCall{200, "{}"})
+	}
+	client = gapiTestToolsFromCalls(t, calls)
+	requests := recordRequests(t, client)
+
+	for expected, export := range exports {
+		if _, err := export(); err != nil {
+			t.Fatal(err)
+		}
+		if uri := (*requests)[len(*requests)-1].uri; uri != expected {
+			t.Errorf("expected request %s; got: %s", expected, uri)
+		}
+	}
+}
+
+func TestExportError(t *testing.T) {
+	client := gapiTestTools(t, 404, `{"message": "not found"}`)
+
+	if _, err := client.ExportAlertRule("missing", ""); !IsNotFound(err) {
+		t.Errorf("expected a not found error; got: %v", err)
+	}
+}
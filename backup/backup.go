@@ -0,0 +1,401 @@
+// Package backup backs up the resources of a Grafana org to a directory tree of JSON files,
+// and restores them to the same or another Grafana instance.
+//
+// A backup directory is laid out as follows, each file holding one resource:
+//
+//	manifest.json
+//	folders/<uid>.json
+//	datasources/<uid>.json
+//	library-panels/<uid>.json
+//	dashboards/<folder uid, or "general">/<uid>.json
+//	alerting/templates/<name>.json
+//	alerting/mute-timings/<name>.json
+//	alerting/contact-points/<uid>.json
+//	alerting/notification-policies.json
+//	alerting/rule-groups/<folder uid>/<group>.json
+//	teams/<name>.json
+//	playlists/<uid>.json
+//
+// Resources are wrapped in an envelope holding the version of the format and their kind, e.g.
+// {"version": 1, "kind": "folder", "spec": {...}}. The files are deterministic: backing up the same
+// resources twice produces the same files, without IDs or timestamps, so backups can be kept in version control.
+//
+// Data sources are backed up without their secrets, which can't be read through the API, and the secrets
+// of contact points as redacted by Grafana: they have to be set again after restoring to another instance.
+package backup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+// FormatVersion is the version of the format of the backup files.
+const FormatVersion = 1
+
+// Kinds of backed up resources.
+const (
+	KindFolder               = "folder"
+	KindDataSource           = "datasource"
+	KindLibraryPanel         = "library-panel"
+	KindDashboard            = "dashboard"
+	KindMessageTemplate      = "message-template"
+	KindMuteTiming           = "mute-timing"
+	KindContactPoint         = "contact-point"
+	KindNotificationPolicies = "notification-policies"
+	KindRuleGroup            = "rule-group"
+	KindTeam                 = "team"
+	KindPlaylist             = "playlist"
+)
+
+// generalFolder is the directory of the dashboards which aren't in a folder.
+const generalFolder = "general"
+
+// directories are the files and directories written by Backup, replaced when backing up again
+// so that deleted resources don't linger.
+var directories = []string{
+	"manifest.json",
+	"folders",
+	"datasources",
+	"library-panels",
+	"dashboards",
+	"alerting",
+	"teams",
+	"playlists",
+}
+
+// Manifest describes a backup. It's stored in manifest.json.
+type Manifest struct {
+	Version int `json:"version"`
+	// Counts are the numbers of resources backed up, by kind.
+	Counts map[string]int `json:"counts"`
+}
+
+type folderSpec struct {
+	UID   string `json:"uid"`
+	Title string `json:"title"`
+}
+
+type dashboardSpec struct {
+	FolderUID string                 `json:"folderUid"`
+	Dashboard map[string]interface{} `json:"dashboard"`
+}
+
+type libraryPanelSpec struct {
+	UID         string                 `json:"uid"`
+	Name        string                 `json:"name"`
+	FolderUID   string                 `json:"folderUid"`
+	Description string                 `json:"description,omitempty"`
+	Model       map[string]interface{} `json:"model"`
+}
+
+type teamSpec struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+	// Members are the logins of the members of the team, as user IDs differ between instances.
+	Members []string `json:"members"`
+}
+
+type backuper struct {
+	client   *gapi.Client
+	dir      string
+	manifest *Manifest
+}
+
+// Backup writes the resources of the org of the client to dir, replacing a previous backup in dir. The backup is
+// written to a temporary directory in dir first, and only replaces the previous one once it's complete: if it
+// fails, the previous backup is left untouched.
+func Backup(client *gapi.Client, dir string) (*Manifest, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+	tmp, err := ioutil.TempDir(dir, ".tmp-backup-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	b := &backuper{
+		client:   client,
+		dir:      tmp,
+		manifest: &Manifest{Version: FormatVersion, Counts: map[string]int{}},
+	}
+	steps := []func() error{
+		b.folders,
+		b.dataSources,
+		b.libraryPanels,
+		b.dashboards,
+		b.messageTemplates,
+		b.muteTimings,
+		b.contactPoints,
+		b.notificationPolicies,
+		b.ruleGroups,
+		b.teams,
+		b.playlists,
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeJSON(filepath.Join(tmp, "manifest.json"), b.manifest); err != nil {
+		return nil, err
+	}
+
+	if err := replaceBackup(tmp, dir); err != nil {
+		return nil, err
+	}
+	return b.manifest, nil
+}
+
+// replaceBackup moves the files and directories of the backup in tmp to dir, replacing the ones of the previous
+// backup. The manifest is moved last, so that dir isn't seen as a complete backup if moving the others fails.
+func replaceBackup(tmp, dir string) error {
+	if err := os.Remove(filepath.Join(dir, "manifest.json")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := len(directories) - 1; i >= 0; i-- {
+		name := directories[i]
+		if err := os.RemoveAll(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+		if _, err := os.Stat(filepath.Join(tmp, name)); os.IsNotExist(err) {
+			continue
+		}
+		if err := os.Rename(filepath.Join(tmp, name), filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *backuper) write(kind string, spec interface{}, path ...string) error {
+	b.manifest.Counts[kind]++
+	return writeResource(filepath.Join(append([]string{b.dir}, path...)...), kind, spec)
+}
+
+func (b *backuper) folders() error {
+	folders, err := b.client.Folders()
+	if err != nil {
+		return err
+	}
+
+	for _, folder := range folders {
+		spec := folderSpec{UID: folder.UID, Title: folder.Title}
+		if err := b.write(KindFolder, spec, "folders", fileName(folder.UID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *backuper) dataSources() error {
+	dataSources, err := b.client.DataSources()
+	if err != nil {
+		return err
+	}
+
+	for _, listed := range dataSources {
+		// The list doesn't include all the fields of the data sources.
+		ds, err := b.client.DataSourceByUID(listed.UID)
+		if err != nil {
+			return err
+		}
+		ds.ID = 0
+		ds.OrgID = 0
+		if err := b.write(KindDataSource, ds, "datasources", fileName(ds.UID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *backuper) libraryPanels() error {
+	panels, err := b.client.LibraryPanels()
+	if err != nil {
+		return err
+	}
+
+	for _, panel := range panels {
+		spec := libraryPanelSpec{
+			UID:         panel.UID,
+			Name:        panel.Name,
+			FolderUID:   panel.FolderUID,
+			Description: panel.Description,
+			Model:       panel.Model,
+		}
+		if spec.FolderUID == "" {
+			spec.FolderUID = panel.Meta.FolderUID
+		}
+		if err := b.write(KindLibraryPanel, spec, "library-panels", fileName(panel.UID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *backuper) dashboards() error {
+	dashboards, err := b.client.Dashboards()
+	if err != nil {
+		return err
+	}
+
+	for _, result := range dashboards {
+		dashboard, err := b.client.DashboardByUID(result.UID)
+		if err != nil {
+			return err
+		}
+		delete(dashboard.Model, "id")
+		delete(dashboard.Model, "version")
+
+		folder := result.FolderUID
+		if folder == "" {
+			folder = generalFolder
+		}
+		spec := dashboardSpec{FolderUID: result.FolderUID, Dashboard: dashboard.Model}
+		if err := b.write(KindDashboard, spec, "dashboards", dirName(folder), fileName(result.UID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *backuper) messageTemplates() error {
+	templates, err := b.client.MessageTemplates()
+	if err != nil {
+		return err
+	}
+
+	for _, template := range templates {
+		if err := b.write(KindMessageTemplate, template, "alerting", "templates", fileName(template.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *backuper) muteTimings() error {
+	timings, err := b.client.MuteTimings()
+	if err != nil {
+		return err
+	}
+
+	for _, timing := range timings {
+		timing.Provenance = ""
+		if err := b.write(KindMuteTiming, timing, "alerting", "mute-timings", fileName(timing.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *backuper) contactPoints() error {
+	points, err := b.client.ContactPoints()
+	if err != nil {
+		return err
+	}
+
+	for _, point := range points {
+		point.Provenance = ""
+		if err := b.write(KindContactPoint, point, "alerting", "contact-points", fileName(point.UID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *backuper) notificationPolicies() error {
+	tree, err := b.client.NotificationPolicyTree()
+	if err != nil {
+		return err
+	}
+
+	tree.Provenance = ""
+	return b.write(KindNotificationPolicies, tree, "alerting", "notification-policies.json")
+}
+
+func (b *backuper) ruleGroups() error {
+	rules, err := b.client.AlertRules()
+	if err != nil {
+		return err
+	}
+
+	// The rules are listed without the intervals of their groups, which are fetched one by one.
+	type groupKey struct{ folderUID, title string }
+	seen := map[groupKey]bool{}
+	var groups []groupKey
+	for _, rule := range rules {
+		key := groupKey{rule.FolderUID, rule.RuleGroup}
+		if !seen[key] {
+			seen[key] = true
+			groups = append(groups, key)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].folderUID != groups[j].folderUID {
+			return groups[i].folderUID < groups[j].folderUID
+		}
+		return groups[i].title < groups[j].title
+	})
+
+	for _, key := range groups {
+		group, err := b.client.AlertRuleGroup(key.folderUID, key.title)
+		if err != nil {
+			return err
+		}
+		for i := range group.Rules {
+			rule := &group.Rules[i]
+			rule.ID = 0
+			rule.OrgID = 0
+			rule.Provenance = ""
+			rule.Updated = time.Time{}
+		}
+		path := []string{"alerting", "rule-groups", dirName(key.folderUID), fileName(key.title)}
+		if err := b.write(KindRuleGroup, group, path...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *backuper) teams() error {
+	return b.client.ForEachTeam("", gapi.PageOptions{}, func(team *gapi.Team) error {
+		members, err := b.client.TeamMembers(team.ID)
+		if err != nil {
+			return err
+		}
+
+		spec := teamSpec{Name: team.Name, Email: team.Email, Members: []string{}}
+		for _, member := range members {
+			spec.Members = append(spec.Members, member.Login)
+		}
+		sort.Strings(spec.Members)
+
+		return b.write(KindTeam, spec, "teams", fileName(team.Name))
+	})
+}
+
+func (b *backuper) playlists() error {
+	playlists, err := b.client.Playlists()
+	if err != nil {
+		return err
+	}
+
+	for _, listed := range playlists {
+		// The list doesn't include the items of the playlists.
+		playlist, err := b.client.Playlist(listed.QueryID())
+		if err != nil {
+			return err
+		}
+		name := fileName(playlist.QueryID())
+		playlist.ID = 0
+		if err := b.write(KindPlaylist, playlist, "playlists", name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,352 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+	"github.com/grafana/grafana-api-golang-client/gapitest"
+)
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// readTree returns the contents of the files under dir, by relative path.
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	files := map[string]string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		files[rel] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// populate creates one resource of each kind backed up, plus a dashboard in the General folder.
+func populate(t *testing.T, client *gapi.Client) {
+	t.Helper()
+
+	if _, err := client.CreateUser(gapi.User{Login: "jane", Email: "jane@example.com", Password: "password"}); err != nil {
+		t.Fatal(err)
+	}
+	folder, err := client.NewFolder("Production", "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.NewDataSource(&gapi.DataSource{UID: "prom", Name: "Prometheus", Type: "prometheus", URL: "http://prometheus:9090"}); err != nil {
+		t.Fatal(err)
+	}
+	panel, err := client.NewLibraryPanel(gapi.LibraryPanel{
+		UID:       "cpu",
+		Name:      "CPU",
+		FolderUID: folder.UID,
+		Model:     map[string]interface{}{"type": "timeseries", "title": "CPU"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.NewDashboard(gapi.Dashboard{FolderUID: folder.UID, Model: map[string]interface{}{
+		"uid":    "services",
+		"title":  "Services",
+		"panels": []interface{}{map[string]interface{}{"libraryPanel": map[string]interface{}{"uid": panel.UID, "name": panel.Name}}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.NewDashboard(gapi.Dashboard{Model: map[string]interface{}{"uid": "home", "title": "Home & Welcome"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.SetMessageTemplate("footer", `{{ define "footer" }}Sent by <b>Grafana</b>{{ end }}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.NewMuteTiming(&gapi.MuteTiming{Name: "weekends", TimeIntervals: []gapi.TimeInterval{{Weekdays: []gapi.WeekdayRange{"saturday:sunday"}}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.NewContactPoint(&gapi.ContactPoint{UID: "slack", Name: "slack", Type: "slack", Settings: map[string]interface{}{"url": "http://slack"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SetNotificationPolicyTree(&gapi.NotificationPolicyTree{
+		Receiver: "default-email",
+		Routes:   []gapi.SpecificPolicy{{Receiver: "slack", MuteTimeIntervals: []string{"weekends"}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	rule := gapi.AlertRule{
+		UID:       "high-cpu",
+		Title:     "High CPU",
+		Condition: "A",
+		Data:      []*gapi.AlertQuery{{RefID: "A", DatasourceUID: "prom", Model: map[string]interface{}{"expr": "cpu > 0.9"}}},
+		For:       "5m",
+	}
+	if err := client.SetAlertRuleGroup(gapi.RuleGroup{Title: "cpu", FolderUID: folder.UID, Interval: 120, Rules: []gapi.AlertRule{rule}}); err != nil {
+		t.Fatal(err)
+	}
+
+	teamID, err := client.AddTeam("SRE", "sre@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jane, err := client.UserByEmail("jane")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.AddTeamMember(teamID, jane.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.NewPlaylist(gapi.Playlist{
+		UID:      "rotation",
+		Name:     "Rotation",
+		Interval: "5m",
+		Items:    []gapi.PlaylistItem{{Type: "dashboard_by_uid", Value: "services", Order: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBackup(t *testing.T) {
+	server := gapitest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	populate(t, client)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	manifest, err := Backup(client, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]int{
+		KindFolder:               1,
+		KindDataSource:           1,
+		KindLibraryPanel:         1,
+		KindDashboard:            2,
+		KindMessageTemplate:      1,
+		KindMuteTiming:           1,
+		KindContactPoint:         2, // Including the default one.
+		KindNotificationPolicies: 1,
+		KindRuleGroup:            1,
+		KindTeam:                 1,
+		KindPlaylist:             1,
+	}
+	if !reflect.DeepEqual(manifest.Counts, expected) {
+		t.Errorf("unexpected counts: %v", manifest.Counts)
+	}
+
+	dashboard := &dashboardSpec{}
+	if err := readResource(filepath.Join(dir, "dashboards", "prod", "services.json"), KindDashboard, dashboard); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := dashboard.Dashboard["id"]; exists || dashboard.FolderUID != "prod" {
+		t.Errorf("unexpected dashboard: %+v", dashboard)
+	}
+	for _, path := range []string{
+		"dashboards/general/home.json",
+		"alerting/rule-groups/prod/cpu.json",
+		"alerting/notification-policies.json",
+		"teams/SRE.json",
+	} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// Backing up again gives the same files, and removes the ones of deleted resources.
+	files := readTree(t, dir)
+	if err := client.DeletePlaylist("rotation"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Backup(client, dir); err != nil {
+		t.Fatal(err)
+	}
+	again := readTree(t, dir)
+	delete(files, filepath.Join("playlists", "rotation.json"))
+	delete(files, "manifest.json")
+	delete(again, "manifest.json")
+	if !reflect.DeepEqual(files, again) {
+		t.Errorf("backing up again changed the files:\n%v\n%v", files, again)
+	}
+}
+
+func TestBackupFailure(t *testing.T) {
+	server := gapitest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	populate(t, client)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	if _, err := Backup(client, dir); err != nil {
+		t.Fatal(err)
+	}
+	files := readTree(t, dir)
+
+	// The backup fails after the folders, dashboards and alerting resources are fetched.
+	failing, err := gapi.New(server.URL, gapi.Config{CallMiddlewares: []gapi.CallMiddleware{
+		func(next gapi.CallHandler) gapi.CallHandler {
+			return func(ctx context.Context, call *gapi.Call) error {
+				if call.Path == "/api/teams/search" {
+					return errors.New("connection refused")
+				}
+				return next(ctx, call)
+			}
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.DeletePlaylist("rotation"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Backup(failing, dir); err == nil {
+		t.Fatal("expected the backup to fail")
+	}
+
+	if again := readTree(t, dir); !reflect.DeepEqual(files, again) {
+		t.Errorf("the failed backup changed the previous one:\n%v\n%v", files, again)
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if !contains(directories, entry.Name()) {
+			t.Errorf("unexpected file left in the backup: %s", entry.Name())
+		}
+	}
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRestore(t *testing.T) {
+	source := gapitest.NewServer()
+	defer source.Close()
+	populate(t, source.Client())
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	if _, err := Backup(source.Client(), dir); err != nil {
+		t.Fatal(err)
+	}
+
+	target := gapitest.NewServer()
+	defer target.Close()
+	client := target.Client()
+	if _, err := client.CreateUser(gapi.User{Login: "jane", Email: "jane@example.com", Password: "password"}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Restore(client, dir, RestoreOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Actions) != 13 || report.Actions[0] != (Action{Op: OpCreate, Kind: KindFolder, Name: "prod"}) {
+		t.Errorf("unexpected dry run report:\n%s", report)
+	}
+	if folders, err := client.Folders(); err != nil || len(folders) != 0 {
+		t.Errorf("the dry run created folders: %v, %v", folders, err)
+	}
+
+	if report, err = Restore(client, dir, RestoreOptions{}); err != nil {
+		t.Fatalf("%v\n%s", err, report)
+	}
+	if len(report.Warnings) != 0 {
+		t.Errorf("unexpected warnings:\n%s", report)
+	}
+
+	// UIDs are preserved.
+	folder, err := client.FolderByUID("prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dashboard, err := client.DashboardByUID("services")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dashboard.FolderID != folder.ID {
+		t.Errorf("expected the dashboard in folder %d; got: %d", folder.ID, dashboard.FolderID)
+	}
+	if _, err := client.LibraryPanelByUID("cpu"); err != nil {
+		t.Error(err)
+	}
+	group, err := client.AlertRuleGroup("prod", "cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.Interval != 120 || group.Rules[0].UID != "high-cpu" {
+		t.Errorf("unexpected rule group: %+v", group)
+	}
+	if _, err := client.ContactPoint("slack"); err != nil {
+		t.Error(err)
+	}
+	playlist, err := client.Playlist("rotation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(playlist.Items) != 1 {
+		t.Errorf("unexpected playlist: %+v", playlist)
+	}
+	teams, err := client.SearchTeam("SRE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(teams.Teams) != 1 || teams.Teams[0].MemberCount != 1 {
+		t.Errorf("unexpected teams: %+v", teams.Teams)
+	}
+
+	// Restoring again updates the resources.
+	report, err = Restore(client, dir, RestoreOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range report.Actions {
+		if action.Op != OpUpdate {
+			t.Errorf("expected only updates:\n%s", report)
+			break
+		}
+	}
+}
+
+func TestRestoreNotABackup(t *testing.T) {
+	server := gapitest.NewServer()
+	defer server.Close()
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	if _, err := Restore(server.Client(), dir, RestoreOptions{}); err == nil {
+		t.Error("expected an error restoring an empty directory")
+	}
+}
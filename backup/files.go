@@ -0,0 +1,118 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// envelope wraps each resource written to a backup.
+type envelope struct {
+	Version int         `json:"version"`
+	Kind    string      `json:"kind"`
+	Spec    interface{} `json:"spec"`
+}
+
+// writeJSON writes v as indented JSON. Map keys are sorted by encoding/json, so that
+// backing up the same resources always produces the same files.
+func writeJSON(path string, v interface{}) error {
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0600)
+}
+
+func writeResource(path, kind string, spec interface{}) error {
+	return writeJSON(path, envelope{Version: FormatVersion, Kind: kind, Spec: spec})
+}
+
+// readResources decodes the resources of a kind stored in the files under dir, in the order of their paths.
+// newSpec returns the value to decode the spec of a file into, and fn is called after each one is decoded.
+func readResources(dir, kind string, newSpec func() interface{}, fn func(spec interface{}) error) error {
+	var paths []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(path, ".json") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		spec := newSpec()
+		if err := readResource(path, kind, spec); err != nil {
+			return err
+		}
+		if err := fn(spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func readResource(path, kind string, spec interface{}) error {
+	var raw json.RawMessage
+	e := envelope{Spec: &raw}
+	if err := readJSON(path, &e); err != nil {
+		return err
+	}
+	if e.Version < 1 || e.Version > FormatVersion {
+		return fmt.Errorf("%s: unsupported format version %d", path, e.Version)
+	}
+	if e.Kind != kind {
+		return fmt.Errorf("%s: expected a %s, found a %s", path, kind, e.Kind)
+	}
+	if err := json.Unmarshal(raw, spec); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// fileName returns the name of the file storing a resource, named after its UID or name.
+func fileName(name string) string {
+	return dirName(name) + ".json"
+}
+
+// dirName escapes a UID or name to be used as the name of a file or directory,
+// e.g. escaping slashes and a leading dot.
+func dirName(name string) string {
+	escaped := url.PathEscape(name)
+	if escaped == "" || strings.HasPrefix(escaped, ".") {
+		escaped = "%2E" + strings.TrimPrefix(escaped, ".")
+	}
+	return escaped
+}
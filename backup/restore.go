@@ -0,0 +1,417 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+// Operations of Action.
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+)
+
+// RestoreOptions configures a restore.
+type RestoreOptions struct {
+	// DryRun reports what would be restored without changing anything.
+	DryRun bool
+}
+
+// Report lists what a restore changed, or would change in a dry run.
+type Report struct {
+	Actions []Action
+	// Warnings are about resources which were only partly restored, e.g. team members which don't exist.
+	Warnings []string
+}
+
+// Action is the creation or update of a resource by a restore.
+type Action struct {
+	Op   string
+	Kind string
+	// Name identifies the resource: its UID, or its name for resources without UID.
+	Name string
+}
+
+// String returns the actions and warnings of the report, one per line.
+func (r *Report) String() string {
+	lines := make([]string, 0, len(r.Actions)+len(r.Warnings))
+	for _, action := range r.Actions {
+		lines = append(lines, fmt.Sprintf("%s %s %s", action.Op, action.Kind, action.Name))
+	}
+	for _, warning := range r.Warnings {
+		lines = append(lines, "warning: "+warning)
+	}
+	return strings.Join(lines, "\n")
+}
+
+type restorer struct {
+	client *gapi.Client
+	dir    string
+	dryRun bool
+	report *Report
+}
+
+// Restore recreates the resources backed up in dir in the org of the client, keeping their UIDs.
+// Existing resources are updated. Resources are restored in dependency order: folders and data sources
+// before the library panels, dashboards and alert rules using them, and alerting templates and
+// mute timings before the contact points and notification policies using them.
+func Restore(client *gapi.Client, dir string, opts RestoreOptions) (*Report, error) {
+	manifest := &Manifest{}
+	if err := readManifest(filepath.Join(dir, "manifest.json"), manifest); err != nil {
+		return nil, err
+	}
+
+	r := &restorer{client: client, dir: dir, dryRun: opts.DryRun, report: &Report{}}
+	steps := []func() error{
+		r.folders,
+		r.dataSources,
+		r.libraryPanels,
+		r.dashboards,
+		r.messageTemplates,
+		r.muteTimings,
+		r.contactPoints,
+		r.notificationPolicies,
+		r.ruleGroups,
+		r.teams,
+		r.playlists,
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return r.report, err
+		}
+	}
+	return r.report, nil
+}
+
+func readManifest(path string, manifest *Manifest) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return fmt.Errorf("%s isn't a backup: manifest.json not found", filepath.Dir(path))
+	}
+	if err := readJSON(path, manifest); err != nil {
+		return err
+	}
+	if manifest.Version < 1 || manifest.Version > FormatVersion {
+		return fmt.Errorf("unsupported backup format version %d", manifest.Version)
+	}
+	return nil
+}
+
+// exists returns whether a resource exists, given the error returned when fetching it.
+func exists(err error) (bool, error) {
+	if err == nil {
+		return true, nil
+	}
+	if gapi.IsNotFound(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// apply records the creation or update of a resource, and makes it unless in a dry run.
+func (r *restorer) apply(kind, name string, exists bool, create, update func() error) error {
+	action := Action{Op: OpCreate, Kind: kind, Name: name}
+	apply := create
+	if exists {
+		action.Op = OpUpdate
+		apply = update
+	}
+	r.report.Actions = append(r.report.Actions, action)
+
+	if r.dryRun {
+		return nil
+	}
+	if err := apply(); err != nil {
+		return fmt.Errorf("%s %s %s: %w", action.Op, kind, name, err)
+	}
+	return nil
+}
+
+func (r *restorer) warn(format string, args ...interface{}) {
+	r.report.Warnings = append(r.report.Warnings, fmt.Sprintf(format, args...))
+}
+
+func (r *restorer) read(kind string, newSpec func() interface{}, fn func(spec interface{}) error, path ...string) error {
+	return readResources(filepath.Join(append([]string{r.dir}, path...)...), kind, newSpec, fn)
+}
+
+func (r *restorer) folders() error {
+	newSpec := func() interface{} { return &folderSpec{} }
+	return r.read(KindFolder, newSpec, func(spec interface{}) error {
+		folder := spec.(*folderSpec)
+		_, err := r.client.FolderByUID(folder.UID)
+		found, err := exists(err)
+		if err != nil {
+			return err
+		}
+
+		return r.apply(KindFolder, folder.UID, found, func() error {
+			_, err := r.client.NewFolder(folder.Title, folder.UID)
+			return err
+		}, func() error {
+			return r.client.UpdateFolder(folder.UID, folder.Title)
+		})
+	}, "folders")
+}
+
+func (r *restorer) dataSources() error {
+	newSpec := func() interface{} { return &gapi.DataSource{} }
+	return r.read(KindDataSource, newSpec, func(spec interface{}) error {
+		ds := spec.(*gapi.DataSource)
+		existing, err := r.client.DataSourceByUID(ds.UID)
+		found, err := exists(err)
+		if err != nil {
+			return err
+		}
+		if found && existing.ReadOnly {
+			r.warn("data source %s is provisioned and can't be updated", ds.UID)
+			return nil
+		}
+
+		return r.apply(KindDataSource, ds.UID, found, func() error {
+			_, err := r.client.NewDataSource(ds)
+			return err
+		}, func() error {
+			ds.ID = existing.ID
+			return r.client.UpdateDataSourceByUID(ds)
+		})
+	}, "datasources")
+}
+
+func (r *restorer) libraryPanels() error {
+	newSpec := func() interface{} { return &libraryPanelSpec{} }
+	return r.read(KindLibraryPanel, newSpec, func(spec interface{}) error {
+		panel := spec.(*libraryPanelSpec)
+		existing, err := r.client.LibraryPanelByUID(panel.UID)
+		found, err := exists(err)
+		if err != nil {
+			return err
+		}
+
+		restored := gapi.LibraryPanel{
+			UID:         panel.UID,
+			Name:        panel.Name,
+			FolderUID:   panel.FolderUID,
+			Description: panel.Description,
+			Model:       panel.Model,
+		}
+		return r.apply(KindLibraryPanel, panel.UID, found, func() error {
+			_, err := r.client.NewLibraryPanel(restored)
+			return err
+		}, func() error {
+			restored.Kind = existing.Kind
+			restored.Version = existing.Version
+			_, err := r.client.PatchLibraryPanel(panel.UID, restored)
+			return err
+		})
+	}, "library-panels")
+}
+
+func (r *restorer) dashboards() error {
+	newSpec := func() interface{} { return &dashboardSpec{} }
+	return r.read(KindDashboard, newSpec, func(spec interface{}) error {
+		dashboard := spec.(*dashboardSpec)
+		uid, _ := dashboard.Dashboard["uid"].(string)
+		_, err := r.client.DashboardByUID(uid)
+		found, err := exists(err)
+		if err != nil {
+			return err
+		}
+
+		save := func() error {
+			_, err := r.client.NewDashboard(gapi.Dashboard{
+				Model:     dashboard.Dashboard,
+				FolderUID: dashboard.FolderUID,
+				Overwrite: true,
+				Message:   "Restored from backup",
+			})
+			return err
+		}
+		return r.apply(KindDashboard, uid, found, save, save)
+	}, "dashboards")
+}
+
+func (r *restorer) messageTemplates() error {
+	newSpec := func() interface{} { return &gapi.AlertingMessageTemplate{} }
+	return r.read(KindMessageTemplate, newSpec, func(spec interface{}) error {
+		template := spec.(*gapi.AlertingMessageTemplate)
+		_, err := r.client.MessageTemplate(template.Name)
+		found, err := exists(err)
+		if err != nil {
+			return err
+		}
+
+		set := func() error {
+			return r.client.SetMessageTemplate(template.Name, template.Template)
+		}
+		return r.apply(KindMessageTemplate, template.Name, found, set, set)
+	}, "alerting", "templates")
+}
+
+func (r *restorer) muteTimings() error {
+	newSpec := func() interface{} { return &gapi.MuteTiming{} }
+	return r.read(KindMuteTiming, newSpec, func(spec interface{}) error {
+		timing := spec.(*gapi.MuteTiming)
+		_, err := r.client.MuteTiming(timing.Name)
+		found, err := exists(err)
+		if err != nil {
+			return err
+		}
+
+		return r.apply(KindMuteTiming, timing.Name, found, func() error {
+			return r.client.NewMuteTiming(timing)
+		}, func() error {
+			return r.client.UpdateMuteTiming(timing)
+		})
+	}, "alerting", "mute-timings")
+}
+
+func (r *restorer) contactPoints() error {
+	// Contact points can only be fetched all at once.
+	points, err := r.client.ContactPoints()
+	if err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for _, point := range points {
+		existing[point.UID] = true
+	}
+
+	newSpec := func() interface{} { return &gapi.ContactPoint{} }
+	return r.read(KindContactPoint, newSpec, func(spec interface{}) error {
+		point := spec.(*gapi.ContactPoint)
+		return r.apply(KindContactPoint, point.UID, existing[point.UID], func() error {
+			_, err := r.client.NewContactPoint(point)
+			return err
+		}, func() error {
+			return r.client.UpdateContactPoint(point)
+		})
+	}, "alerting", "contact-points")
+}
+
+func (r *restorer) notificationPolicies() error {
+	path := filepath.Join(r.dir, "alerting", "notification-policies.json")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	tree := &gapi.NotificationPolicyTree{}
+	if err := readResource(path, KindNotificationPolicies, tree); err != nil {
+		return err
+	}
+	// The notification policy tree always exists.
+	set := func() error {
+		return r.client.SetNotificationPolicyTree(tree)
+	}
+	return r.apply(KindNotificationPolicies, "", true, set, set)
+}
+
+func (r *restorer) ruleGroups() error {
+	newSpec := func() interface{} { return &gapi.RuleGroup{} }
+	return r.read(KindRuleGroup, newSpec, func(spec interface{}) error {
+		group := spec.(*gapi.RuleGroup)
+		_, err := r.client.AlertRuleGroup(group.FolderUID, group.Title)
+		found, err := exists(err)
+		if err != nil {
+			return err
+		}
+
+		set := func() error {
+			return r.client.SetAlertRuleGroup(*group)
+		}
+		return r.apply(KindRuleGroup, group.FolderUID+"/"+group.Title, found, set, set)
+	}, "alerting", "rule-groups")
+}
+
+func (r *restorer) teams() error {
+	newSpec := func() interface{} { return &teamSpec{} }
+	return r.read(KindTeam, newSpec, func(spec interface{}) error {
+		team := spec.(*teamSpec)
+		existing, err := r.findTeam(team.Name)
+		if err != nil {
+			return err
+		}
+
+		id := int64(0)
+		if existing != nil {
+			id = existing.ID
+		}
+		err = r.apply(KindTeam, team.Name, existing != nil, func() error {
+			id, err = r.client.AddTeam(team.Name, team.Email)
+			return err
+		}, func() error {
+			return r.client.UpdateTeam(id, team.Name, team.Email)
+		})
+		if err != nil || r.dryRun {
+			return err
+		}
+
+		return r.teamMembers(id, team)
+	}, "teams")
+}
+
+func (r *restorer) findTeam(name string) (*gapi.Team, error) {
+	result, err := r.client.SearchTeam(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, team := range result.Teams {
+		if team.Name == name {
+			return team, nil
+		}
+	}
+	return nil, nil
+}
+
+// teamMembers adds the members of a backed up team which aren't already members.
+// Users are looked up by login, and must already exist.
+func (r *restorer) teamMembers(id int64, team *teamSpec) error {
+	members, err := r.client.TeamMembers(id)
+	if err != nil {
+		return err
+	}
+	isMember := map[string]bool{}
+	for _, member := range members {
+		isMember[member.Login] = true
+	}
+
+	for _, login := range team.Members {
+		if isMember[login] {
+			continue
+		}
+		user, err := r.client.UserByEmail(login)
+		if gapi.IsNotFound(err) {
+			r.warn("team %s: user %s not found", team.Name, login)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := r.client.AddTeamMember(id, user.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *restorer) playlists() error {
+	newSpec := func() interface{} { return &gapi.Playlist{} }
+	return r.read(KindPlaylist, newSpec, func(spec interface{}) error {
+		playlist := spec.(*gapi.Playlist)
+		_, err := r.client.Playlist(playlist.UID)
+		found, err := exists(err)
+		if err != nil {
+			return err
+		}
+
+		return r.apply(KindPlaylist, playlist.UID, found, func() error {
+			_, err := r.client.NewPlaylist(*playlist)
+			return err
+		}, func() error {
+			return r.client.UpdatePlaylist(*playlist)
+		})
+	}, "playlists")
+}
@@ -0,0 +1,153 @@
+// Package cassette records HTTP interactions with a Grafana instance into fixture files,
+// and replays them in tests.
+//
+// Both the Recorder and the Replayer are http.RoundTrippers, to be used as the transport of
+// the HTTP client given to the Grafana API client:
+//
+//	recorder := cassette.NewRecorder(nil)
+//	client, err := gapi.New(url, gapi.Config{APIKey: key, Client: &http.Client{Transport: recorder}})
+//	// ... make calls against a real Grafana instance ...
+//	err = recorder.Cassette().Save("testdata/dashboards.json")
+//
+//	c, err := cassette.Load("testdata/dashboards.json")
+//	client, err := gapi.New(url, gapi.Config{Client: &http.Client{Transport: cassette.NewReplayer(c)}})
+//
+// Secrets are scrubbed from recorded headers and JSON bodies before they are stored.
+package cassette
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/grafana/grafana-api-golang-client/internal/redact"
+)
+
+// Cassette is a list of recorded HTTP interactions.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded HTTP request.
+type Request struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Query  string      `json:"query,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response is a recorded HTTP response.
+type Response struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// scrubbedHeaders lists the canonical names of headers whose values are never recorded.
+var scrubbedHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"Set-Cookie":    true,
+	"X-Api-Key":     true,
+}
+
+const scrubbed = "[REDACTED]"
+
+// Load reads a cassette from a file.
+func Load(path string) (*Cassette, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cassette{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Save writes the cassette to a file.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// ScrubHeader returns a copy of the header, without the values of authentication headers.
+func ScrubHeader(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+
+	scrubbedHeader := make(http.Header, len(h))
+	for k, v := range h {
+		if scrubbedHeaders[http.CanonicalHeaderKey(k)] {
+			scrubbedHeader[k] = []string{scrubbed}
+			continue
+		}
+		scrubbedHeader[k] = append([]string(nil), v...)
+	}
+	return scrubbedHeader
+}
+
+// ScrubBody returns the body with the values of secret JSON fields replaced.
+func ScrubBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	return redact.JSON(body)
+}
+
+// newRequest records a request, scrubbing its secrets.
+func newRequest(req *http.Request, body []byte) Request {
+	return Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  req.URL.RawQuery,
+		Header: ScrubHeader(req.Header),
+		Body:   ScrubBody(body),
+	}
+}
+
+// matches reports whether the recorded request matches the given one on method, path, query and body.
+// The query parameters' order doesn't matter, and JSON bodies are compared semantically.
+func (r Request) matches(other Request) bool {
+	if r.Method != other.Method || r.Path != other.Path {
+		return false
+	}
+
+	q1, err1 := url.ParseQuery(r.Query)
+	q2, err2 := url.ParseQuery(other.Query)
+	if err1 != nil || err2 != nil || q1.Encode() != q2.Encode() {
+		return false
+	}
+
+	return equalBodies(r.Body, other.Body)
+}
+
+func equalBodies(b1, b2 string) bool {
+	if strings.TrimSpace(b1) == strings.TrimSpace(b2) {
+		return true
+	}
+
+	var v1, v2 interface{}
+	if json.Unmarshal([]byte(b1), &v1) != nil || json.Unmarshal([]byte(b2), &v2) != nil {
+		return false
+	}
+
+	d1, err1 := json.Marshal(v1)
+	d2, err2 := json.Marshal(v2)
+	return err1 == nil && err2 == nil && string(d1) == string(d2)
+}
@@ -0,0 +1,136 @@
+package cassette
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/api/auth/keys":
+			fmt.Fprint(w, `{"id":1,"name":"key-name","key":"mock-api-key"}`)
+		case r.Method == "GET" && r.URL.Path == "/api/folders":
+			fmt.Fprint(w, `[{"id":1,"uid":"nErXDvCkzz","title":"Folder"}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"not found"}`)
+		}
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder(nil)
+	client, err := gapi.New(server.URL, gapi.Config{APIKey: "my-key", Client: &http.Client{Transport: recorder}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.CreateAPIKey(gapi.CreateAPIKeyRequest{Name: "key-name", Role: "Admin"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Folders(); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "cassette")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "cassette.json")
+	if err := recorder.Cassette().Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"my-key", "mock-api-key"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("expected %s to be scrubbed from the cassette", secret)
+		}
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayer := NewReplayer(c)
+	client, err = gapi.New("http://my-grafana.com", gapi.Config{Client: &http.Client{Transport: replayer}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	folders, err := client.Folders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(folders) != 1 || folders[0].UID != "nErXDvCkzz" {
+		t.Errorf("unexpected folders: %v", folders)
+	}
+
+	if _, err := client.Folders(); err == nil {
+		t.Errorf("expected interactions not to be replayed twice")
+	}
+	if unused := replayer.Unused(); len(unused) != 1 || unused[0].Request.Path != "/api/auth/keys" {
+		t.Errorf("expected the API key interaction to be unused; got: %v", unused)
+	}
+}
+
+func TestRequestMatches(t *testing.T) {
+	recorded := Request{Method: "POST", Path: "/api/search", Query: "type=dash-db&limit=10", Body: `{"a": 1, "b": [1, 2]}`}
+
+	cases := []struct {
+		req      Request
+		expected bool
+	}{
+		{Request{Method: "POST", Path: "/api/search", Query: "limit=10&type=dash-db", Body: `{"b":[1,2],"a":1}`}, true},
+		{Request{Method: "GET", Path: "/api/search", Query: "limit=10&type=dash-db", Body: `{"b":[1,2],"a":1}`}, false},
+		{Request{Method: "POST", Path: "/api/folders", Query: "limit=10&type=dash-db", Body: `{"b":[1,2],"a":1}`}, false},
+		{Request{Method: "POST", Path: "/api/search", Query: "limit=20&type=dash-db", Body: `{"b":[1,2],"a":1}`}, false},
+		{Request{Method: "POST", Path: "/api/search", Query: "limit=10&type=dash-db", Body: `{"b":[2,1],"a":1}`}, false},
+	}
+
+	for i, c := range cases {
+		if recorded.matches(c.req) != c.expected {
+			t.Errorf("case %d: expected match to be %t", i, c.expected)
+		}
+	}
+}
+
+func TestRecorderDoesNotModifyRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	body := ioutil.NopCloser(strings.NewReader(`{"name":"folder"}`))
+	req, err := http.NewRequest("POST", server.URL+"/api/folders", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := NewRecorder(nil)
+	resp, err := recorder.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if req.Body != body {
+		t.Error("expected the body of the request not to be replaced")
+	}
+	if recorded := recorder.Cassette().Interactions[0]; recorded.Request.Body != `{"name":"folder"}` || recorded.Response.Body != `{"name":"folder"}` {
+		t.Errorf("unexpected interaction: %+v", recorded)
+	}
+}
@@ -0,0 +1,75 @@
+package cassette
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+// Recorder is an http.RoundTripper which records the interactions going through it.
+type Recorder struct {
+	transport http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder creates a recorder sending requests through the given transport.
+// A nil transport defaults to a pooled transport from go-cleanhttp.
+func NewRecorder(transport http.RoundTripper) *Recorder {
+	if transport == nil {
+		transport = cleanhttp.DefaultPooledTransport()
+	}
+	return &Recorder{transport: transport}
+}
+
+// RoundTrip implements http.RoundTripper. The request is sent as a clone, whose body is read to be recorded.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: newRequest(req, reqBody),
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     ScrubHeader(resp.Header),
+			Body:       ScrubBody(respBody),
+		},
+	})
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+// Cassette returns a copy of the interactions recorded so far.
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return &Cassette{Interactions: append([]Interaction(nil), r.cassette.Interactions...)}
+}
@@ -2,14 +2,13 @@ package gapi
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
-	"os"
 	"path"
 	"strconv"
 	"time"
@@ -22,6 +21,8 @@ type Client struct {
 	config  Config
 	baseURL url.URL
 	client  *http.Client
+	ctx     context.Context
+	limiter *limiter
 }
 
 // Config contains client configuration.
@@ -39,7 +40,29 @@ type Config struct {
 	// with APIKey, it is disallowed because API keys are scoped to a single org
 	OrgID int64
 	// NumRetries contains the number of attempted retries
+	// it is ignored when RetryPolicy is set
 	NumRetries int
+	// RetryPolicy provides an optional retry policy. Without one, requests are retried
+	// NumRetries times with a fixed 5 second delay.
+	RetryPolicy *RetryPolicy
+	// Logger provides an optional logger for requests. Without one, requests are logged
+	// to the standard logger if the GF_LOG environment variable is set.
+	Logger Logger
+	// LogBodies enables logging request and response bodies, with secrets redacted.
+	LogBodies bool
+	// CallMiddlewares wrap each logical API call, the first one being the outermost.
+	CallMiddlewares []CallMiddleware
+	// AttemptMiddlewares wrap each HTTP attempt made for a call, including retries,
+	// the first one being the outermost.
+	AttemptMiddlewares []AttemptMiddleware
+	// RateLimit optionally limits the number of HTTP attempts per second.
+	// Clients derived with WithOrgID or WithContext share the same limit.
+	RateLimit float64
+	// RateLimitBurst is the number of attempts which may exceed RateLimit at once. It defaults to 1.
+	RateLimitBurst int
+	// MaxConcurrentRequests optionally limits the number of HTTP attempts in flight.
+	// Clients derived with WithOrgID or WithContext share the same limit.
+	MaxConcurrentRequests int
 }
 
 // New creates a new Grafana client.
@@ -62,6 +85,7 @@ func New(baseURL string, cfg Config) (*Client, error) {
 		config:  cfg,
 		baseURL: *u,
 		client:  cli,
+		limiter: newLimiter(cfg),
 	}, nil
 }
 
@@ -71,79 +95,139 @@ func (c Client) WithOrgID(orgID int64) *Client {
 	return &c
 }
 
+// WithContext returns a new client whose requests are bound to the provided context.
+// Cancelling the context aborts in-flight requests as well as any pending retry.
+func (c Client) WithContext(ctx context.Context) *Client {
+	c.ctx = ctx
+	return &c
+}
+
+// Context returns the context the client's requests are bound to.
+// It defaults to context.Background().
+func (c *Client) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
 func (c *Client) request(method, requestPath string, query url.Values, body io.Reader, responseStruct interface{}) error {
+	// Stash the request data in memory, so that it can be both replayed on retries and logged.
+	var reqBody []byte
+	if body != nil {
+		var err error
+		if reqBody, err = ioutil.ReadAll(body); err != nil {
+			return err
+		}
+	}
+
+	call := &Call{
+		Operation: callerOperation(),
+		OrgID:     c.config.OrgID,
+		Method:    method,
+		Path:      requestPath,
+		Query:     query,
+		Body:      reqBody,
+		Response:  responseStruct,
+	}
+
+	handler := CallHandler(c.doCall)
+	for i := len(c.config.CallMiddlewares) - 1; i >= 0; i-- {
+		handler = c.config.CallMiddlewares[i](handler)
+	}
+
+	return handler(c.Context(), call)
+}
+
+// doCall performs a logical API call, retrying HTTP attempts according to the retry policy.
+func (c *Client) doCall(ctx context.Context, call *Call) error {
 	var (
 		req          *http.Request
 		resp         *http.Response
 		err          error
 		bodyContents []byte
+		body         io.Reader
+		release      func()
 	)
 
-	// If we want to retry a request that sends data, we'll need to stash the request data in memory. Otherwise, we lose it since readers cannot be replayed.
-	var bodyBuffer bytes.Buffer
-	if c.config.NumRetries > 0 && body != nil {
-		body = io.TeeReader(body, &bodyBuffer)
-	}
+	method, reqBody := call.Method, call.Body
+	policy := c.retryPolicy()
+	retryable := policy.retriesMethod(method)
+	transport := c.transport()
 
 	// retry logic
-	for n := 0; n <= c.config.NumRetries; n++ {
-		// If it's not the first request, re-use the request body we stashed earlier.
-		if n > 0 {
-			body = bytes.NewReader(bodyBuffer.Bytes())
+	start := time.Now()
+	for n := 1; ; n++ {
+		body = nil
+		if reqBody != nil {
+			body = bytes.NewReader(reqBody)
 		}
 
-		req, err = c.newRequest(method, requestPath, query, body)
+		req, err = c.newRequest(ctx, method, call.Path, call.Query, body)
 		if err != nil {
 			return err
 		}
 
-		// Wait a bit if that's not the first request
-		if n != 0 {
-			time.Sleep(time.Second * 5)
+		release, err = c.limiter.acquire(ctx)
+		if err != nil {
+			return err
 		}
 
-		resp, err = c.client.Do(req)
+		attemptStart := time.Now()
 
-		// If err is not nil, retry again
-		// That's either caused by client policy, or failure to speak HTTP (such as network connectivity problem). A
+		// err is either caused by client policy, or failure to speak HTTP (such as network connectivity problem). A
 		// non-2xx status code doesn't cause an error.
-		if err != nil {
-			continue
+		resp, err = transport.RoundTrip(req)
+		call.Attempts = n
+		call.StatusCode = 0
+		bodyContents = nil
+		if err == nil {
+			call.StatusCode = resp.StatusCode
+			// read the body (even on non-successful HTTP status codes), as that's what the unit tests expect
+			bodyContents, err = ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
 		}
+		release()
+		c.logAttempt(req, n, time.Since(attemptStart), reqBody, resp, bodyContents, err)
 
-		defer resp.Body.Close()
-
-		// read the body (even on non-successful HTTP status codes), as that's what the unit tests expect
-		bodyContents, err = ioutil.ReadAll(resp.Body)
+		// A cancelled context is final, there's no point in retrying.
+		if err != nil && ctx.Err() != nil {
+			return err
+		}
 
-		// if there was an error reading the body, try again
-		if err != nil {
-			continue
+		// Exit the loop if we have something final to return.
+		if !retryable || n >= policy.MaxAttempts || !policy.shouldRetry(resp, err) {
+			break
 		}
 
-		// Exit the loop if we have something final to return. This is anything < 500, if it's not a 429.
-		if resp.StatusCode < http.StatusInternalServerError && resp.StatusCode != http.StatusTooManyRequests {
+		wait := policy.delay(n, resp)
+		if policy.MaxElapsedTime > 0 && time.Since(start)+wait > policy.MaxElapsedTime {
 			break
 		}
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
 	}
 	if err != nil {
 		return err
 	}
 
-	if os.Getenv("GF_LOG") != "" {
-		log.Printf("response status %d with body %v", resp.StatusCode, string(bodyContents))
-	}
-
 	// check status code.
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("status: %d, body: %v", resp.StatusCode, string(bodyContents))
+		return newAPIError(method, call.Path, resp.StatusCode, bodyContents)
+	}
+
+	if call.Response == nil {
+		return nil
 	}
 
-	if responseStruct == nil {
+	// Responses which aren't JSON, like YAML exports, are returned as is.
+	if raw, ok := call.Response.(*[]byte); ok {
+		*raw = bodyContents
 		return nil
 	}
 
-	err = json.Unmarshal(bodyContents, responseStruct)
+	err = json.Unmarshal(bodyContents, call.Response)
 	if err != nil {
 		return err
 	}
@@ -151,11 +235,17 @@ func (c *Client) request(method, requestPath string, query url.Values, body io.R
 	return nil
 }
 
-func (c *Client) newRequest(method, requestPath string, query url.Values, body io.Reader) (*http.Request, error) {
-	url := c.baseURL
-	url.Path = path.Join(url.Path, requestPath)
-	url.RawQuery = query.Encode()
-	req, err := http.NewRequest(method, url.String(), body)
+func (c *Client) newRequest(ctx context.Context, method, requestPath string, query url.Values, body io.Reader) (*http.Request, error) {
+	u := c.baseURL
+	// Request paths may have escaped segments, e.g. names with slashes, which must not be escaped again.
+	escapedPath := path.Join(u.EscapedPath(), requestPath)
+	if unescapedPath, err := url.PathUnescape(escapedPath); err == nil {
+		u.Path, u.RawPath = unescapedPath, escapedPath
+	} else {
+		u.Path, u.RawPath = escapedPath, ""
+	}
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
 	if err != nil {
 		return req, err
 	}
@@ -174,14 +264,19 @@ func (c *Client) newRequest(method, requestPath string, query url.Values, body i
 		}
 	}
 
-	if os.Getenv("GF_LOG") != "" {
-		if body == nil {
-			log.Printf("request (%s) to %s with no body data", method, url.String())
-		} else {
-			log.Printf("request (%s) to %s with body data: %s", method, url.String(), body.(*bytes.Buffer).String())
-		}
-	}
-
 	req.Header.Add("Content-Type", "application/json")
 	return req, err
 }
+
+// sleep pauses for the given duration, returning early with the context's error if it is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
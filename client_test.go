@@ -2,9 +2,12 @@ package gapi
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/url"
 	"testing"
+	"time"
 )
 
 func TestNew_basicAuth(t *testing.T) {
@@ -173,3 +176,56 @@ func TestRequest_200UnmarshalPut(t *testing.T) {
 		t.Errorf("expected: name; got: %s", result.Name)
 	}
 }
+
+func TestWithContext(t *testing.T) {
+	c, err := New("http://my-grafana.com", Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Context() != context.Background() {
+		t.Errorf("expected default context to be context.Background()")
+	}
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "value")
+	cc := c.WithContext(ctx)
+	if cc.Context() != ctx {
+		t.Errorf("expected context to be set on the new client")
+	}
+	if c.Context() != context.Background() {
+		t.Errorf("expected original client to be left untouched")
+	}
+}
+
+func TestRequest_contextCanceled(t *testing.T) {
+	client := gapiTestTools(t, 200, `{"foo":"bar"}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := client.WithContext(ctx).request("GET", "/foo", url.Values{}, nil, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error: %v; got: %v", context.Canceled, err)
+	}
+}
+
+func TestRequest_contextInterruptsRetry(t *testing.T) {
+	client := gapiTestToolsFromCalls(t, []mockServerCall{
+		{500, `{"foo":"bar"}`},
+		{200, `{"foo":"bar"}`},
+	})
+	client.config.NumRetries = 1
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := client.WithContext(ctx).request("GET", "/foo", url.Values{}, nil, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error: %v; got: %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected retry wait to be interrupted; took %s", elapsed)
+	}
+}
@@ -47,6 +47,7 @@ func (c *Client) CreateTemporaryStackGrafanaClient(stackSlug, tempKeyPrefix stri
 	if err != nil {
 		return nil, nil, err
 	}
+	client.ctx = c.ctx
 
 	cleanup = func() error {
 		_, err = client.DeleteAPIKey(apiKey.ID)
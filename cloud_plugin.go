@@ -4,8 +4,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 )
 
 type Plugin struct {
@@ -59,28 +57,12 @@ func (c *Client) UninstallCloudPlugin(stackSlug string, pluginSlug string) error
 
 // IsCloudPluginInstalled returns a boolean if the specified plugin is installed on the stack.
 func (c *Client) IsCloudPluginInstalled(stackSlug string, pluginSlug string) (bool, error) {
-	req, err := c.newRequest("GET", fmt.Sprintf("/api/instances/%s/plugins/%s", stackSlug, pluginSlug), nil, nil)
+	err := c.request("GET", fmt.Sprintf("/api/instances/%s/plugins/%s", stackSlug, pluginSlug), nil, nil, nil)
 	if err != nil {
-		return false, err
-	}
-
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return false, err
-	}
-
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		if resp.StatusCode == http.StatusNotFound {
+		if IsNotFound(err) {
 			return false, nil
 		}
-		bodyContents, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return false, err
-		}
-
-		return false, fmt.Errorf("status: %d, body: %v", resp.StatusCode, string(bodyContents))
+		return false, err
 	}
 
 	return true, nil
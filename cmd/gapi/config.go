@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+// Environment variables of the connection settings.
+const (
+	envURL     = "GRAFANA_URL"
+	envAuth    = "GRAFANA_AUTH"
+	envOrgID   = "GRAFANA_ORG_ID"
+	envProfile = "GAPI_PROFILE"
+	envConfig  = "GAPI_CONFIG"
+)
+
+const defaultProfile = "default"
+
+// connection holds the settings used to connect to Grafana.
+type connection struct {
+	URL string `json:"url"`
+	// Auth is an API key or service account token, or basic auth credentials as user:password.
+	Auth  string `json:"auth"`
+	OrgID int64  `json:"orgId"`
+}
+
+// profiles is the format of the profile file, e.g.:
+//
+//	{"profiles": {"default": {"url": "http://localhost:3000", "auth": "admin:admin"}}}
+type profiles struct {
+	Profiles map[string]connection `json:"profiles"`
+}
+
+// configPath returns the path of the profile file: $GAPI_CONFIG, or gapi/config.json in the user config directory.
+func configPath(getenv func(string) string) string {
+	if path := getenv(envConfig); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gapi", "config.json")
+}
+
+// loadProfile reads a profile from the profile file. The default profile is optional, as is the file when
+// no profile is requested.
+func loadProfile(path, name string) (connection, error) {
+	requested := name != ""
+	if !requested {
+		name = defaultProfile
+	}
+	if path == "" {
+		if requested {
+			return connection{}, fmt.Errorf("profile %s not found: no config file", name)
+		}
+		return connection{}, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !requested {
+		return connection{}, nil
+	}
+	if err != nil {
+		return connection{}, err
+	}
+	file := profiles{}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return connection{}, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	profile, exists := file.Profiles[name]
+	if !exists && requested {
+		return connection{}, fmt.Errorf("profile %s not found in %s", name, path)
+	}
+	return profile, nil
+}
+
+// resolve returns the connection settings from the flags, falling back to the environment, then to the profile.
+func resolve(flags connection, profile string, configFile string, getenv func(string) string) (connection, error) {
+	if profile == "" {
+		profile = getenv(envProfile)
+	}
+	if configFile == "" {
+		configFile = configPath(getenv)
+	}
+	conn, err := loadProfile(configFile, profile)
+	if err != nil {
+		return conn, err
+	}
+
+	if baseURL := getenv(envURL); baseURL != "" {
+		conn.URL = baseURL
+	}
+	if auth := getenv(envAuth); auth != "" {
+		conn.Auth = auth
+	}
+	if orgID := getenv(envOrgID); orgID != "" {
+		id, err := strconv.ParseInt(orgID, 10, 64)
+		if err != nil {
+			return conn, fmt.Errorf("invalid %s: %w", envOrgID, err)
+		}
+		conn.OrgID = id
+	}
+
+	if flags.URL != "" {
+		conn.URL = flags.URL
+	}
+	if flags.Auth != "" {
+		conn.Auth = flags.Auth
+	}
+	if flags.OrgID != 0 {
+		conn.OrgID = flags.OrgID
+	}
+
+	if conn.URL == "" {
+		return conn, fmt.Errorf("no Grafana URL: use -url, %s or a profile", envURL)
+	}
+	return conn, nil
+}
+
+// client returns a client for the connection.
+func (conn connection) client() (*gapi.Client, error) {
+	cfg := gapi.Config{OrgID: conn.OrgID}
+	if parts := strings.SplitN(conn.Auth, ":", 2); len(parts) == 2 {
+		cfg.BasicAuth = url.UserPassword(parts[0], parts[1])
+	} else {
+		cfg.APIKey = conn.Auth
+	}
+	return gapi.New(conn.URL, cfg)
+}
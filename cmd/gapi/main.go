@@ -0,0 +1,414 @@
+// Command gapi manages the resources of a Grafana instance from the command line.
+//
+// Usage:
+//
+//	gapi [flags] <command> <kind> [arguments]
+//
+// The commands are:
+//
+//	list <kind>                      list the resources of a kind
+//	get <kind> <id>                  print a resource
+//	apply <kind> -f <file>           create or update the resources of a file
+//	import <kind> -f <file>          create the resources of a file, skipping existing ones
+//	export <kind>                    print all the resources of a kind, in the format accepted by apply and import
+//	delete <kind> <id>...            delete resources
+//	diff <kind> -f <file>            compare the resources of a file with the existing ones
+//
+// The kinds are dashboards, folders, datasources, contact-points, mute-timings, policies, rule-groups, users
+// and teams. Resources are identified by UID, except mute timings, users and teams which are identified by
+// name or login, and rule groups by <folder UID>/<title>. The notification policy tree has no identifier.
+//
+// Files contain a resource or a list of resources as JSON, in the format printed by get, "-" being the
+// standard input. When comparing them, the fields they don't set are ignored.
+//
+// Connection settings are read from the flags, then from the GRAFANA_URL, GRAFANA_AUTH and GRAFANA_ORG_ID
+// environment variables, then from a profile: -profile or $GAPI_PROFILE, "default" otherwise. Profiles are read
+// from -config, $GAPI_CONFIG or gapi/config.json in the user config directory, e.g. ~/.config/gapi/config.json:
+//
+//	{"profiles": {"default": {"url": "http://localhost:3000", "auth": "admin:admin", "orgId": 1}}}
+//
+// Auth is an API key or service account token, or basic auth credentials as user:password.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+// Output formats.
+const (
+	formatJSON  = "json"
+	formatTable = "table"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(c *cli, r *resource, args []string) error
+}
+
+var commands = []command{
+	{"list", "<kind>", (*cli).list},
+	{"get", "<kind> <id>", (*cli).get},
+	{"apply", "<kind> -f <file>", (*cli).apply},
+	{"import", "<kind> -f <file> [-overwrite]", (*cli).importResources},
+	{"export", "<kind>", (*cli).export},
+	{"delete", "<kind> <id>...", (*cli).delete},
+	{"diff", "<kind> -f <file>", (*cli).diff},
+}
+
+type cli struct {
+	client *gapi.Client
+	// format is the output format, empty for the default format of the command.
+	format string
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	err := run(os.Args[1:], os.Getenv, os.Stdin, os.Stdout, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gapi:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, getenv func(string) string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("gapi", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	conn := connection{}
+	flags.StringVar(&conn.URL, "url", "", "Grafana URL, e.g. http://localhost:3000")
+	flags.StringVar(&conn.Auth, "auth", "", "API key, service account token or user:password")
+	flags.Int64Var(&conn.OrgID, "org", 0, "ID of the org, with basic auth")
+	profile := flags.String("profile", "", "connection profile")
+	configFile := flags.String("config", "", "profile file")
+	format := flags.String("o", "", "output format: json or table")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: gapi [flags] <command> <kind> [arguments]\n\nCommands:")
+		for _, cmd := range commands {
+			fmt.Fprintf(stderr, "  %s %s\n", cmd.name, cmd.usage)
+		}
+		names := make([]string, 0, len(resources))
+		for _, r := range resources {
+			names = append(names, r.names[0])
+		}
+		fmt.Fprintf(stderr, "\nKinds: %s\n\nFlags:\n", strings.Join(names, ", "))
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return flag.ErrHelp
+	}
+	if *format != "" && *format != formatJSON && *format != formatTable {
+		return fmt.Errorf("invalid output format %q", *format)
+	}
+
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == flags.Arg(0) {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		return fmt.Errorf("unknown command %q", flags.Arg(0))
+	}
+	r, err := findResource(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	conn, err = resolve(conn, *profile, *configFile, getenv)
+	if err != nil {
+		return err
+	}
+	client, err := conn.client()
+	if err != nil {
+		return err
+	}
+	c := &cli{client: client, format: *format, stdin: stdin, stdout: stdout, stderr: stderr}
+	return cmd.run(c, r, flags.Args()[2:])
+}
+
+// parseFlags parses the flags of a command, and checks its number of arguments.
+func parseFlags(flags *flag.FlagSet, args []string, minArgs, maxArgs int) error {
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage of %s:\n", flags.Name())
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < minArgs || (maxArgs >= 0 && flags.NArg() > maxArgs) {
+		flags.Usage()
+		return flag.ErrHelp
+	}
+	return nil
+}
+
+func (c *cli) newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	return flags
+}
+
+func (c *cli) list(r *resource, args []string) error {
+	if err := parseFlags(c.newFlagSet("list"), args, 0, 0); err != nil {
+		return err
+	}
+	items, err := r.list(c.client)
+	if err != nil {
+		return err
+	}
+	if c.format == formatJSON {
+		return c.printJSON(items)
+	}
+	return c.printTable(r, items)
+}
+
+func (c *cli) get(r *resource, args []string) error {
+	minArgs := 1
+	if r.singleton {
+		minArgs = 0
+	}
+	flags := c.newFlagSet("get")
+	if err := parseFlags(flags, args, minArgs, 1); err != nil {
+		return err
+	}
+	item, err := r.get(c.client, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if c.format == formatTable {
+		return c.printTable(r, []interface{}{asObject(item)})
+	}
+	return c.printJSON(item)
+}
+
+// export prints the resources of a kind in the format returned by get.
+func (c *cli) export(r *resource, args []string) error {
+	if err := parseFlags(c.newFlagSet("export"), args, 0, 0); err != nil {
+		return err
+	}
+	items, err := r.list(c.client)
+	if err != nil {
+		return err
+	}
+	exported := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		resource, err := r.get(c.client, r.id(item))
+		if err != nil {
+			return err
+		}
+		exported = append(exported, resource)
+	}
+	return c.printJSON(exported)
+}
+
+func (c *cli) apply(r *resource, args []string) error {
+	flags := c.newFlagSet("apply")
+	file := flags.String("f", "", "file of the resources, - for the standard input")
+	if err := parseFlags(flags, args, 0, 0); err != nil {
+		return err
+	}
+	items, err := c.readResources(*file)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		id, err := r.apply(c.client, item)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout, "applied %s %s\n", r.names[0], id)
+	}
+	return nil
+}
+
+// importResources creates the resources of a file which don't exist, e.g. to copy the export of another instance.
+func (c *cli) importResources(r *resource, args []string) error {
+	flags := c.newFlagSet("import")
+	file := flags.String("f", "", "file of the resources, - for the standard input")
+	overwrite := flags.Bool("overwrite", false, "update the existing resources")
+	if err := parseFlags(flags, args, 0, 0); err != nil {
+		return err
+	}
+	items, err := c.readResources(*file)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		id, err := r.decodeID(item)
+		if err != nil {
+			return err
+		}
+		if !*overwrite && !r.singleton && id != "" {
+			_, err := r.get(c.client, id)
+			if err == nil {
+				fmt.Fprintf(c.stdout, "skipped %s %s: already exists\n", r.names[0], id)
+				continue
+			}
+			if !gapi.IsNotFound(err) {
+				return err
+			}
+		}
+		if id, err = r.apply(c.client, item); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout, "imported %s %s\n", r.names[0], id)
+	}
+	return nil
+}
+
+func (c *cli) delete(r *resource, args []string) error {
+	minArgs := 1
+	if r.singleton {
+		minArgs = 0
+	}
+	flags := c.newFlagSet("delete")
+	if err := parseFlags(flags, args, minArgs, -1); err != nil {
+		return err
+	}
+	ids := flags.Args()
+	if r.singleton {
+		ids = []string{""}
+	}
+	for _, id := range ids {
+		if err := r.delete(c.client, id); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout, "deleted %s %s\n", r.names[0], id)
+	}
+	return nil
+}
+
+// diff prints the changes applying a file would make, as JSON pointers. The fields the file doesn't set are ignored.
+func (c *cli) diff(r *resource, args []string) error {
+	flags := c.newFlagSet("diff")
+	file := flags.String("f", "", "file of the resources, - for the standard input")
+	if err := parseFlags(flags, args, 0, 0); err != nil {
+		return err
+	}
+	items, err := c.readResources(*file)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		desired := map[string]interface{}{}
+		if err := json.Unmarshal(item, &desired); err != nil {
+			return err
+		}
+		if r.document != nil {
+			desired = r.document(desired)
+		}
+		id := r.id(desired)
+
+		current, err := r.get(c.client, id)
+		if gapi.IsNotFound(err) {
+			fmt.Fprintf(c.stdout, "+ %s %s\n", r.names[0], id)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		changes, err := gapi.DiffDashboardModels(setFields(asObject(current), desired), desired)
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			fmt.Fprintf(c.stdout, "= %s %s\n", r.names[0], id)
+			continue
+		}
+		fmt.Fprintf(c.stdout, "~ %s %s\n", r.names[0], id)
+		for _, change := range changes {
+			before, _ := json.Marshal(change.Old)
+			after, _ := json.Marshal(change.New)
+			switch change.Op {
+			case gapi.DashboardChangeAdd:
+				fmt.Fprintf(c.stdout, "    %s: %s\n", change.Path, after)
+			case gapi.DashboardChangeRemove:
+				fmt.Fprintf(c.stdout, "    %s: %s -> removed\n", change.Path, before)
+			default:
+				fmt.Fprintf(c.stdout, "    %s: %s -> %s\n", change.Path, before, after)
+			}
+		}
+	}
+	return nil
+}
+
+// setFields returns the fields of an object which are set in another one, recursively.
+func setFields(object, set map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for key, value := range object {
+		setValue, exists := set[key]
+		if !exists {
+			continue
+		}
+		valueObject, isObject := value.(map[string]interface{})
+		setObject, setIsObject := setValue.(map[string]interface{})
+		if isObject && setIsObject {
+			value = setFields(valueObject, setObject)
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// readResources reads a resource, or a list of resources, from a file.
+func (c *cli) readResources(path string) ([]json.RawMessage, error) {
+	var data []byte
+	var err error
+	switch path {
+	case "":
+		return nil, errors.New("missing file: use -f")
+	case "-":
+		data, err = ioutil.ReadAll(c.stdin)
+	default:
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if data = bytes.TrimSpace(data); bytes.HasPrefix(data, []byte("[")) {
+		var items []json.RawMessage
+		err = json.Unmarshal(data, &items)
+		return items, err
+	}
+	var item json.RawMessage
+	err = json.Unmarshal(data, &item)
+	return []json.RawMessage{item}, err
+}
+
+func (c *cli) printJSON(v interface{}) error {
+	encoder := json.NewEncoder(c.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func (c *cli) printTable(r *resource, items []interface{}) error {
+	w := tabwriter.NewWriter(c.stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(r.columns, "\t"))
+	for _, item := range items {
+		fmt.Fprintln(w, strings.Join(r.row(item), "\t"))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+	"github.com/grafana/grafana-api-golang-client/gapitest"
+)
+
+// gapiCommand runs a command against a server, with the given standard input.
+func gapiCommand(t *testing.T, server *gapitest.Server, stdin string, args ...string) (string, error) {
+	t.Helper()
+
+	env := map[string]string{envURL: server.URL, envAuth: "token", envConfig: filepath.Join("testdata", "missing.json")}
+	stdout := &bytes.Buffer{}
+	err := run(args, func(key string) string { return env[key] }, strings.NewReader(stdin), stdout, ioutil.Discard)
+	return stdout.String(), err
+}
+
+func TestListAndGet(t *testing.T) {
+	server := gapitest.NewServer()
+	defer server.Close()
+	if _, err := server.Client().NewFolder("Production", "prod"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := gapiCommand(t, server, "", "list", "folders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "UID   TITLE\nprod  Production\n"; out != expected {
+		t.Errorf("expected table:\n%s\ngot:\n%s", expected, out)
+	}
+
+	out, err = gapiCommand(t, server, "", "-o", "json", "get", "folder", "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	folder := gapi.Folder{}
+	if err := json.Unmarshal([]byte(out), &folder); err != nil || folder.Title != "Production" {
+		t.Errorf("unexpected folder %s: %v", out, err)
+	}
+
+	if _, err := gapiCommand(t, server, "", "get", "folder", "missing"); !gapi.IsNotFound(err) {
+		t.Errorf("expected a not found error; got: %v", err)
+	}
+	if _, err := gapiCommand(t, server, "", "get", "widgets", "prod"); err == nil {
+		t.Error("expected an error for an unknown kind")
+	}
+}
+
+func TestApplyDiffDelete(t *testing.T) {
+	server := gapitest.NewServer()
+	defer server.Close()
+
+	dashboard := `{"uid": "services", "title": "Services", "tags": ["prod"]}`
+	out, err := gapiCommand(t, server, dashboard, "apply", "dashboards", "-f", "-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "applied dashboards services\n" {
+		t.Errorf("unexpected output: %s", out)
+	}
+
+	// The fields set by Grafana, like the version, are ignored.
+	out, err = gapiCommand(t, server, dashboard, "diff", "dash", "-f", "-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "= dashboards services\n" {
+		t.Errorf("expected no differences; got:\n%s", out)
+	}
+	changed := `[{"uid": "services", "title": "Services", "tags": ["staging"]}, {"uid": "new", "title": "New"}]`
+	out, err = gapiCommand(t, server, changed, "diff", "dash", "-f", "-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "~ dashboards services\n    /dashboard/tags/0: \"prod\" -> \"staging\"\n+ dashboards new\n"; out != expected {
+		t.Errorf("expected diff:\n%s\ngot:\n%s", expected, out)
+	}
+
+	if _, err := gapiCommand(t, server, "", "delete", "dashboards", "services"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.Client().DashboardByUID("services"); !gapi.IsNotFound(err) {
+		t.Errorf("expected the dashboard to be deleted; got: %v", err)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	source := gapitest.NewServer()
+	defer source.Close()
+	destination := gapitest.NewServer()
+	defer destination.Close()
+
+	if _, err := source.Client().NewDataSource(&gapi.DataSource{UID: "prom", Name: "Prometheus", Type: "prometheus", URL: "http://prometheus:9090"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := destination.Client().NewDataSource(&gapi.DataSource{UID: "loki", Name: "Loki", Type: "loki"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.Client().NewDataSource(&gapi.DataSource{UID: "loki", Name: "Loki", Type: "loki", URL: "http://loki:3100"}); err != nil {
+		t.Fatal(err)
+	}
+
+	export, err := gapiCommand(t, source, "", "export", "datasources")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := gapiCommand(t, destination, export, "import", "datasources", "-f", "-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "skipped datasources loki: already exists\nimported datasources prom\n"; out != expected {
+		t.Errorf("expected output:\n%s\ngot:\n%s", expected, out)
+	}
+
+	ds, err := destination.Client().DataSourceByUID("prom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ds.URL != "http://prometheus:9090" {
+		t.Errorf("unexpected imported data source: %+v", ds)
+	}
+}
+
+func TestConnectionSettings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gapi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	config := filepath.Join(dir, "config.json")
+	err = ioutil.WriteFile(config, []byte(`{"profiles": {
+		"default": {"url": "http://default:3000", "auth": "token"},
+		"prod": {"url": "http://prod:3000", "auth": "admin:secret", "orgId": 2}
+	}}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, test := range map[string]struct {
+		flags    connection
+		profile  string
+		env      map[string]string
+		expected connection
+	}{
+		"default profile": {
+			expected: connection{URL: "http://default:3000", Auth: "token"},
+		},
+		"profile": {
+			profile:  "prod",
+			expected: connection{URL: "http://prod:3000", Auth: "admin:secret", OrgID: 2},
+		},
+		"environment": {
+			env:      map[string]string{envProfile: "prod", envURL: "http://env:3000", envOrgID: "3"},
+			expected: connection{URL: "http://env:3000", Auth: "admin:secret", OrgID: 3},
+		},
+		"flags": {
+			flags:    connection{URL: "http://flag:3000"},
+			env:      map[string]string{envURL: "http://env:3000"},
+			expected: connection{URL: "http://flag:3000", Auth: "token"},
+		},
+	} {
+		conn, err := resolve(test.flags, test.profile, config, func(key string) string { return test.env[key] })
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if conn != test.expected {
+			t.Errorf("%s: expected %+v; got: %+v", name, test.expected, conn)
+		}
+	}
+
+	if _, err := resolve(connection{}, "missing", config, func(string) string { return "" }); err == nil {
+		t.Error("expected an error for a missing profile")
+	}
+	if _, err := resolve(connection{}, "", filepath.Join(dir, "missing.json"), func(string) string { return "" }); err == nil {
+		t.Error("expected an error without URL")
+	}
+}
@@ -0,0 +1,535 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+// resource describes how to manage a kind of resource. Resources are represented as JSON objects, in the format
+// returned by get, which apply accepts back.
+type resource struct {
+	// names are the name of the kind, followed by its aliases.
+	names   []string
+	columns []string
+	// singleton resources, like the notification policy tree, have no identifier.
+	singleton bool
+
+	list func(c *gapi.Client) ([]interface{}, error)
+	get  func(c *gapi.Client, id string) (interface{}, error)
+	// apply creates or updates a resource, returning its identifier.
+	apply  func(c *gapi.Client, data []byte) (string, error)
+	delete func(c *gapi.Client, id string) error
+	// id returns the identifier of a listed resource, or of a resource to apply.
+	id  func(item interface{}) string
+	row func(item interface{}) []string
+	// document optionally converts a resource to apply to the format returned by get.
+	document func(object map[string]interface{}) map[string]interface{}
+}
+
+var resources = []*resource{
+	dashboards,
+	folders,
+	dataSources,
+	contactPoints,
+	muteTimings,
+	policies,
+	ruleGroups,
+	users,
+	teams,
+}
+
+func findResource(name string) (*resource, error) {
+	for _, r := range resources {
+		for _, n := range r.names {
+			if n == name {
+				return r, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown resource kind %q", name)
+}
+
+// decodeID returns the identifier of a resource to apply.
+func (r *resource) decodeID(data []byte) (string, error) {
+	object := map[string]interface{}{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return "", err
+	}
+	return r.id(object), nil
+}
+
+// field returns a string field of a JSON object, or of a nested object.
+func field(item interface{}, path ...string) string {
+	for _, key := range path {
+		object, _ := item.(map[string]interface{})
+		item = object[key]
+	}
+	switch v := item.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// asObject converts a resource to a JSON object, so that its fields can be read by id.
+func asObject(v interface{}) map[string]interface{} {
+	data, _ := json.Marshal(v)
+	object := map[string]interface{}{}
+	_ = json.Unmarshal(data, &object)
+	return object
+}
+
+func objects(items interface{}) []interface{} {
+	data, _ := json.Marshal(items)
+	result := []interface{}{}
+	_ = json.Unmarshal(data, &result)
+	return result
+}
+
+var dashboards = &resource{
+	names:   []string{"dashboards", "dashboard", "dash"},
+	columns: []string{"UID", "TITLE", "FOLDER"},
+	list: func(c *gapi.Client) ([]interface{}, error) {
+		var items []interface{}
+		err := c.ForEachDashboard(gapi.PageOptions{}, func(result gapi.FolderDashboardSearchResponse) error {
+			if result.Type == "dash-db" {
+				items = append(items, asObject(result))
+			}
+			return nil
+		})
+		return items, err
+	},
+	get: func(c *gapi.Client, uid string) (interface{}, error) {
+		dashboard, err := c.DashboardByUID(uid)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"folderUid": dashboard.FolderUID, "dashboard": dashboard.Model}, nil
+	},
+	// Dashboards are applied with their folder, as returned by get, or as bare models saved in the General folder.
+	apply: func(c *gapi.Client, data []byte) (string, error) {
+		dashboard := gapi.Dashboard{}
+		if err := json.Unmarshal(data, &dashboard); err != nil {
+			return "", err
+		}
+		if dashboard.Model == nil {
+			if err := json.Unmarshal(data, &dashboard.Model); err != nil {
+				return "", err
+			}
+		}
+		// The dashboard is identified by its UID, which may be applied to another instance.
+		delete(dashboard.Model, "id")
+		dashboard.Overwrite = true
+		resp, err := c.NewDashboard(dashboard)
+		if err != nil {
+			return "", err
+		}
+		return resp.UID, nil
+	},
+	delete: func(c *gapi.Client, uid string) error {
+		return c.DeleteDashboardByUID(uid)
+	},
+	id: func(item interface{}) string {
+		if uid := field(item, "dashboard", "uid"); uid != "" {
+			return uid
+		}
+		return field(item, "uid")
+	},
+	row: func(item interface{}) []string {
+		return []string{field(item, "uid"), field(item, "title"), field(item, "folderTitle")}
+	},
+	document: func(object map[string]interface{}) map[string]interface{} {
+		if _, wrapped := object["dashboard"]; wrapped {
+			return object
+		}
+		return map[string]interface{}{"folderUid": "", "dashboard": object}
+	},
+}
+
+var folders = &resource{
+	names:   []string{"folders", "folder"},
+	columns: []string{"UID", "TITLE"},
+	list: func(c *gapi.Client) ([]interface{}, error) {
+		folders, err := c.Folders()
+		return objects(folders), err
+	},
+	get: func(c *gapi.Client, uid string) (interface{}, error) {
+		return c.FolderByUID(uid)
+	},
+	apply: func(c *gapi.Client, data []byte) (string, error) {
+		folder := gapi.Folder{}
+		if err := json.Unmarshal(data, &folder); err != nil {
+			return "", err
+		}
+		if folder.UID != "" {
+			_, err := c.FolderByUID(folder.UID)
+			if err == nil {
+				return folder.UID, c.UpdateFolder(folder.UID, folder.Title)
+			}
+			if !gapi.IsNotFound(err) {
+				return "", err
+			}
+		}
+		created, err := c.NewFolder(folder.Title, folder.UID)
+		return created.UID, err
+	},
+	delete: func(c *gapi.Client, uid string) error {
+		return c.DeleteFolder(uid)
+	},
+	id: func(item interface{}) string {
+		return field(item, "uid")
+	},
+	row: func(item interface{}) []string {
+		return []string{field(item, "uid"), field(item, "title")}
+	},
+}
+
+var dataSources = &resource{
+	names:   []string{"datasources", "datasource", "ds"},
+	columns: []string{"UID", "NAME", "TYPE", "URL"},
+	list: func(c *gapi.Client) ([]interface{}, error) {
+		dataSources, err := c.DataSources()
+		return objects(dataSources), err
+	},
+	get: func(c *gapi.Client, uid string) (interface{}, error) {
+		return c.DataSourceByUID(uid)
+	},
+	apply: func(c *gapi.Client, data []byte) (string, error) {
+		ds := &gapi.DataSource{}
+		if err := json.Unmarshal(data, ds); err != nil {
+			return "", err
+		}
+		if ds.UID != "" {
+			existing, err := c.DataSourceByUID(ds.UID)
+			if err == nil {
+				ds.ID = existing.ID
+				return ds.UID, c.UpdateDataSourceByUID(ds)
+			}
+			if !gapi.IsNotFound(err) {
+				return "", err
+			}
+		}
+		// The data source may be applied to another instance.
+		ds.ID = 0
+		id, err := c.NewDataSource(ds)
+		if err != nil || ds.UID != "" {
+			return ds.UID, err
+		}
+		created, err := c.DataSource(id)
+		if err != nil {
+			return "", err
+		}
+		return created.UID, nil
+	},
+	delete: func(c *gapi.Client, uid string) error {
+		ds, err := c.DataSourceByUID(uid)
+		if err != nil {
+			return err
+		}
+		return c.DeleteDataSource(ds.ID)
+	},
+	id: func(item interface{}) string {
+		return field(item, "uid")
+	},
+	row: func(item interface{}) []string {
+		return []string{field(item, "uid"), field(item, "name"), field(item, "type"), field(item, "url")}
+	},
+}
+
+var contactPoints = &resource{
+	names:   []string{"contact-points", "contact-point", "cp"},
+	columns: []string{"UID", "NAME", "TYPE"},
+	list: func(c *gapi.Client) ([]interface{}, error) {
+		points, err := c.ContactPoints()
+		return objects(points), err
+	},
+	get: func(c *gapi.Client, uid string) (interface{}, error) {
+		return c.ContactPoint(uid)
+	},
+	apply: func(c *gapi.Client, data []byte) (string, error) {
+		point := &gapi.ContactPoint{}
+		if err := json.Unmarshal(data, point); err != nil {
+			return "", err
+		}
+		if point.UID != "" {
+			_, err := c.ContactPoint(point.UID)
+			if err == nil {
+				return point.UID, c.UpdateContactPoint(point)
+			}
+			if !gapi.IsNotFound(err) {
+				return "", err
+			}
+		}
+		return c.NewContactPoint(point)
+	},
+	delete: func(c *gapi.Client, uid string) error {
+		return c.DeleteContactPoint(uid)
+	},
+	id: func(item interface{}) string {
+		return field(item, "uid")
+	},
+	row: func(item interface{}) []string {
+		return []string{field(item, "uid"), field(item, "name"), field(item, "type")}
+	},
+}
+
+var muteTimings = &resource{
+	names:   []string{"mute-timings", "mute-timing", "mt"},
+	columns: []string{"NAME", "INTERVALS"},
+	list: func(c *gapi.Client) ([]interface{}, error) {
+		timings, err := c.MuteTimings()
+		return objects(timings), err
+	},
+	get: func(c *gapi.Client, name string) (interface{}, error) {
+		return c.MuteTiming(name)
+	},
+	apply: func(c *gapi.Client, data []byte) (string, error) {
+		timing := &gapi.MuteTiming{}
+		if err := json.Unmarshal(data, timing); err != nil {
+			return "", err
+		}
+		_, err := c.MuteTiming(timing.Name)
+		if err == nil {
+			return timing.Name, c.UpdateMuteTiming(timing)
+		}
+		if !gapi.IsNotFound(err) {
+			return "", err
+		}
+		return timing.Name, c.NewMuteTiming(timing)
+	},
+	delete: func(c *gapi.Client, name string) error {
+		return c.DeleteMuteTiming(name)
+	},
+	id: func(item interface{}) string {
+		return field(item, "name")
+	},
+	row: func(item interface{}) []string {
+		intervals, _ := item.(map[string]interface{})["time_intervals"].([]interface{})
+		return []string{field(item, "name"), fmt.Sprint(len(intervals))}
+	},
+}
+
+// policies is the notification policy tree of the org. Deleting it resets it to the default policy.
+var policies = &resource{
+	names:     []string{"policies", "policy", "notification-policies"},
+	columns:   []string{"RECEIVER", "ROUTES"},
+	singleton: true,
+	list: func(c *gapi.Client) ([]interface{}, error) {
+		tree, err := c.NotificationPolicyTree()
+		return []interface{}{asObject(tree)}, err
+	},
+	get: func(c *gapi.Client, _ string) (interface{}, error) {
+		return c.NotificationPolicyTree()
+	},
+	apply: func(c *gapi.Client, data []byte) (string, error) {
+		tree := &gapi.NotificationPolicyTree{}
+		if err := json.Unmarshal(data, tree); err != nil {
+			return "", err
+		}
+		return "", c.SetNotificationPolicyTree(tree)
+	},
+	delete: func(c *gapi.Client, _ string) error {
+		return c.ResetNotificationPolicyTree()
+	},
+	id: func(item interface{}) string {
+		return ""
+	},
+	row: func(item interface{}) []string {
+		routes, _ := item.(map[string]interface{})["routes"].([]interface{})
+		return []string{field(item, "receiver"), fmt.Sprint(len(routes))}
+	},
+}
+
+// ruleGroup fetches a rule group by the UID of its folder and its title, e.g. prod/cpu.
+func ruleGroup(c *gapi.Client, id string) (gapi.RuleGroup, error) {
+	parts := strings.SplitN(id, "/", 2)
+	if len(parts) != 2 {
+		return gapi.RuleGroup{}, fmt.Errorf("invalid rule group %q: expected <folder UID>/<title>", id)
+	}
+	return c.AlertRuleGroup(parts[0], parts[1])
+}
+
+// ruleGroups are identified by the UID of their folder and their title, e.g. prod/cpu.
+var ruleGroups = &resource{
+	names:   []string{"rule-groups", "rule-group", "rg"},
+	columns: []string{"FOLDER", "TITLE", "INTERVAL", "RULES"},
+	list: func(c *gapi.Client) ([]interface{}, error) {
+		rules, err := c.AlertRules()
+		if err != nil {
+			return nil, err
+		}
+		var ids []string
+		seen := map[string]bool{}
+		for _, rule := range rules {
+			id := rule.FolderUID + "/" + rule.RuleGroup
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+
+		items := make([]interface{}, 0, len(ids))
+		for _, id := range ids {
+			group, err := ruleGroup(c, id)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, asObject(group))
+		}
+		return items, nil
+	},
+	get: func(c *gapi.Client, id string) (interface{}, error) {
+		return ruleGroup(c, id)
+	},
+	apply: func(c *gapi.Client, data []byte) (string, error) {
+		group := gapi.RuleGroup{}
+		if err := json.Unmarshal(data, &group); err != nil {
+			return "", err
+		}
+		return group.FolderUID + "/" + group.Title, c.SetAlertRuleGroup(group)
+	},
+	// Rule groups are deleted by deleting their rules.
+	delete: func(c *gapi.Client, id string) error {
+		group, err := ruleGroup(c, id)
+		if err != nil {
+			return err
+		}
+		for _, rule := range group.Rules {
+			if err := c.DeleteAlertRule(rule.UID); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	id: func(item interface{}) string {
+		return field(item, "folderUid") + "/" + field(item, "title")
+	},
+	row: func(item interface{}) []string {
+		rules, _ := item.(map[string]interface{})["rules"].([]interface{})
+		return []string{field(item, "folderUid"), field(item, "title"), field(item, "interval"), fmt.Sprint(len(rules))}
+	},
+}
+
+// userByID fetches a user by ID, or by login or email.
+func userByID(c *gapi.Client, id string) (gapi.User, error) {
+	if numeric, err := strconv.ParseInt(id, 10, 64); err == nil {
+		return c.User(numeric)
+	}
+	return c.UserByEmail(id)
+}
+
+// users are identified by ID, login or email. Managing them requires a Grafana server admin.
+var users = &resource{
+	names:   []string{"users", "user"},
+	columns: []string{"ID", "LOGIN", "EMAIL", "NAME"},
+	list: func(c *gapi.Client) ([]interface{}, error) {
+		users, err := c.Users()
+		return objects(users), err
+	},
+	get: func(c *gapi.Client, id string) (interface{}, error) {
+		return userByID(c, id)
+	},
+	// Users are created with their password, and updated without.
+	apply: func(c *gapi.Client, data []byte) (string, error) {
+		user := gapi.User{}
+		if err := json.Unmarshal(data, &user); err != nil {
+			return "", err
+		}
+		existing, err := c.UserByEmail(user.Login)
+		if err == nil {
+			user.ID = existing.ID
+			return user.Login, c.UserUpdate(user)
+		}
+		if !gapi.IsNotFound(err) {
+			return "", err
+		}
+		user.ID = 0
+		_, err = c.CreateUser(user)
+		return user.Login, err
+	},
+	delete: func(c *gapi.Client, id string) error {
+		user, err := userByID(c, id)
+		if err != nil {
+			return err
+		}
+		return c.DeleteUser(user.ID)
+	},
+	id: func(item interface{}) string {
+		return field(item, "login")
+	},
+	row: func(item interface{}) []string {
+		return []string{field(item, "id"), field(item, "login"), field(item, "email"), field(item, "name")}
+	},
+}
+
+// teamByID fetches a team by ID, or by name.
+func teamByID(c *gapi.Client, id string) (*gapi.Team, error) {
+	if numeric, err := strconv.ParseInt(id, 10, 64); err == nil {
+		return c.Team(numeric)
+	}
+	result, err := c.SearchTeam(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, team := range result.Teams {
+		if team.Name == id {
+			return team, nil
+		}
+	}
+	return nil, fmt.Errorf("team %s: %w", id, gapi.ErrNotFound)
+}
+
+// teams are identified by ID or name.
+var teams = &resource{
+	names:   []string{"teams", "team"},
+	columns: []string{"ID", "NAME", "EMAIL", "MEMBERS"},
+	list: func(c *gapi.Client) ([]interface{}, error) {
+		var items []interface{}
+		err := c.ForEachTeam("", gapi.PageOptions{}, func(team *gapi.Team) error {
+			items = append(items, asObject(team))
+			return nil
+		})
+		return items, err
+	},
+	get: func(c *gapi.Client, id string) (interface{}, error) {
+		return teamByID(c, id)
+	},
+	apply: func(c *gapi.Client, data []byte) (string, error) {
+		team := gapi.Team{}
+		if err := json.Unmarshal(data, &team); err != nil {
+			return "", err
+		}
+		result, err := c.SearchTeam(team.Name)
+		if err != nil {
+			return "", err
+		}
+		for _, existing := range result.Teams {
+			if existing.Name == team.Name {
+				return team.Name, c.UpdateTeam(existing.ID, team.Name, team.Email)
+			}
+		}
+		_, err = c.AddTeam(team.Name, team.Email)
+		return team.Name, err
+	},
+	delete: func(c *gapi.Client, id string) error {
+		team, err := teamByID(c, id)
+		if err != nil {
+			return err
+		}
+		return c.DeleteTeam(team.ID)
+	},
+	id: func(item interface{}) string {
+		return field(item, "name")
+	},
+	row: func(item interface{}) []string {
+		return []string{field(item, "id"), field(item, "name"), field(item, "email"), field(item, "memberCount")}
+	},
+}
@@ -12,6 +12,7 @@ type DashboardMeta struct {
 	IsStarred bool   `json:"isStarred"`
 	Slug      string `json:"slug"`
 	Folder    int64  `json:"folderId"`
+	FolderUID string `json:"folderUid"`
 	URL       string `json:"url"`
 }
 
@@ -75,32 +76,38 @@ func (c *Client) NewDashboard(dashboard Dashboard) (*DashboardSaveResponse, erro
 
 // Dashboards fetches and returns all dashboards.
 func (c *Client) Dashboards() ([]FolderDashboardSearchResponse, error) {
-	const limit = 1000
-
-	var (
-		page          = 0
-		newDashboards []FolderDashboardSearchResponse
-		dashboards    []FolderDashboardSearchResponse
-		query         = make(url.Values)
-	)
+	dashboards := []FolderDashboardSearchResponse{}
+	err := c.ForEachDashboard(PageOptions{}, func(dashboard FolderDashboardSearchResponse) error {
+		dashboards = append(dashboards, dashboard)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	query.Set("type", "dash-db")
-	query.Set("limit", fmt.Sprint(limit))
+	return dashboards, nil
+}
 
-	for {
-		page++
+// ForEachDashboard calls fn for each dashboard, fetching them a page at a time.
+// fn can return ErrStopIteration to stop early.
+func (c *Client) ForEachDashboard(opts PageOptions, fn func(FolderDashboardSearchResponse) error) error {
+	return paginate(opts, 1000, func(page, perPage int) (int, int64, error) {
+		query := url.Values{}
+		query.Set("type", "dash-db")
+		query.Set("limit", fmt.Sprint(perPage))
 		query.Set("page", fmt.Sprint(page))
 
-		if err := c.request("GET", "/api/search", query, nil, &newDashboards); err != nil {
-			return nil, err
+		var dashboards []FolderDashboardSearchResponse
+		if err := c.request("GET", "/api/search", query, nil, &dashboards); err != nil {
+			return 0, 0, err
 		}
-
-		dashboards = append(dashboards, newDashboards...)
-
-		if len(newDashboards) < limit {
-			return dashboards, nil
+		for _, dashboard := range dashboards {
+			if err := fn(dashboard); err != nil {
+				return 0, 0, err
+			}
 		}
-	}
+		return len(dashboards), -1, nil
+	})
 }
 
 // Dashboard will be removed.
@@ -136,6 +143,7 @@ func (c *Client) dashboard(path string) (*Dashboard, error) {
 		return nil, err
 	}
 	result.FolderID = result.Meta.Folder
+	result.FolderUID = result.Meta.FolderUID
 
 	return result, err
 }
@@ -0,0 +1,253 @@
+package gapi
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// builtInDataSources are the UIDs and names of the data sources which exist in every Grafana instance,
+// and which exported dashboards can keep referencing.
+var builtInDataSources = map[string]bool{
+	"grafana":         true,
+	"-- Grafana --":   true,
+	"-- Mixed --":     true,
+	"-- Dashboard --": true,
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// ExportDashboard fetches a dashboard and returns it in the format of Grafana's "export for sharing":
+// see ExportDashboardModel.
+func (c *Client) ExportDashboard(uid string) (map[string]interface{}, error) {
+	dashboard, err := c.DashboardByUID(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.ExportDashboardModel(dashboard.Model)
+}
+
+// ExportDashboardModel returns a copy of a dashboard model, portable to other Grafana instances
+// like with Grafana's "export for sharing": the data sources it references are replaced
+// with inputs listed in __inputs, the plugins it uses are listed in __requires, and its ID is removed.
+// The dashboard can then be imported with ImportSharedDashboard.
+func (c *Client) ExportDashboardModel(model map[string]interface{}) (map[string]interface{}, error) {
+	typed, err := NewDashboardModel(model)
+	if err != nil {
+		return nil, err
+	}
+
+	exporter := &dashboardExporter{
+		client:      c,
+		inputs:      map[string]*DashboardInput{},
+		dataSources: map[string]*DataSource{},
+		requires:    map[string]string{},
+	}
+	if err := exporter.export(typed); err != nil {
+		return nil, err
+	}
+
+	exported, err := typed.Map()
+	if err != nil {
+		return nil, err
+	}
+	delete(exported, "id")
+
+	requirements, err := exporter.requirements()
+	if err != nil {
+		return nil, err
+	}
+	exported["__inputs"] = exporter.sortedInputs()
+	exported["__requires"] = requirements
+
+	// Return the inputs and requirements as JSON values, like the rest of the model.
+	normalized, err := normalizeJSON(exported)
+	if err != nil {
+		return nil, err
+	}
+	return normalized.(map[string]interface{}), nil
+}
+
+type dashboardExporter struct {
+	client *Client
+	// inputs are the inputs replacing data sources, by data source UID.
+	inputs map[string]*DashboardInput
+	// dataSources are the data sources already fetched, by UID and by name.
+	dataSources map[string]*DataSource
+	// requires maps the plugins the dashboard requires to their types.
+	requires map[string]string
+}
+
+func (e *dashboardExporter) export(model *DashboardModel) error {
+	for _, panel := range model.AllPanels() {
+		if panel.Type != "row" && panel.LibraryPanel == nil {
+			e.requires[panel.Type] = "panel"
+		}
+		if err := e.replace(panel.Datasource); err != nil {
+			return err
+		}
+		for _, target := range panel.Targets {
+			if err := e.replace(target.Datasource); err != nil {
+				return err
+			}
+		}
+	}
+
+	if model.Templating != nil {
+		for _, variable := range model.Templating.List {
+			if variable.Type == "datasource" {
+				if pluginID, ok := variable.Query.(string); ok && pluginID != "" {
+					e.requires[pluginID] = "datasource"
+				}
+			}
+			if err := e.replace(variable.Datasource); err != nil {
+				return err
+			}
+		}
+	}
+
+	if model.Annotations != nil {
+		for _, annotation := range model.Annotations.List {
+			if err := e.replace(annotation.Datasource); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// replace replaces a reference to a data source with a reference to an input.
+func (e *dashboardExporter) replace(ref *DashboardDataSourceRef) error {
+	if ref == nil {
+		return nil
+	}
+
+	key := ref.UID
+	if key == "" {
+		key = ref.Name
+	}
+	// Keep built-in data sources, variables and references to the default data source.
+	if key == "" || builtInDataSources[key] || ref.Type == "datasource" || strings.HasPrefix(key, "$") {
+		return nil
+	}
+
+	ds, err := e.dataSource(ref)
+	if err != nil {
+		return err
+	}
+	e.requires[ds.Type] = "datasource"
+
+	input, exists := e.inputs[ds.UID]
+	if !exists {
+		input = &DashboardInput{
+			Name:       e.inputName(ds),
+			Label:      ds.Name,
+			Type:       "datasource",
+			PluginID:   ds.Type,
+			PluginName: ds.Type,
+		}
+		e.inputs[ds.UID] = input
+	}
+
+	placeholder := "${" + input.Name + "}"
+	if ref.UID != "" {
+		ref.UID = placeholder
+		ref.Type = ds.Type
+	} else {
+		ref.Name = placeholder
+	}
+	return nil
+}
+
+// inputName names the input replacing a data source after its name, like Grafana.
+func (e *dashboardExporter) inputName(ds *DataSource) string {
+	base := "DS_" + strings.ToUpper(strings.Trim(nonAlphanumeric.ReplaceAllString(ds.Name, "_"), "_"))
+	name := base
+	for i := 2; ; i++ {
+		taken := false
+		for _, input := range e.inputs {
+			taken = taken || input.Name == name
+		}
+		if !taken {
+			return name
+		}
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+}
+
+func (e *dashboardExporter) dataSource(ref *DashboardDataSourceRef) (*DataSource, error) {
+	key := ref.UID
+	if key == "" {
+		key = ref.Name
+	}
+	if ds, exists := e.dataSources[key]; exists {
+		return ds, nil
+	}
+
+	var ds *DataSource
+	var err error
+	if ref.UID != "" {
+		ds, err = e.client.DataSourceByUID(ref.UID)
+	} else {
+		ds, err = e.client.DataSourceByName(ref.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	e.dataSources[key] = ds
+	return ds, nil
+}
+
+func (e *dashboardExporter) sortedInputs() []DashboardInput {
+	inputs := make([]DashboardInput, 0, len(e.inputs))
+	for _, input := range e.inputs {
+		inputs = append(inputs, *input)
+	}
+	sort.Slice(inputs, func(i, j int) bool { return inputs[i].Name < inputs[j].Name })
+	return inputs
+}
+
+// requirements lists the Grafana version and the plugins required by the dashboard, with their installed versions.
+// It also sets the names of the plugins of the inputs.
+func (e *dashboardExporter) requirements() ([]DashboardRequirement, error) {
+	health, err := e.client.Health()
+	if err != nil {
+		return nil, err
+	}
+	plugins, err := e.client.InstalledPlugins()
+	if err != nil {
+		return nil, err
+	}
+	installed := map[string]InstalledPlugin{}
+	for _, plugin := range plugins {
+		installed[plugin.ID] = plugin
+	}
+	for _, input := range e.inputs {
+		if plugin, exists := installed[input.PluginID]; exists {
+			input.PluginName = plugin.Name
+		}
+	}
+
+	requirements := make([]DashboardRequirement, 0, len(e.requires))
+	for id, pluginType := range e.requires {
+		requirement := DashboardRequirement{Type: pluginType, ID: id, Name: id}
+		if plugin, exists := installed[id]; exists {
+			requirement.Name = plugin.Name
+			requirement.Version = plugin.Info.Version
+		}
+		requirements = append(requirements, requirement)
+	}
+	sort.Slice(requirements, func(i, j int) bool {
+		if requirements[i].Type != requirements[j].Type {
+			return requirements[i].Type < requirements[j].Type
+		}
+		return requirements[i].ID < requirements[j].ID
+	})
+
+	grafana := DashboardRequirement{Type: "grafana", ID: "grafana", Name: "Grafana", Version: health.Version}
+	return append([]DashboardRequirement{grafana}, requirements...), nil
+}
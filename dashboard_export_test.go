@@ -0,0 +1,88 @@
+package gapi
+
+import (
+	"testing"
+
+	"github.com/gobs/pretty"
+)
+
+const exportDashboardJSON = `{
+	"meta": {"slug": "services"},
+	"dashboard": {
+		"id": 12,
+		"uid": "services",
+		"title": "Services",
+		"panels": [
+			{"id": 1, "type": "timeseries", "datasource": {"type": "prometheus", "uid": "prom"}, "targets": [{"refId": "A", "datasource": {"type": "prometheus", "uid": "prom"}, "expr": "up"}]},
+			{"id": 2, "type": "row", "collapsed": true, "panels": [
+				{"id": 3, "type": "graph", "datasource": "Logs"},
+				{"id": 4, "type": "table", "datasource": {"type": "datasource", "uid": "-- Mixed --"}, "targets": [{"refId": "A", "datasource": {"uid": "${ds}"}}]}
+			]}
+		],
+		"templating": {"list": [{"name": "ds", "type": "datasource", "query": "prometheus"}]},
+		"annotations": {"list": [{"name": "Annotations & Alerts", "builtIn": 1, "enable": true, "datasource": {"type": "grafana", "uid": "-- Grafana --"}}]}
+	}
+}`
+
+func TestExportDashboard(t *testing.T) {
+	client := gapiTestToolsFromCalls(t, []mockServerCall{
+		{200, exportDashboardJSON},
+		{200, `{"id": 1, "uid": "prom", "name": "Prometheus (prod)", "type": "prometheus"}`},
+		{200, `{"id": 2, "uid": "logs", "name": "Logs", "type": "loki"}`},
+		{200, `{"version": "10.2.0"}`},
+		{200, `[{"id": "prometheus", "name": "Prometheus", "type": "datasource", "info": {"version": "1.0.0"}}, {"id": "timeseries", "name": "Time series", "type": "panel", "info": {"version": "10.2.0"}}]`},
+	})
+
+	exported, err := client.ExportDashboard("services")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Log(pretty.PrettyFormat(exported))
+
+	if _, exists := exported["id"]; exists {
+		t.Error("the ID should be removed")
+	}
+
+	inputs, err := DashboardInputs(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) != 2 || inputs[0].Name != "DS_LOGS" || inputs[1].Name != "DS_PROMETHEUS_PROD" || inputs[1].PluginName != "Prometheus" {
+		t.Errorf("unexpected inputs: %+v", inputs)
+	}
+
+	requirements, err := DashboardRequirements(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []DashboardRequirement{
+		{Type: "grafana", ID: "grafana", Name: "Grafana", Version: "10.2.0"},
+		{Type: "datasource", ID: "loki", Name: "loki"},
+		{Type: "datasource", ID: "prometheus", Name: "Prometheus", Version: "1.0.0"},
+		{Type: "panel", ID: "graph", Name: "graph"},
+		{Type: "panel", ID: "table", Name: "table"},
+		{Type: "panel", ID: "timeseries", Name: "Time series", Version: "10.2.0"},
+	}
+	if pretty.PrettyFormat(requirements) != pretty.PrettyFormat(expected) {
+		t.Errorf("unexpected requirements: %s", pretty.PrettyFormat(requirements))
+	}
+
+	model, err := NewDashboardModel(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	panels := model.AllPanels()
+	if panels[0].Datasource.UID != "${DS_PROMETHEUS_PROD}" || panels[0].Targets[0].Datasource.UID != "${DS_PROMETHEUS_PROD}" {
+		t.Errorf("the Prometheus data source wasn't replaced: %+v", panels[0])
+	}
+	if panels[2].Datasource.Name != "${DS_LOGS}" {
+		t.Errorf("the data source referenced by name wasn't replaced: %+v", panels[2].Datasource)
+	}
+	if panels[3].Datasource.UID != "-- Mixed --" || panels[3].Targets[0].Datasource.UID != "${ds}" {
+		t.Errorf("built-in data sources and variables should be kept: %+v", panels[3])
+	}
+	if model.Annotations.List[0].Datasource.UID != "-- Grafana --" {
+		t.Errorf("the built-in annotation data source should be kept: %+v", model.Annotations.List[0].Datasource)
+	}
+}
@@ -0,0 +1,285 @@
+package gapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DashboardInput is an input of a dashboard exported for sharing, as listed in its __inputs.
+// Inputs are referenced as ${NAME} in the dashboard.
+type DashboardInput struct {
+	Name        string `json:"name"`
+	Label       string `json:"label,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Type is "datasource" or "constant".
+	Type       string `json:"type"`
+	PluginID   string `json:"pluginId,omitempty"`
+	PluginName string `json:"pluginName,omitempty"`
+	// Value is the default value of constant inputs.
+	Value string `json:"value,omitempty"`
+}
+
+// DashboardRequirement is a plugin or Grafana version required by a dashboard exported for sharing,
+// as listed in its __requires.
+type DashboardRequirement struct {
+	// Type is "grafana", "datasource", "panel" or "app".
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// DashboardImportInput is the value of an input of an imported dashboard.
+// The value of data source inputs is the UID of a data source.
+type DashboardImportInput struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	PluginID string `json:"pluginId,omitempty"`
+	Value    string `json:"value"`
+}
+
+// DashboardImport represents a request to import a dashboard.
+type DashboardImport struct {
+	Dashboard map[string]interface{} `json:"dashboard"`
+	Inputs    []DashboardImportInput `json:"inputs"`
+	FolderUID string                 `json:"folderUid,omitempty"`
+	Overwrite bool                   `json:"overwrite"`
+}
+
+// DashboardImportResponse represents the Grafana API response to importing a dashboard.
+type DashboardImportResponse struct {
+	UID              string `json:"uid"`
+	PluginID         string `json:"pluginId"`
+	Title            string `json:"title"`
+	Imported         bool   `json:"imported"`
+	ImportedURI      string `json:"importedUri"`
+	ImportedURL      string `json:"importedUrl"`
+	Slug             string `json:"slug"`
+	DashboardID      int64  `json:"dashboardId"`
+	FolderID         int64  `json:"folderId"`
+	FolderUID        string `json:"folderUid"`
+	ImportedRevision int64  `json:"importedRevision"`
+	Revision         int64  `json:"revision"`
+	Description      string `json:"description"`
+	Path             string `json:"path"`
+	Removed          bool   `json:"removed"`
+}
+
+// DashboardImportOptions configures the import of a dashboard exported for sharing.
+type DashboardImportOptions struct {
+	FolderUID string
+	Overwrite bool
+	// Inputs are the values of the inputs of the dashboard, by name. The value of a data source input is
+	// the name or UID of a data source. When it isn't given, the default data source of the type
+	// required by the input is used, or else the first one. Constant inputs default to their value.
+	Inputs map[string]string
+}
+
+// ImportDashboard imports a dashboard through the import API, which substitutes its inputs.
+func (c *Client) ImportDashboard(dashboard DashboardImport) (*DashboardImportResponse, error) {
+	data, err := json.Marshal(dashboard)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DashboardImportResponse{}
+	err = c.request("POST", "/api/dashboards/import", nil, bytes.NewBuffer(data), result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ImportSharedDashboard imports a dashboard exported for sharing, after checking that the plugins it
+// requires are installed and resolving its inputs.
+func (c *Client) ImportSharedDashboard(model map[string]interface{}, opts DashboardImportOptions) (*DashboardImportResponse, error) {
+	if err := c.CheckDashboardRequirements(model); err != nil {
+		return nil, err
+	}
+
+	inputs, err := c.ResolveDashboardInputs(model, opts.Inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.ImportDashboard(DashboardImport{
+		Dashboard: model,
+		Inputs:    inputs,
+		FolderUID: opts.FolderUID,
+		Overwrite: opts.Overwrite,
+	})
+}
+
+// CheckDashboardRequirements returns an error listing the plugins required by a dashboard
+// exported for sharing which aren't installed. The required Grafana version isn't checked.
+func (c *Client) CheckDashboardRequirements(model map[string]interface{}) error {
+	requirements, err := DashboardRequirements(model)
+	if err != nil || len(requirements) == 0 {
+		return err
+	}
+
+	plugins, err := c.InstalledPlugins()
+	if err != nil {
+		return err
+	}
+	installed := map[string]bool{}
+	for _, plugin := range plugins {
+		installed[plugin.ID] = true
+	}
+
+	var missing []string
+	for _, requirement := range requirements {
+		if requirement.Type != "grafana" && !installed[requirement.ID] {
+			missing = append(missing, fmt.Sprintf("%s %s", requirement.Type, requirement.ID))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("the dashboard requires plugins which aren't installed: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// ResolveDashboardInputs resolves the values of the inputs of a dashboard exported for sharing.
+// values are the values given for the inputs, by name, as described in DashboardImportOptions.
+func (c *Client) ResolveDashboardInputs(model map[string]interface{}, values map[string]string) ([]DashboardImportInput, error) {
+	inputs, err := DashboardInputs(model)
+	if err != nil || len(inputs) == 0 {
+		return []DashboardImportInput{}, err
+	}
+
+	var dataSources []*DataSource
+	resolved := make([]DashboardImportInput, 0, len(inputs))
+	for _, input := range inputs {
+		value, given := values[input.Name]
+
+		switch input.Type {
+		case "datasource":
+			if dataSources == nil {
+				if dataSources, err = c.DataSources(); err != nil {
+					return nil, err
+				}
+			}
+			ds := findInputDataSource(dataSources, input.PluginID, value, given)
+			if ds == nil {
+				if given {
+					return nil, fmt.Errorf("input %s: data source %q of type %s not found", input.Name, value, input.PluginID)
+				}
+				return nil, fmt.Errorf("input %s: no data source of type %s", input.Name, input.PluginID)
+			}
+			value = ds.UID
+		case "constant":
+			if !given {
+				value = input.Value
+			}
+		default:
+			return nil, fmt.Errorf("input %s: unsupported type %q", input.Name, input.Type)
+		}
+
+		resolved = append(resolved, DashboardImportInput{
+			Name:     input.Name,
+			Type:     input.Type,
+			PluginID: input.PluginID,
+			Value:    value,
+		})
+	}
+
+	return resolved, nil
+}
+
+func findInputDataSource(dataSources []*DataSource, pluginID, value string, given bool) *DataSource {
+	var first *DataSource
+	for _, ds := range dataSources {
+		if ds.Type != pluginID {
+			continue
+		}
+		if given {
+			if ds.UID == value || ds.Name == value {
+				return ds
+			}
+			continue
+		}
+		if ds.IsDefault {
+			return ds
+		}
+		if first == nil {
+			first = ds
+		}
+	}
+	return first
+}
+
+// DashboardInputs returns the inputs of a dashboard exported for sharing.
+func DashboardInputs(model map[string]interface{}) ([]DashboardInput, error) {
+	inputs := []DashboardInput{}
+	err := decodeDashboardField(model, "__inputs", &inputs)
+	return inputs, err
+}
+
+// DashboardRequirements returns the requirements of a dashboard exported for sharing.
+func DashboardRequirements(model map[string]interface{}) ([]DashboardRequirement, error) {
+	requirements := []DashboardRequirement{}
+	err := decodeDashboardField(model, "__requires", &requirements)
+	return requirements, err
+}
+
+func decodeDashboardField(model map[string]interface{}, key string, v interface{}) error {
+	field, exists := model[key]
+	if !exists {
+		return nil
+	}
+
+	data, err := json.Marshal(field)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return nil
+}
+
+// SubstituteDashboardInputs returns a copy of a dashboard exported for sharing, with its inputs
+// replaced by their values and without its __inputs and __requires, ready to be saved with NewDashboard.
+// This does locally what the import API does.
+func SubstituteDashboardInputs(model map[string]interface{}, inputs []DashboardImportInput) (map[string]interface{}, error) {
+	pairs := make([]string, 0, 2*len(inputs))
+	for _, input := range inputs {
+		pairs = append(pairs, "${"+input.Name+"}", input.Value)
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	substituted, ok := substituteJSON(model, replacer).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid dashboard")
+	}
+	delete(substituted, "__inputs")
+	delete(substituted, "__requires")
+	delete(substituted, "__elements")
+
+	return substituted, nil
+}
+
+func substituteJSON(v interface{}, replacer *strings.Replacer) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		substituted := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			substituted[key] = substituteJSON(item, replacer)
+		}
+		return substituted
+	case []interface{}:
+		substituted := make([]interface{}, len(value))
+		for i, item := range value {
+			substituted[i] = substituteJSON(item, replacer)
+		}
+		return substituted
+	case string:
+		return replacer.Replace(value)
+	default:
+		return value
+	}
+}
@@ -0,0 +1,152 @@
+package gapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gobs/pretty"
+)
+
+const (
+	sharedDashboardJSON = `{
+	"__inputs": [
+		{"name": "DS_PROMETHEUS", "label": "Prometheus", "type": "datasource", "pluginId": "prometheus", "pluginName": "Prometheus"},
+		{"name": "VAR_ENV", "label": "Environment", "type": "constant", "value": "dev"}
+	],
+	"__requires": [
+		{"type": "grafana", "id": "grafana", "name": "Grafana", "version": "10.2.0"},
+		{"type": "datasource", "id": "prometheus", "name": "Prometheus", "version": "1.0.0"},
+		{"type": "panel", "id": "timeseries", "name": "Time series", "version": ""}
+	],
+	"title": "Shared",
+	"uid": "shared",
+	"panels": [
+		{"id": 1, "type": "timeseries", "title": "Up in ${VAR_ENV}", "datasource": {"type": "prometheus", "uid": "${DS_PROMETHEUS}"}}
+	]
+}`
+	importDashboardDataSourcesJSON = `[
+	{"id": 1, "uid": "loki", "name": "Loki", "type": "loki", "isDefault": true},
+	{"id": 2, "uid": "prom-1", "name": "Prometheus 1", "type": "prometheus"},
+	{"id": 3, "uid": "prom-2", "name": "Prometheus 2", "type": "prometheus", "isDefault": false}
+]`
+	importDashboardPluginsJSON  = `[{"id": "prometheus", "type": "datasource"}, {"id": "graph", "type": "panel"}]`
+	importDashboardResponseJSON = `{"uid": "shared", "title": "Shared", "imported": true, "importedUrl": "/d/shared/shared", "slug": "shared", "dashboardId": 5, "folderUid": "folder"}`
+)
+
+func sharedDashboard(t *testing.T) map[string]interface{} {
+	t.Helper()
+
+	model := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(sharedDashboardJSON), &model); err != nil {
+		t.Fatal(err)
+	}
+	return model
+}
+
+func TestImportDashboard(t *testing.T) {
+	client := gapiTestTools(t, 200, importDashboardResponseJSON)
+
+	resp, err := client.ImportDashboard(DashboardImport{Dashboard: sharedDashboard(t), FolderUID: "folder"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Log(pretty.PrettyFormat(resp))
+
+	if resp.UID != "shared" || !resp.Imported || resp.DashboardID != 5 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestResolveDashboardInputs(t *testing.T) {
+	cases := []struct {
+		name     string
+		values   map[string]string
+		expected []string
+	}{
+		{name: "defaults", expected: []string{"prom-1", "dev"}},
+		{name: "by name", values: map[string]string{"DS_PROMETHEUS": "Prometheus 2", "VAR_ENV": "prod"}, expected: []string{"prom-2", "prod"}},
+		{name: "by UID", values: map[string]string{"DS_PROMETHEUS": "prom-2"}, expected: []string{"prom-2", "dev"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := gapiTestTools(t, 200, importDashboardDataSourcesJSON)
+
+			inputs, err := client.ResolveDashboardInputs(sharedDashboard(t), tc.values)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(inputs) != 2 || inputs[0].Value != tc.expected[0] || inputs[1].Value != tc.expected[1] {
+				t.Errorf("unexpected inputs: %+v", inputs)
+			}
+		})
+	}
+}
+
+func TestResolveDashboardInputsNotFound(t *testing.T) {
+	client := gapiTestTools(t, 200, importDashboardDataSourcesJSON)
+
+	_, err := client.ResolveDashboardInputs(sharedDashboard(t), map[string]string{"DS_PROMETHEUS": "Loki"})
+	if err == nil || !strings.Contains(err.Error(), "DS_PROMETHEUS") {
+		t.Errorf("expected an error about the input, got %v", err)
+	}
+}
+
+func TestCheckDashboardRequirements(t *testing.T) {
+	client := gapiTestTools(t, 200, importDashboardPluginsJSON)
+
+	err := client.CheckDashboardRequirements(sharedDashboard(t))
+	if err == nil || !strings.Contains(err.Error(), "panel timeseries") || strings.Contains(err.Error(), "prometheus") {
+		t.Errorf("expected an error about the missing panel plugin, got %v", err)
+	}
+}
+
+func TestImportSharedDashboard(t *testing.T) {
+	client := gapiTestToolsFromCalls(t, []mockServerCall{
+		{200, `[{"id": "prometheus", "type": "datasource"}, {"id": "timeseries", "type": "panel"}]`},
+		{200, importDashboardDataSourcesJSON},
+		{200, importDashboardResponseJSON},
+	})
+
+	resp, err := client.ImportSharedDashboard(sharedDashboard(t), DashboardImportOptions{FolderUID: "folder"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.FolderUID != "folder" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestSubstituteDashboardInputs(t *testing.T) {
+	model := sharedDashboard(t)
+
+	substituted, err := SubstituteDashboardInputs(model, []DashboardImportInput{
+		{Name: "DS_PROMETHEUS", Type: "datasource", PluginID: "prometheus", Value: "prom-1"},
+		{Name: "VAR_ENV", Type: "constant", Value: "prod"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Log(pretty.PrettyFormat(substituted))
+
+	if _, exists := substituted["__inputs"]; exists {
+		t.Error("__inputs should be removed")
+	}
+	if _, exists := substituted["__requires"]; exists {
+		t.Error("__requires should be removed")
+	}
+	panel := substituted["panels"].([]interface{})[0].(map[string]interface{})
+	if panel["title"] != "Up in prod" || panel["datasource"].(map[string]interface{})["uid"] != "prom-1" {
+		t.Errorf("unexpected panel: %v", panel)
+	}
+
+	// The original model isn't changed.
+	if _, exists := model["__inputs"]; !exists {
+		t.Error("the original model was changed")
+	}
+}
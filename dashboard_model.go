@@ -0,0 +1,670 @@
+package gapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// DashboardModel is a typed representation of the JSON model of a Grafana dashboard,
+// which is found in Dashboard.Model.
+//
+// Each type of the model keeps the fields it doesn't know in its Extra field,
+// so that plugin-specific options and fields of newer schema versions survive a round trip.
+type DashboardModel struct {
+	ID                   int64                      `json:"id,omitempty"`
+	UID                  string                     `json:"uid,omitempty"`
+	Title                string                     `json:"title"`
+	Description          string                     `json:"description,omitempty"`
+	Tags                 []string                   `json:"tags,omitempty"`
+	Timezone             string                     `json:"timezone,omitempty"`
+	Editable             *bool                      `json:"editable,omitempty"`
+	GraphTooltip         int64                      `json:"graphTooltip,omitempty"`
+	Time                 *DashboardTime             `json:"time,omitempty"`
+	Refresh              string                     `json:"refresh,omitempty"`
+	FiscalYearStartMonth int64                      `json:"fiscalYearStartMonth,omitempty"`
+	WeekStart            string                     `json:"weekStart,omitempty"`
+	SchemaVersion        int64                      `json:"schemaVersion,omitempty"`
+	Version              int64                      `json:"version,omitempty"`
+	Panels               []*DashboardPanel          `json:"panels,omitempty"`
+	Templating           *DashboardTemplating       `json:"templating,omitempty"`
+	Annotations          *DashboardAnnotations      `json:"annotations,omitempty"`
+	Links                []*DashboardLink           `json:"links,omitempty"`
+	Extra                map[string]json.RawMessage `json:"-"`
+}
+
+// DashboardTime is the default time range of a dashboard.
+type DashboardTime struct {
+	From  string                     `json:"from"`
+	To    string                     `json:"to"`
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// DashboardPanel represents a panel of a dashboard. Rows are panels of type "row":
+// the panels of a collapsed row are in its Panels field.
+type DashboardPanel struct {
+	ID              int64                      `json:"id,omitempty"`
+	Type            string                     `json:"type"`
+	Title           string                     `json:"title,omitempty"`
+	Description     string                     `json:"description,omitempty"`
+	GridPos         *DashboardGridPos          `json:"gridPos,omitempty"`
+	Datasource      *DashboardDataSourceRef    `json:"datasource,omitempty"`
+	Targets         []*DashboardTarget         `json:"targets,omitempty"`
+	FieldConfig     *DashboardFieldConfig      `json:"fieldConfig,omitempty"`
+	Options         map[string]interface{}     `json:"options,omitempty"`
+	Transparent     bool                       `json:"transparent,omitempty"`
+	Interval        string                     `json:"interval,omitempty"`
+	MaxDataPoints   int64                      `json:"maxDataPoints,omitempty"`
+	Repeat          string                     `json:"repeat,omitempty"`
+	RepeatDirection string                     `json:"repeatDirection,omitempty"`
+	LibraryPanel    *DashboardLibraryPanelRef  `json:"libraryPanel,omitempty"`
+	Collapsed       bool                       `json:"collapsed,omitempty"`
+	Panels          []*DashboardPanel          `json:"panels,omitempty"`
+	Extra           map[string]json.RawMessage `json:"-"`
+}
+
+// DashboardGridPos is the position and size of a panel in the dashboard grid.
+type DashboardGridPos struct {
+	H     int64                      `json:"h"`
+	W     int64                      `json:"w"`
+	X     int64                      `json:"x"`
+	Y     int64                      `json:"y"`
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// DashboardLibraryPanelRef references the library panel a panel is linked to.
+type DashboardLibraryPanelRef struct {
+	UID   string                     `json:"uid"`
+	Name  string                     `json:"name,omitempty"`
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// DashboardDataSourceRef references a data source from a panel, a target, a variable or an annotation.
+type DashboardDataSourceRef struct {
+	Type string `json:"type,omitempty"`
+	UID  string `json:"uid,omitempty"`
+	// Name is set instead of Type and UID by dashboards from before Grafana 8.3,
+	// which reference data sources by name.
+	Name  string                     `json:"-"`
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// DashboardTarget is a query of a panel. The query itself depends on the data source
+// and is kept in Extra, e.g. Extra["expr"] for Prometheus.
+type DashboardTarget struct {
+	RefID      string                     `json:"refId,omitempty"`
+	Datasource *DashboardDataSourceRef    `json:"datasource,omitempty"`
+	Hide       bool                       `json:"hide,omitempty"`
+	QueryType  string                     `json:"queryType,omitempty"`
+	Extra      map[string]json.RawMessage `json:"-"`
+}
+
+// DashboardFieldConfig configures how the fields returned by the queries of a panel are displayed.
+type DashboardFieldConfig struct {
+	Defaults  DashboardFieldConfigDefaults    `json:"defaults"`
+	Overrides []*DashboardFieldConfigOverride `json:"overrides"`
+	Extra     map[string]json.RawMessage      `json:"-"`
+}
+
+// DashboardFieldConfigDefaults is the configuration applied to all the fields of a panel.
+type DashboardFieldConfigDefaults struct {
+	Unit        string                     `json:"unit,omitempty"`
+	Decimals    *int64                     `json:"decimals,omitempty"`
+	Min         *float64                   `json:"min,omitempty"`
+	Max         *float64                   `json:"max,omitempty"`
+	DisplayName string                     `json:"displayName,omitempty"`
+	NoValue     string                     `json:"noValue,omitempty"`
+	Color       map[string]interface{}     `json:"color,omitempty"`
+	Thresholds  *DashboardThresholds       `json:"thresholds,omitempty"`
+	Mappings    []map[string]interface{}   `json:"mappings,omitempty"`
+	Links       []map[string]interface{}   `json:"links,omitempty"`
+	Custom      map[string]interface{}     `json:"custom,omitempty"`
+	Extra       map[string]json.RawMessage `json:"-"`
+}
+
+// DashboardThresholds are the thresholds of a field.
+type DashboardThresholds struct {
+	Mode  string                     `json:"mode"`
+	Steps []*DashboardThresholdStep  `json:"steps"`
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// DashboardThresholdStep is a threshold. The value of the first step is null, meaning minus infinity.
+type DashboardThresholdStep struct {
+	Color string                     `json:"color"`
+	Value *float64                   `json:"value"`
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// DashboardFieldConfigOverride overrides the configuration of the fields matched by Matcher.
+type DashboardFieldConfigOverride struct {
+	Matcher    DashboardMatcher             `json:"matcher"`
+	Properties []*DashboardOverrideProperty `json:"properties"`
+	Extra      map[string]json.RawMessage   `json:"-"`
+}
+
+// DashboardMatcher selects fields, e.g. by name with the "byName" matcher.
+type DashboardMatcher struct {
+	ID      string                     `json:"id"`
+	Options interface{}                `json:"options,omitempty"`
+	Extra   map[string]json.RawMessage `json:"-"`
+}
+
+// DashboardOverrideProperty is a property set by an override, e.g. "unit".
+type DashboardOverrideProperty struct {
+	ID    string                     `json:"id"`
+	Value interface{}                `json:"value"`
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// DashboardTemplating holds the template variables of a dashboard.
+type DashboardTemplating struct {
+	List  []*DashboardVariable       `json:"list"`
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// DashboardVariable is a template variable. Query is a string or, for some data sources, an object.
+type DashboardVariable struct {
+	Name        string                     `json:"name"`
+	Type        string                     `json:"type"`
+	Label       string                     `json:"label,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	Hide        int64                      `json:"hide,omitempty"`
+	Datasource  *DashboardDataSourceRef    `json:"datasource,omitempty"`
+	Query       interface{}                `json:"query,omitempty"`
+	Definition  string                     `json:"definition,omitempty"`
+	Regex       string                     `json:"regex,omitempty"`
+	Refresh     int64                      `json:"refresh,omitempty"`
+	Sort        int64                      `json:"sort,omitempty"`
+	Multi       bool                       `json:"multi,omitempty"`
+	IncludeAll  bool                       `json:"includeAll,omitempty"`
+	AllValue    string                     `json:"allValue,omitempty"`
+	Current     *DashboardVariableOption   `json:"current,omitempty"`
+	Options     []*DashboardVariableOption `json:"options,omitempty"`
+	Extra       map[string]json.RawMessage `json:"-"`
+}
+
+// DashboardVariableOption is a value of a template variable. Text and Value are strings,
+// or lists of strings for variables with multiple values selected.
+type DashboardVariableOption struct {
+	Text     interface{}                `json:"text"`
+	Value    interface{}                `json:"value"`
+	Selected bool                       `json:"selected,omitempty"`
+	Extra    map[string]json.RawMessage `json:"-"`
+}
+
+// DashboardAnnotations holds the annotation queries of a dashboard.
+type DashboardAnnotations struct {
+	List  []*DashboardAnnotation     `json:"list"`
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// DashboardAnnotation is an annotation query of a dashboard. BuiltIn is 1 for the
+// "Annotations & Alerts" query which Grafana adds to every dashboard.
+type DashboardAnnotation struct {
+	Name       string                     `json:"name"`
+	Datasource *DashboardDataSourceRef    `json:"datasource,omitempty"`
+	Enable     bool                       `json:"enable"`
+	Hide       bool                       `json:"hide,omitempty"`
+	IconColor  string                     `json:"iconColor,omitempty"`
+	BuiltIn    int64                      `json:"builtIn,omitempty"`
+	Type       string                     `json:"type,omitempty"`
+	Target     map[string]interface{}     `json:"target,omitempty"`
+	Extra      map[string]json.RawMessage `json:"-"`
+}
+
+// DashboardLink is a link shown at the top of a dashboard.
+type DashboardLink struct {
+	Title       string                     `json:"title"`
+	Type        string                     `json:"type"`
+	URL         string                     `json:"url,omitempty"`
+	Icon        string                     `json:"icon,omitempty"`
+	Tooltip     string                     `json:"tooltip,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	AsDropdown  bool                       `json:"asDropdown,omitempty"`
+	TargetBlank bool                       `json:"targetBlank,omitempty"`
+	IncludeVars bool                       `json:"includeVars,omitempty"`
+	KeepTime    bool                       `json:"keepTime,omitempty"`
+	Extra       map[string]json.RawMessage `json:"-"`
+}
+
+// NewDashboardModel converts the JSON model of a dashboard, as found in Dashboard.Model, to a DashboardModel.
+func NewDashboardModel(model map[string]interface{}) (*DashboardModel, error) {
+	data, err := json.Marshal(model)
+	if err != nil {
+		return nil, err
+	}
+
+	typed := &DashboardModel{}
+	if err := json.Unmarshal(data, typed); err != nil {
+		return nil, err
+	}
+
+	return typed, nil
+}
+
+// Map converts the dashboard model back to its JSON representation, to be set in Dashboard.Model.
+func (d *DashboardModel) Map() (map[string]interface{}, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+
+	model := map[string]interface{}{}
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, err
+	}
+
+	return model, nil
+}
+
+// TypedModel returns the typed model of the dashboard.
+func (d *Dashboard) TypedModel() (*DashboardModel, error) {
+	return NewDashboardModel(d.Model)
+}
+
+// SetTypedModel replaces the model of the dashboard.
+func (d *Dashboard) SetTypedModel(model *DashboardModel) error {
+	m, err := model.Map()
+	if err != nil {
+		return err
+	}
+	d.Model = m
+	return nil
+}
+
+// AllPanels returns the panels of the dashboard, including the ones nested in collapsed rows.
+func (d *DashboardModel) AllPanels() []*DashboardPanel {
+	var panels []*DashboardPanel
+	for _, panel := range d.Panels {
+		panels = append(panels, panel)
+		panels = append(panels, panel.Panels...)
+	}
+	return panels
+}
+
+// Variable returns the template variable with the given name, or nil if there is none.
+func (d *DashboardModel) Variable(name string) *DashboardVariable {
+	if d.Templating == nil {
+		return nil
+	}
+	for _, v := range d.Templating.List {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON keeps the fields which DashboardModel doesn't have in Extra.
+func (d *DashboardModel) UnmarshalJSON(data []byte) error {
+	type plain DashboardModel
+	extra, err := unmarshalWithExtra(data, (*plain)(d))
+	d.Extra = extra
+	return err
+}
+
+// MarshalJSON adds the fields kept in Extra.
+func (d DashboardModel) MarshalJSON() ([]byte, error) {
+	type plain DashboardModel
+	return marshalWithExtra(plain(d), d.Extra)
+}
+
+// UnmarshalJSON keeps the fields which DashboardTime doesn't have in Extra.
+func (t *DashboardTime) UnmarshalJSON(data []byte) error {
+	type plain DashboardTime
+	extra, err := unmarshalWithExtra(data, (*plain)(t))
+	t.Extra = extra
+	return err
+}
+
+// MarshalJSON adds the fields kept in Extra.
+func (t DashboardTime) MarshalJSON() ([]byte, error) {
+	type plain DashboardTime
+	return marshalWithExtra(plain(t), t.Extra)
+}
+
+// UnmarshalJSON keeps the fields which DashboardPanel doesn't have in Extra.
+func (p *DashboardPanel) UnmarshalJSON(data []byte) error {
+	type plain DashboardPanel
+	extra, err := unmarshalWithExtra(data, (*plain)(p))
+	p.Extra = extra
+	return err
+}
+
+// MarshalJSON adds the fields kept in Extra.
+func (p DashboardPanel) MarshalJSON() ([]byte, error) {
+	type plain DashboardPanel
+	return marshalWithExtra(plain(p), p.Extra)
+}
+
+// UnmarshalJSON keeps the fields which DashboardGridPos doesn't have in Extra.
+func (g *DashboardGridPos) UnmarshalJSON(data []byte) error {
+	type plain DashboardGridPos
+	extra, err := unmarshalWithExtra(data, (*plain)(g))
+	g.Extra = extra
+	return err
+}
+
+// MarshalJSON adds the fields kept in Extra.
+func (g DashboardGridPos) MarshalJSON() ([]byte, error) {
+	type plain DashboardGridPos
+	return marshalWithExtra(plain(g), g.Extra)
+}
+
+// UnmarshalJSON keeps the fields which DashboardLibraryPanelRef doesn't have in Extra.
+func (l *DashboardLibraryPanelRef) UnmarshalJSON(data []byte) error {
+	type plain DashboardLibraryPanelRef
+	extra, err := unmarshalWithExtra(data, (*plain)(l))
+	l.Extra = extra
+	return err
+}
+
+// MarshalJSON adds the fields kept in Extra.
+func (l DashboardLibraryPanelRef) MarshalJSON() ([]byte, error) {
+	type plain DashboardLibraryPanelRef
+	return marshalWithExtra(plain(l), l.Extra)
+}
+
+// UnmarshalJSON keeps the fields which DashboardTarget doesn't have in Extra.
+func (t *DashboardTarget) UnmarshalJSON(data []byte) error {
+	type plain DashboardTarget
+	extra, err := unmarshalWithExtra(data, (*plain)(t))
+	t.Extra = extra
+	return err
+}
+
+// MarshalJSON adds the fields kept in Extra.
+func (t DashboardTarget) MarshalJSON() ([]byte, error) {
+	type plain DashboardTarget
+	return marshalWithExtra(plain(t), t.Extra)
+}
+
+// UnmarshalJSON keeps the fields which DashboardFieldConfig doesn't have in Extra.
+func (f *DashboardFieldConfig) UnmarshalJSON(data []byte) error {
+	type plain DashboardFieldConfig
+	extra, err := unmarshalWithExtra(data, (*plain)(f))
+	f.Extra = extra
+	return err
+}
+
+// MarshalJSON adds the fields kept in Extra.
+func (f DashboardFieldConfig) MarshalJSON() ([]byte, error) {
+	type plain DashboardFieldConfig
+	return marshalWithExtra(plain(f), f.Extra)
+}
+
+// UnmarshalJSON keeps the fields which DashboardFieldConfigDefaults doesn't have in Extra.
+func (f *DashboardFieldConfigDefaults) UnmarshalJSON(data []byte) error {
+	type plain DashboardFieldConfigDefaults
+	extra, err := unmarshalWithExtra(data, (*plain)(f))
+	f.Extra = extra
+	return err
+}
+
+// MarshalJSON adds the fields kept in Extra.
+func (f DashboardFieldConfigDefaults) MarshalJSON() ([]byte, error) {
+	type plain DashboardFieldConfigDefaults
+	return marshalWithExtra(plain(f), f.Extra)
+}
+
+// UnmarshalJSON keeps the fields which DashboardThresholds doesn't have in Extra.
+func (t *DashboardThresholds) UnmarshalJSON(data []byte) error {
+	type plain DashboardThresholds
+	extra, err := unmarshalWithExtra(data, (*plain)(t))
+	t.Extra = extra
+	return err
+}
+
+// MarshalJSON adds the fields kept in Extra.
+func (t DashboardThresholds) MarshalJSON() ([]byte, error) {
+	type plain DashboardThresholds
+	return marshalWithExtra(plain(t), t.Extra)
+}
+
+// UnmarshalJSON keeps the fields which DashboardThresholdStep doesn't have in Extra.
+func (t *DashboardThresholdStep) UnmarshalJSON(data []byte) error {
+	type plain DashboardThresholdStep
+	extra, err := unmarshalWithExtra(data, (*plain)(t))
+	t.Extra = extra
+	return err
+}
+
+// MarshalJSON adds the fields kept in Extra.
+func (t DashboardThresholdStep) MarshalJSON() ([]byte, error) {
+	type plain DashboardThresholdStep
+	return marshalWithExtra(plain(t), t.Extra)
+}
+
+// UnmarshalJSON keeps the fields which DashboardFieldConfigOverride doesn't have in Extra.
+func (f *DashboardFieldConfigOverride) UnmarshalJSON(data []byte) error {
+	type plain DashboardFieldConfigOverride
+	extra, err := unmarshalWithExtra(data, (*plain)(f))
+	f.Extra = extra
+	return err
+}
+
+// MarshalJSON adds the fields kept in Extra.
+func (f DashboardFieldConfigOverride) MarshalJSON() ([]byte, error) {
+	type plain DashboardFieldConfigOverride
+	return marshalWithExtra(plain(f), f.Extra)
+}
+
+// UnmarshalJSON keeps the fields which DashboardMatcher doesn't have in Extra.
+func (m *DashboardMatcher) UnmarshalJSON(data []byte) error {
+	type plain DashboardMatcher
+	extra, err := unmarshalWithExtra(data, (*plain)(m))
+	m.Extra = extra
+	return err
+}
+
+// MarshalJSON adds the fields kept in Extra.
+func (m DashboardMatcher) MarshalJSON() ([]byte, error) {
+	type plain DashboardMatcher
+	return marshalWithExtra(plain(m), m.Extra)
+}
+
+// UnmarshalJSON keeps the fields which DashboardOverrideProperty doesn't have in Extra.
+func (o *DashboardOverrideProperty) UnmarshalJSON(data []byte) error {
+	type plain DashboardOverrideProperty
+	extra, err := unmarshalWithExtra(data, (*plain)(o))
+	o.Extra = extra
+	return err
+}
+
+// MarshalJSON adds the fields kept in Extra.
+func (o DashboardOverrideProperty) MarshalJSON() ([]byte, error) {
+	type plain DashboardOverrideProperty
+	return marshalWithExtra(plain(o), o.Extra)
+}
+
+// UnmarshalJSON keeps the fields which DashboardTemplating doesn't have in Extra.
+func (t *DashboardTemplating) UnmarshalJSON(data []byte) error {
+	type plain DashboardTemplating
+	extra, err := unmarshalWithExtra(data, (*plain)(t))
+	t.Extra = extra
+	return err
+}
+
+// MarshalJSON adds the fields kept in Extra.
+func (t DashboardTemplating) MarshalJSON() ([]byte, error) {
+	type plain DashboardTemplating
+	return marshalWithExtra(plain(t), t.Extra)
+}
+
+// UnmarshalJSON keeps the fields which DashboardVariable doesn't have in Extra.
+func (v *DashboardVariable) UnmarshalJSON(data []byte) error {
+	type plain DashboardVariable
+	extra, err := unmarshalWithExtra(data, (*plain)(v))
+	v.Extra = extra
+	return err
+}
+
+// MarshalJSON adds the fields kept in Extra.
+func (v DashboardVariable) MarshalJSON() ([]byte, error) {
+	type plain DashboardVariable
+	return marshalWithExtra(plain(v), v.Extra)
+}
+
+// UnmarshalJSON keeps the fields which DashboardVariableOption doesn't have in Extra.
+func (v *DashboardVariableOption) UnmarshalJSON(data []byte) error {
+	type plain DashboardVariableOption
+	extra, err := unmarshalWithExtra(data, (*plain)(v))
+	v.Extra = extra
+	return err
+}
+
+// MarshalJSON adds the fields kept in Extra.
+func (v DashboardVariableOption) MarshalJSON() ([]byte, error) {
+	type plain DashboardVariableOption
+	return marshalWithExtra(plain(v), v.Extra)
+}
+
+// UnmarshalJSON keeps the fields which DashboardAnnotations doesn't have in Extra.
+func (a *DashboardAnnotations) UnmarshalJSON(data []byte) error {
+	type plain DashboardAnnotations
+	extra, err := unmarshalWithExtra(data, (*plain)(a))
+	a.Extra = extra
+	return err
+}
+
+// MarshalJSON adds the fields kept in Extra.
+func (a DashboardAnnotations) MarshalJSON() ([]byte, error) {
+	type plain DashboardAnnotations
+	return marshalWithExtra(plain(a), a.Extra)
+}
+
+// UnmarshalJSON keeps the fields which DashboardAnnotation doesn't have in Extra.
+func (a *DashboardAnnotation) UnmarshalJSON(data []byte) error {
+	type plain DashboardAnnotation
+	extra, err := unmarshalWithExtra(data, (*plain)(a))
+	a.Extra = extra
+	return err
+}
+
+// MarshalJSON adds the fields kept in Extra.
+func (a DashboardAnnotation) MarshalJSON() ([]byte, error) {
+	type plain DashboardAnnotation
+	return marshalWithExtra(plain(a), a.Extra)
+}
+
+// UnmarshalJSON keeps the fields which DashboardLink doesn't have in Extra.
+func (l *DashboardLink) UnmarshalJSON(data []byte) error {
+	type plain DashboardLink
+	extra, err := unmarshalWithExtra(data, (*plain)(l))
+	l.Extra = extra
+	return err
+}
+
+// MarshalJSON adds the fields kept in Extra.
+func (l DashboardLink) MarshalJSON() ([]byte, error) {
+	type plain DashboardLink
+	return marshalWithExtra(plain(l), l.Extra)
+}
+
+// UnmarshalJSON also accepts the data source names used by old dashboards.
+func (r *DashboardDataSourceRef) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*r = DashboardDataSourceRef{Name: name}
+		return nil
+	}
+
+	type plain DashboardDataSourceRef
+	extra, err := unmarshalWithExtra(data, (*plain)(r))
+	r.Extra = extra
+	return err
+}
+
+// MarshalJSON marshals references by name the way old dashboards have them.
+func (r DashboardDataSourceRef) MarshalJSON() ([]byte, error) {
+	if r.Name != "" && r.Type == "" && r.UID == "" {
+		return json.Marshal(r.Name)
+	}
+
+	type plain DashboardDataSourceRef
+	return marshalWithExtra(plain(r), r.Extra)
+}
+
+// unmarshalWithExtra unmarshals data into v, a pointer to a struct, and returns the fields which v doesn't have.
+// Fields which v has but which are empty in data are returned as well, so that they can be marshaled
+// back even though their struct fields are omitted when empty.
+func unmarshalWithExtra(data []byte, v interface{}) (map[string]json.RawMessage, error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	known := jsonFieldNames(reflect.TypeOf(v).Elem())
+	for name, value := range fields {
+		// Like encoding/json, match the names of the fields case-insensitively.
+		if known[strings.ToLower(name)] && !isEmptyJSON(value) {
+			delete(fields, name)
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// marshalWithExtra marshals v, a struct, adding the extra fields which aren't already marshaled.
+func marshalWithExtra(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	marshaled := make(map[string]bool, len(fields))
+	for name := range fields {
+		marshaled[strings.ToLower(name)] = true
+	}
+	for name, value := range extra {
+		if !marshaled[strings.ToLower(name)] {
+			fields[name] = value
+		}
+	}
+
+	return json.Marshal(fields)
+}
+
+func isEmptyJSON(value json.RawMessage) bool {
+	compacted := &bytes.Buffer{}
+	if err := json.Compact(compacted, value); err != nil {
+		return false
+	}
+	switch compacted.String() {
+	case "null", "false", "0", `""`, "[]", "{}":
+		return true
+	default:
+		return false
+	}
+}
+
+// jsonFieldNames returns the lower-cased JSON names of the fields of a struct type.
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+		names[strings.ToLower(name)] = true
+	}
+	return names
+}
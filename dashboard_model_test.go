@@ -0,0 +1,208 @@
+package gapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const dashboardModelJSON = `{
+	"id": 12,
+	"uid": "abc",
+	"title": "Services",
+	"tags": [],
+	"timezone": "browser",
+	"editable": true,
+	"graphTooltip": 1,
+	"time": {"from": "now-6h", "to": "now"},
+	"timepicker": {"refresh_intervals": ["5s", "1m"]},
+	"refresh": "",
+	"schemaVersion": 39,
+	"version": 3,
+	"liveNow": false,
+	"panels": [
+		{
+			"id": 1,
+			"type": "timeseries",
+			"title": "Requests",
+			"gridPos": {"h": 8, "w": 12, "x": 0, "y": 0},
+			"datasource": {"type": "prometheus", "uid": "prom"},
+			"targets": [
+				{"refId": "A", "expr": "sum(rate(http_requests_total[5m]))", "legendFormat": "{{code}}", "datasource": {"type": "prometheus", "uid": "prom"}}
+			],
+			"fieldConfig": {
+				"defaults": {
+					"unit": "reqps",
+					"decimals": 2,
+					"color": {"mode": "palette-classic"},
+					"custom": {"lineWidth": 1, "fillOpacity": 10},
+					"thresholds": {"mode": "absolute", "steps": [{"color": "green", "value": null}, {"color": "red", "value": 80}]},
+					"mappings": []
+				},
+				"overrides": [
+					{"matcher": {"id": "byName", "options": "500"}, "properties": [{"id": "color", "value": {"mode": "fixed", "fixedColor": "red"}}]}
+				]
+			},
+			"options": {"legend": {"displayMode": "list", "placement": "bottom"}, "tooltip": {"mode": "single"}},
+			"pluginVersion": "10.2.0"
+		},
+		{
+			"id": 2,
+			"type": "row",
+			"title": "Details",
+			"collapsed": true,
+			"gridPos": {"h": 1, "w": 24, "x": 0, "y": 8},
+			"panels": [
+				{"id": 3, "type": "graph", "title": "Legacy", "datasource": "Prometheus", "gridPos": {"h": 8, "w": 24, "x": 0, "y": 9}, "targets": [{"refId": "A", "expr": "up"}]}
+			]
+		}
+	],
+	"templating": {
+		"list": [
+			{
+				"name": "instance",
+				"type": "query",
+				"datasource": {"type": "prometheus", "uid": "prom"},
+				"query": {"query": "label_values(up, instance)", "refId": "A"},
+				"refresh": 1,
+				"multi": true,
+				"includeAll": true,
+				"current": {"text": ["All"], "value": ["$__all"]},
+				"options": []
+			}
+		]
+	},
+	"annotations": {
+		"list": [
+			{"builtIn": 1, "datasource": {"type": "grafana", "uid": "-- Grafana --"}, "enable": true, "hide": true, "iconColor": "rgba(0, 211, 255, 1)", "name": "Annotations & Alerts", "type": "dashboard"}
+		]
+	},
+	"links": [
+		{"title": "Docs", "type": "link", "url": "https://example.com", "targetBlank": true}
+	]
+}`
+
+func decodeJSON(t *testing.T, data []byte) interface{} {
+	t.Helper()
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestDashboardModelRoundTrip(t *testing.T) {
+	model := &DashboardModel{}
+	if err := json.Unmarshal([]byte(dashboardModelJSON), model); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := decodeJSON(t, []byte(dashboardModelJSON))
+	if actual := decodeJSON(t, data); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("the dashboard changed through a round trip:\n%s", data)
+	}
+}
+
+func TestDashboardModelFields(t *testing.T) {
+	model := &DashboardModel{}
+	if err := json.Unmarshal([]byte(dashboardModelJSON), model); err != nil {
+		t.Fatal(err)
+	}
+
+	if model.UID != "abc" || model.Time.From != "now-6h" || *model.Editable != true {
+		t.Errorf("unexpected dashboard: %+v", model)
+	}
+
+	panels := model.AllPanels()
+	if len(panels) != 3 {
+		t.Fatalf("expected 3 panels including the nested one, got %d", len(panels))
+	}
+	requests := panels[0]
+	if requests.GridPos.W != 12 || requests.Datasource.UID != "prom" || requests.Targets[0].RefID != "A" {
+		t.Errorf("unexpected panel: %+v", requests)
+	}
+	if string(requests.Targets[0].Extra["expr"]) != `"sum(rate(http_requests_total[5m]))"` {
+		t.Errorf("unexpected query: %s", requests.Targets[0].Extra["expr"])
+	}
+	if requests.FieldConfig.Defaults.Unit != "reqps" || *requests.FieldConfig.Defaults.Thresholds.Steps[1].Value != 80 {
+		t.Errorf("unexpected field config: %+v", requests.FieldConfig.Defaults)
+	}
+	if requests.FieldConfig.Overrides[0].Matcher.ID != "byName" {
+		t.Errorf("unexpected overrides: %+v", requests.FieldConfig.Overrides)
+	}
+
+	if legacy := panels[2]; legacy.Title != "Legacy" || legacy.Datasource.Name != "Prometheus" {
+		t.Errorf("unexpected nested panel: %+v", legacy)
+	}
+
+	variable := model.Variable("instance")
+	if variable == nil || !variable.Multi || variable.Datasource.Type != "prometheus" {
+		t.Errorf("unexpected variable: %+v", variable)
+	}
+	if model.Variable("missing") != nil {
+		t.Error("expected no variable")
+	}
+
+	if model.Annotations.List[0].BuiltIn != 1 || model.Links[0].URL != "https://example.com" {
+		t.Errorf("unexpected annotations or links: %+v %+v", model.Annotations.List[0], model.Links[0])
+	}
+}
+
+func TestDashboardTypedModel(t *testing.T) {
+	dashboard := &Dashboard{Model: decodeJSON(t, []byte(dashboardModelJSON)).(map[string]interface{})}
+
+	model, err := dashboard.TypedModel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.Title = "Renamed"
+	model.Panels[0].FieldConfig.Defaults.Unit = "short"
+	model.Panels = append(model.Panels, &DashboardPanel{Type: "text", Title: "New", GridPos: &DashboardGridPos{H: 4, W: 24, Y: 17}})
+
+	if err := dashboard.SetTypedModel(model); err != nil {
+		t.Fatal(err)
+	}
+
+	if dashboard.Model["title"] != "Renamed" || dashboard.Model["timepicker"] == nil {
+		t.Errorf("unexpected model: %v", dashboard.Model)
+	}
+	panels := dashboard.Model["panels"].([]interface{})
+	if len(panels) != 3 {
+		t.Fatalf("expected 3 panels, got %d", len(panels))
+	}
+	first := panels[0].(map[string]interface{})
+	if first["pluginVersion"] != "10.2.0" {
+		t.Errorf("unknown panel fields weren't kept: %v", first)
+	}
+	defaults := first["fieldConfig"].(map[string]interface{})["defaults"].(map[string]interface{})
+	if defaults["unit"] != "short" {
+		t.Errorf("the unit wasn't changed: %v", defaults)
+	}
+	if _, exists := panels[2].(map[string]interface{})["panels"]; exists {
+		t.Error("empty fields of new panels shouldn't be marshaled")
+	}
+}
+
+func TestDashboardModelZeroedField(t *testing.T) {
+	model := &DashboardModel{}
+	if err := json.Unmarshal([]byte(`{"title": "a", "description": "b", "refresh": ""}`), model); err != nil {
+		t.Fatal(err)
+	}
+	model.Description = ""
+
+	data, err := json.Marshal(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := decodeJSON(t, []byte(`{"title": "a", "refresh": ""}`))
+	if actual := decodeJSON(t, data); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("unexpected dashboard: %s", data)
+	}
+}
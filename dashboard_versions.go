@@ -0,0 +1,248 @@
+package gapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DashboardVersion represents a version of a Grafana dashboard.
+// Data, the model of the dashboard at this version, is only set when fetching a single version.
+type DashboardVersion struct {
+	ID            int64                  `json:"id"`
+	DashboardID   int64                  `json:"dashboardId"`
+	DashboardUID  string                 `json:"dashboardUid"`
+	ParentVersion int64                  `json:"parentVersion"`
+	RestoredFrom  int64                  `json:"restoredFrom"`
+	Version       int64                  `json:"version"`
+	Created       time.Time              `json:"created"`
+	CreatedBy     string                 `json:"createdBy"`
+	Message       string                 `json:"message"`
+	Data          map[string]interface{} `json:"data,omitempty"`
+}
+
+// DashboardChange is a difference between two dashboard models.
+// Path is a JSON pointer to the changed value, e.g. /panels/0/title.
+type DashboardChange struct {
+	Op   string      `json:"op"`
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// Operations of DashboardChange.
+const (
+	DashboardChangeAdd     = "add"
+	DashboardChangeRemove  = "remove"
+	DashboardChangeReplace = "replace"
+)
+
+// DashboardVersions fetches and returns the versions of a dashboard, the newest first.
+func (c *Client) DashboardVersions(uid string) ([]DashboardVersion, error) {
+	versions := []DashboardVersion{}
+	err := c.ForEachDashboardVersion(uid, PageOptions{}, func(version DashboardVersion) error {
+		versions = append(versions, version)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// ForEachDashboardVersion calls fn for each version of a dashboard, the newest first, fetching them a page at a time.
+// fn can return ErrStopIteration to stop early.
+func (c *Client) ForEachDashboardVersion(uid string, opts PageOptions, fn func(DashboardVersion) error) error {
+	continueToken := ""
+	return paginate(opts, 1000, func(page, perPage int) (int, int64, error) {
+		query := url.Values{}
+		query.Set("limit", fmt.Sprint(perPage))
+		if continueToken != "" {
+			query.Set("continueToken", continueToken)
+		} else {
+			query.Set("start", fmt.Sprint((page-1)*perPage))
+		}
+
+		var raw json.RawMessage
+		if err := c.request("GET", fmt.Sprintf("/api/dashboards/uid/%s/versions", uid), query, nil, &raw); err != nil {
+			return 0, 0, err
+		}
+
+		// Grafana 11 wraps the versions in an object, along with the token of the next page, and may ignore start.
+		// Older versions return a list.
+		var versions []DashboardVersion
+		tokenPaged := false
+		if err := json.Unmarshal(raw, &versions); err != nil {
+			wrapped := struct {
+				Versions      []DashboardVersion `json:"versions"`
+				ContinueToken string             `json:"continueToken"`
+			}{}
+			if err := json.Unmarshal(raw, &wrapped); err != nil {
+				return 0, 0, err
+			}
+			versions = wrapped.Versions
+			continueToken = wrapped.ContinueToken
+			tokenPaged = true
+		}
+
+		for _, version := range versions {
+			if err := fn(version); err != nil {
+				return 0, 0, err
+			}
+		}
+		if !tokenPaged {
+			return len(versions), -1, nil
+		}
+		// Without a token, this is the last page. With one, there are more versions, even after a short page.
+		if continueToken == "" {
+			return 0, 0, ErrStopIteration
+		}
+		return perPage, -1, nil
+	})
+}
+
+// DashboardVersion fetches a version of a dashboard, including its model.
+func (c *Client) DashboardVersion(uid string, version int64) (*DashboardVersion, error) {
+	result := &DashboardVersion{}
+	err := c.request("GET", fmt.Sprintf("/api/dashboards/uid/%s/versions/%d", uid, version), nil, nil, result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// RestoreDashboardVersion restores a dashboard to a previous version, which creates a new version.
+func (c *Client) RestoreDashboardVersion(uid string, version int64) (*DashboardSaveResponse, error) {
+	data, err := json.Marshal(map[string]int64{"version": version})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DashboardSaveResponse{}
+	err = c.request("POST", fmt.Sprintf("/api/dashboards/uid/%s/restore", uid), nil, bytes.NewBuffer(data), result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DiffDashboardVersions returns the changes made to a dashboard between two of its versions.
+func (c *Client) DiffDashboardVersions(uid string, from, to int64) ([]DashboardChange, error) {
+	fromVersion, err := c.DashboardVersion(uid, from)
+	if err != nil {
+		return nil, err
+	}
+	toVersion, err := c.DashboardVersion(uid, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return DiffDashboardModels(fromVersion.Data, toVersion.Data)
+}
+
+// DiffDashboardVersion returns the changes between a version of a dashboard and a local dashboard,
+// e.g. to check what saving the local dashboard would change.
+func (c *Client) DiffDashboardVersion(uid string, version int64, dashboard Dashboard) ([]DashboardChange, error) {
+	base, err := c.DashboardVersion(uid, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return DiffDashboardModels(base.Data, dashboard.Model)
+}
+
+// DiffDashboardModels returns the changes needed to turn the base dashboard model into the target one.
+// Object keys are compared in alphabetical order and list items by index.
+func DiffDashboardModels(base, target map[string]interface{}) ([]DashboardChange, error) {
+	// Compare the models as JSON, so that e.g. ints of a local model match the floats of a fetched one.
+	normalizedBase, err := normalizeJSON(base)
+	if err != nil {
+		return nil, err
+	}
+	normalizedTarget, err := normalizeJSON(target)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []DashboardChange{}
+	diffJSON("", normalizedBase, normalizedTarget, &changes)
+	return changes, nil
+}
+
+func normalizeJSON(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+func diffJSON(path string, base, target interface{}, changes *[]DashboardChange) {
+	switch baseValue := base.(type) {
+	case map[string]interface{}:
+		if targetValue, ok := target.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(baseValue)+len(targetValue))
+			for key := range baseValue {
+				keys = append(keys, key)
+			}
+			for key := range targetValue {
+				if _, exists := baseValue[key]; !exists {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				keyPath := path + "/" + escapeJSONPointer(key)
+				baseItem, inBase := baseValue[key]
+				targetItem, inTarget := targetValue[key]
+				switch {
+				case !inTarget:
+					*changes = append(*changes, DashboardChange{Op: DashboardChangeRemove, Path: keyPath, Old: baseItem})
+				case !inBase:
+					*changes = append(*changes, DashboardChange{Op: DashboardChangeAdd, Path: keyPath, New: targetItem})
+				default:
+					diffJSON(keyPath, baseItem, targetItem, changes)
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if targetValue, ok := target.([]interface{}); ok {
+			for i := 0; i < len(baseValue) || i < len(targetValue); i++ {
+				itemPath := fmt.Sprintf("%s/%d", path, i)
+				switch {
+				case i >= len(targetValue):
+					*changes = append(*changes, DashboardChange{Op: DashboardChangeRemove, Path: itemPath, Old: baseValue[i]})
+				case i >= len(baseValue):
+					*changes = append(*changes, DashboardChange{Op: DashboardChangeAdd, Path: itemPath, New: targetValue[i]})
+				default:
+					diffJSON(itemPath, baseValue[i], targetValue[i], changes)
+				}
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(base, target) {
+		*changes = append(*changes, DashboardChange{Op: DashboardChangeReplace, Path: path, Old: base, New: target})
+	}
+}
+
+// escapeJSONPointer escapes a key to be used in a JSON pointer, as defined by RFC 6901.
+func escapeJSONPointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
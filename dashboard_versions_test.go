@@ -0,0 +1,164 @@
+package gapi
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/gobs/pretty"
+)
+
+const (
+	getDashboardVersionsJSON = `[
+	{"id": 2, "dashboardId": 1, "dashboardUid": "nErXDvCkzz", "parentVersion": 1, "restoredFrom": 0, "version": 2, "created": "2023-01-02T10:00:00Z", "createdBy": "admin", "message": "Faster refresh"},
+	{"id": 1, "dashboardId": 1, "dashboardUid": "nErXDvCkzz", "parentVersion": 0, "restoredFrom": 0, "version": 1, "created": "2023-01-01T10:00:00Z", "createdBy": "admin", "message": ""}
+]`
+	getDashboardVersionsWrappedJSON = `{"continueToken": "", "versions": ` + getDashboardVersionsJSON + `}`
+	getDashboardVersionJSON         = `{"id": 1, "dashboardId": 1, "dashboardUid": "nErXDvCkzz", "version": 1, "createdBy": "admin", "data": {"title": "Overview", "refresh": "5m", "version": 1}}`
+	getDashboardVersion2JSON        = `{"id": 2, "dashboardId": 1, "dashboardUid": "nErXDvCkzz", "version": 2, "createdBy": "admin", "data": {"title": "Overview", "refresh": "1m", "version": 2, "tags": ["prod"]}}`
+	restoreDashboardVersionJSON     = `{"id": 1, "slug": "overview", "status": "success", "uid": "nErXDvCkzz", "url": "/d/nErXDvCkzz/overview", "version": 3}`
+)
+
+func TestDashboardVersions(t *testing.T) {
+	for _, body := range []string{getDashboardVersionsJSON, getDashboardVersionsWrappedJSON} {
+		client := gapiTestTools(t, 200, body)
+
+		versions, err := client.DashboardVersions("nErXDvCkzz")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Log(pretty.PrettyFormat(versions))
+
+		if len(versions) != 2 || versions[0].Version != 2 || versions[0].Message != "Faster refresh" {
+			t.Errorf("unexpected versions: %+v", versions)
+		}
+	}
+}
+
+func TestForEachDashboardVersionContinueToken(t *testing.T) {
+	// Like Grafana 11, pages are found by their continue token, and may be short before the last one.
+	client := gapiTestToolsFromCalls(t, []mockServerCall{
+		{200, `{"continueToken": "t1", "versions": [{"version": 5}, {"version": 4}]}`},
+		{200, `{"continueToken": "t2", "versions": [{"version": 3}]}`},
+		{200, `{"continueToken": "", "versions": [{"version": 2}, {"version": 1}]}`},
+	})
+	queries := []url.Values{}
+	transport := client.client.Transport
+	client.client.Transport = RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		queries = append(queries, req.URL.Query())
+		return transport.RoundTrip(req)
+	})
+
+	versions := []int64{}
+	err := client.ForEachDashboardVersion("nErXDvCkzz", PageOptions{PageSize: 2}, func(version DashboardVersion) error {
+		versions = append(versions, version.Version)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(versions, []int64{5, 4, 3, 2, 1}) {
+		t.Errorf("unexpected versions: %v", versions)
+	}
+	tokens := []string{}
+	for _, query := range queries {
+		tokens = append(tokens, query.Get("continueToken"))
+	}
+	if !reflect.DeepEqual(tokens, []string{"", "t1", "t2"}) || queries[1].Get("start") != "" {
+		t.Errorf("unexpected requests: %v", queries)
+	}
+}
+
+func TestDashboardVersion(t *testing.T) {
+	client := gapiTestTools(t, 200, getDashboardVersionJSON)
+
+	version, err := client.DashboardVersion("nErXDvCkzz", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if version.Version != 1 || version.Data["refresh"] != "5m" {
+		t.Errorf("unexpected version: %+v", version)
+	}
+}
+
+func TestRestoreDashboardVersion(t *testing.T) {
+	client := gapiTestTools(t, 200, restoreDashboardVersionJSON)
+
+	resp, err := client.RestoreDashboardVersion("nErXDvCkzz", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Version != 3 || resp.UID != "nErXDvCkzz" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestDiffDashboardVersions(t *testing.T) {
+	client := gapiTestToolsFromCalls(t, []mockServerCall{
+		{200, getDashboardVersionJSON},
+		{200, getDashboardVersion2JSON},
+	})
+
+	changes, err := client.DiffDashboardVersions("nErXDvCkzz", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Log(pretty.PrettyFormat(changes))
+
+	expected := []DashboardChange{
+		{Op: DashboardChangeReplace, Path: "/refresh", Old: "5m", New: "1m"},
+		{Op: DashboardChangeAdd, Path: "/tags", New: []interface{}{"prod"}},
+		{Op: DashboardChangeReplace, Path: "/version", Old: float64(1), New: float64(2)},
+	}
+	if pretty.PrettyFormat(changes) != pretty.PrettyFormat(expected) {
+		t.Errorf("unexpected changes: %s", pretty.PrettyFormat(changes))
+	}
+}
+
+func TestDiffDashboardModels(t *testing.T) {
+	base := map[string]interface{}{
+		"title": "Overview",
+		"panels": []interface{}{
+			map[string]interface{}{"id": float64(1), "title": "CPU", "gridPos": map[string]interface{}{"h": float64(8)}},
+			map[string]interface{}{"id": float64(2), "title": "Memory"},
+		},
+		"a/b": "x",
+	}
+	// A local model, with ints where a fetched one has floats.
+	target := map[string]interface{}{
+		"title": "Overview",
+		"panels": []interface{}{
+			map[string]interface{}{"id": 1, "title": "CPU usage", "gridPos": map[string]interface{}{"h": 8}},
+		},
+	}
+
+	changes, err := DiffDashboardModels(base, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Log(pretty.PrettyFormat(changes))
+
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %d", len(changes))
+	}
+	if changes[0].Op != DashboardChangeRemove || changes[0].Path != "/a~1b" {
+		t.Errorf("unexpected change: %+v", changes[0])
+	}
+	if changes[1].Op != DashboardChangeReplace || changes[1].Path != "/panels/0/title" || changes[1].New != "CPU usage" {
+		t.Errorf("unexpected change: %+v", changes[1])
+	}
+	if changes[2].Op != DashboardChangeRemove || changes[2].Path != "/panels/1" {
+		t.Errorf("unexpected change: %+v", changes[2])
+	}
+
+	if changes, _ := DiffDashboardModels(base, base); len(changes) != 0 {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"net/url"
 )
 
 // DataSource represents a Grafana data source.
@@ -28,11 +29,32 @@ type DataSource struct {
 
 	BasicAuth     bool   `json:"basicAuth"`
 	BasicAuthUser string `json:"basicAuthUser,omitempty"`
+	// WithCredentials sends credentials like cookies with the cross-site requests of browser access.
+	WithCredentials bool `json:"withCredentials,omitempty"`
 	// Deprecated: Use secureJsonData.basicAuthPassword instead.
 	BasicAuthPassword string `json:"basicAuthPassword,omitempty"`
 
-	JSONData       map[string]interface{} `json:"jsonData,omitempty"`
+	JSONData map[string]interface{} `json:"jsonData,omitempty"`
+	// SecureJSONData holds secrets, which the API never returns. Updates leave the secrets they don't have
+	// untouched, and remove the secrets they set to an empty string.
 	SecureJSONData map[string]interface{} `json:"secureJsonData,omitempty"`
+	// SecureJSONFields is only returned by the API: it tells which secrets of secureJsonData are set.
+	// It's read from responses, but never sent.
+	SecureJSONFields map[string]bool `json:"-"`
+}
+
+// UnmarshalJSON reads SecureJSONFields, which isn't marshaled.
+func (ds *DataSource) UnmarshalJSON(data []byte) error {
+	type plain DataSource
+	fields := struct {
+		*plain
+		SecureJSONFields map[string]bool `json:"secureJsonFields"`
+	}{plain: (*plain)(ds)}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	ds.SecureJSONFields = fields.SecureJSONFields
+	return nil
 }
 
 // NewDataSource creates a new Grafana data source.
@@ -65,6 +87,7 @@ func (c *Client) UpdateDataSource(s *DataSource) error {
 	return c.request("PUT", path, nil, bytes.NewBuffer(data), nil)
 }
 
+// UpdateDataSourceByUID updates a Grafana data source, identified by its UID.
 func (c *Client) UpdateDataSourceByUID(s *DataSource) error {
 	path := fmt.Sprintf("/api/datasources/uid/%s", s.UID)
 	data, err := json.Marshal(s)
@@ -99,6 +122,18 @@ func (c *Client) DataSourceByUID(uid string) (*DataSource, error) {
 	return result, err
 }
 
+// DataSourceByName fetches and returns the Grafana data source whose name is passed.
+func (c *Client) DataSourceByName(name string) (*DataSource, error) {
+	path := fmt.Sprintf("/api/datasources/name/%s", url.PathEscape(name))
+	result := &DataSource{}
+	err := c.request("GET", path, nil, nil, result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, err
+}
+
 // DataSourceIDByName returns the Grafana data source ID by name.
 func (c *Client) DataSourceIDByName(name string) (int64, error) {
 	path := fmt.Sprintf("/api/datasources/id/%s", name)
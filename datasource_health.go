@@ -0,0 +1,157 @@
+package gapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// DataSourceHealthStatus is the status of a data source health check.
+type DataSourceHealthStatus string
+
+const (
+	DataSourceHealthOK      DataSourceHealthStatus = "OK"
+	DataSourceHealthError   DataSourceHealthStatus = "ERROR"
+	DataSourceHealthUnknown DataSourceHealthStatus = "UNKNOWN"
+)
+
+// DataSourceHealth is the result of a data source health check, which tests the connection of Grafana to the
+// backend of the data source.
+type DataSourceHealth struct {
+	Status  DataSourceHealthStatus `json:"status"`
+	Message string                 `json:"message"`
+	// Details depend on the data source, e.g. the error of the request testing the connection.
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// OK reports whether the data source could reach its backend.
+func (h *DataSourceHealth) OK() bool {
+	return h.Status == DataSourceHealthOK
+}
+
+// CheckDataSourceHealth runs the health check of the data source with the given UID. A failed check isn't an
+// error: it's returned with the ERROR status. Data sources without health checks have the UNKNOWN status.
+func (c *Client) CheckDataSourceHealth(uid string) (*DataSourceHealth, error) {
+	path := fmt.Sprintf("/api/datasources/uid/%s/health", uid)
+	health := &DataSourceHealth{}
+	err := c.request("GET", path, nil, nil, health)
+	if err == nil {
+		return health, nil
+	}
+
+	apiErr := &APIError{}
+	if !errors.As(err, &apiErr) {
+		return nil, err
+	}
+	switch {
+	// Grafana responds to failed checks with 400 and the result of the check.
+	case apiErr.StatusCode == http.StatusBadRequest && apiErr.Status != "":
+		if err := json.Unmarshal(apiErr.Body, health); err != nil {
+			return nil, apiErr
+		}
+		return health, nil
+	case apiErr.StatusCode == http.StatusNotImplemented:
+		return &DataSourceHealth{Status: DataSourceHealthUnknown, Message: apiErr.Message}, nil
+	}
+	return nil, err
+}
+
+// DataSourceHealthResult is the health check of a data source in a report. Err is set instead of Health if the
+// check couldn't run.
+type DataSourceHealthResult struct {
+	DataSource *DataSource
+	Health     *DataSourceHealth
+	Err        error
+}
+
+// OK reports whether the check ran and the data source could reach its backend.
+func (r DataSourceHealthResult) OK() bool {
+	return r.Err == nil && r.Health.OK()
+}
+
+// DataSourceHealthReport holds the health checks of data sources, in the order of the data sources.
+type DataSourceHealthReport struct {
+	Results []DataSourceHealthResult
+}
+
+// Failed returns the results of the checks which failed or couldn't run. Data sources without health checks
+// aren't failures.
+func (r *DataSourceHealthReport) Failed() []DataSourceHealthResult {
+	failed := []DataSourceHealthResult{}
+	for _, result := range r.Results {
+		if result.Err != nil || result.Health.Status == DataSourceHealthError {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Err returns an error listing the failed checks, or nil if there's none.
+func (r *DataSourceHealthReport) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	messages := make([]string, len(failed))
+	for i, result := range failed {
+		messages[i] = fmt.Sprintf("%s: %s", result.DataSource.Name, result.message())
+	}
+	return fmt.Errorf("%d data source health checks failed: %s", len(failed), strings.Join(messages, "; "))
+}
+
+// String returns the report with a line per data source, e.g. "OK Prometheus (prom): Data source is working".
+func (r *DataSourceHealthReport) String() string {
+	b := &strings.Builder{}
+	for _, result := range r.Results {
+		status := "FAILED"
+		if result.Err == nil {
+			status = string(result.Health.Status)
+		}
+		fmt.Fprintf(b, "%s %s (%s): %s\n", status, result.DataSource.Name, result.DataSource.UID, result.message())
+	}
+	return b.String()
+}
+
+func (r DataSourceHealthResult) message() string {
+	if r.Err != nil {
+		return r.Err.Error()
+	}
+	return r.Health.Message
+}
+
+// CheckDataSourcesHealth runs the health checks of all the data sources returned by DataSources, running up to
+// concurrency checks at once, or all of them if it's 0. The limits of Config still apply.
+func (c *Client) CheckDataSourcesHealth(concurrency int) (*DataSourceHealthReport, error) {
+	dataSources, err := c.DataSources()
+	if err != nil {
+		return nil, err
+	}
+	if concurrency <= 0 || concurrency > len(dataSources) {
+		concurrency = len(dataSources)
+	}
+
+	report := &DataSourceHealthReport{Results: make([]DataSourceHealthResult, len(dataSources))}
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				ds := dataSources[i]
+				health, err := c.CheckDataSourceHealth(ds.UID)
+				report.Results[i] = DataSourceHealthResult{DataSource: ds, Health: health, Err: err}
+			}
+		}()
+	}
+	for i := range dataSources {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return report, nil
+}
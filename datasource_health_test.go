@@ -0,0 +1,94 @@
+package gapi
+
+import (
+	"strings"
+	"testing"
+)
+
+const healthDataSourcesJSON = `[
+	{"uid": "prometheus", "name": "Prometheus"},
+	{"uid": "loki", "name": "Loki"},
+	{"uid": "tempo", "name": "Tempo"},
+	{"uid": "csv", "name": "CSV"}
+]`
+
+// healthChecks are the responses of the health checks of the data sources of healthDataSourcesJSON, in order.
+var healthChecks = []mockServerCall{
+	{200, `{"status": "OK", "message": "Successfully queried the Prometheus API."}`},
+	{400, `{"status": "ERROR", "message": "Unable to connect with Loki.", "details": {"verboseMessage": "dial tcp: connection refused"}}`},
+	{500, `{"message": "Plugin health check failed"}`},
+	{501, `{"message": "Health check not implemented"}`},
+}
+
+func TestCheckDataSourceHealth(t *testing.T) {
+	client := gapiTestToolsFromCalls(t, healthChecks)
+
+	health, err := client.CheckDataSourceHealth("prometheus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !health.OK() || health.Message != "Successfully queried the Prometheus API." {
+		t.Errorf("unexpected health: %+v", health)
+	}
+
+	health, err = client.CheckDataSourceHealth("loki")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health.OK() || health.Status != DataSourceHealthError || health.Details["verboseMessage"] != "dial tcp: connection refused" {
+		t.Errorf("unexpected health: %+v", health)
+	}
+
+	if _, err := client.CheckDataSourceHealth("tempo"); err == nil {
+		t.Error("expected an error")
+	}
+
+	health, err = client.CheckDataSourceHealth("csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health.Status != DataSourceHealthUnknown {
+		t.Errorf("unexpected health: %+v", health)
+	}
+}
+
+func TestCheckDataSourcesHealth(t *testing.T) {
+	// Checking one data source at a time, the checks get the responses in order.
+	client := gapiTestToolsFromCalls(t, append([]mockServerCall{{200, healthDataSourcesJSON}}, healthChecks...))
+
+	report, err := client.CheckDataSourcesHealth(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `OK Prometheus (prometheus): Successfully queried the Prometheus API.
+ERROR Loki (loki): Unable to connect with Loki.
+FAILED Tempo (tempo): status: 500, body: {"message": "Plugin health check failed"}
+UNKNOWN CSV (csv): Health check not implemented
+`
+	if report.String() != expected {
+		t.Errorf("expected report:\n%s\ngot:\n%s", expected, report)
+	}
+	if failed := report.Failed(); len(failed) != 2 || failed[0].DataSource.UID != "loki" || failed[1].DataSource.UID != "tempo" {
+		t.Errorf("unexpected failed checks: %+v", failed)
+	}
+	if err := report.Err(); err == nil || !strings.HasPrefix(err.Error(), "2 data source health checks failed: Loki: Unable to connect with Loki.; Tempo: ") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCheckDataSourcesHealthConcurrently(t *testing.T) {
+	calls := []mockServerCall{{200, healthDataSourcesJSON}}
+	for range healthChecks {
+		calls = append(calls, healthChecks[0])
+	}
+	client := gapiTestToolsFromCalls(t, calls)
+
+	report, err := client.CheckDataSourcesHealth(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 4 || report.Err() != nil || report.Results[3].DataSource.UID != "csv" || !report.Results[3].OK() {
+		t.Errorf("unexpected report: %s", report)
+	}
+}
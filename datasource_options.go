@@ -0,0 +1,641 @@
+package gapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+)
+
+// DataSourceOptions are the settings of a type of data source. Unlike JSONData, which has the fields of all
+// the types, each implementation only has the fields of its type, and validates them.
+//
+// The fields marshaled to JSON go to jsonData, and the string fields tagged with `secure:"name"` go to
+// secureJsonData, under the name of the tag.
+type DataSourceOptions interface {
+	// DataSourceType returns the type of the data sources the options apply to, e.g. prometheus.
+	DataSourceType() string
+	// Validate reports missing required settings and settings which conflict.
+	Validate() error
+}
+
+// SetOptions validates options and sets the type, jsonData and secureJsonData of the data source from them.
+// The previous jsonData and secureJsonData are replaced.
+func (ds *DataSource) SetOptions(options DataSourceOptions) error {
+	if err := options.Validate(); err != nil {
+		return fmt.Errorf("invalid %s options: %w", options.DataSourceType(), err)
+	}
+	jsonData, secureJSONData, err := DataSourceOptionsMaps(options)
+	if err != nil {
+		return err
+	}
+
+	ds.Type = options.DataSourceType()
+	ds.JSONData = jsonData
+	ds.SecureJSONData = secureJSONData
+	return nil
+}
+
+// DataSourceOptionsMaps converts options to the jsonData and secureJsonData of data sources, without
+// validating them.
+func DataSourceOptionsMaps(options DataSourceOptions) (map[string]interface{}, map[string]interface{}, error) {
+	b, err := json.Marshal(options)
+	if err != nil {
+		return nil, nil, err
+	}
+	jsonData := map[string]interface{}{}
+	if err := json.Unmarshal(b, &jsonData); err != nil {
+		return nil, nil, err
+	}
+	secureJSONData := map[string]interface{}{}
+	secureFields(reflect.ValueOf(options), secureJSONData)
+
+	if o, ok := options.(interface{ fixedJSONData() map[string]interface{} }); ok {
+		for key, value := range o.fixedJSONData() {
+			jsonData[key] = value
+		}
+	}
+	if o, ok := options.(interface{ httpHeaders() map[string]string }); ok && len(o.httpHeaders()) > 0 {
+		jsonData, secureJSONData = JSONDataWithHeaders(jsonData, secureJSONData, o.httpHeaders())
+	}
+	return jsonData, secureJSONData, nil
+}
+
+// secureFields adds the non-empty string fields tagged as secure of a struct, and of the structs it embeds.
+func secureFields(v reflect.Value, secureJSONData map[string]interface{}) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Anonymous {
+			secureFields(v.Field(i), secureJSONData)
+			continue
+		}
+		if name := field.Tag.Get("secure"); name != "" && field.Type.Kind() == reflect.String && v.Field(i).String() != "" {
+			secureJSONData[name] = v.Field(i).String()
+		}
+	}
+}
+
+// DataSourceHTTPOptions are the settings of the data sources whose backend is reached over HTTP. Headers are
+// sent with each request to the backend, their values being kept in secureJsonData.
+type DataSourceHTTPOptions struct {
+	TLSSkipVerify     bool     `json:"tlsSkipVerify,omitempty"`
+	TLSAuth           bool     `json:"tlsAuth,omitempty"`
+	TLSAuthWithCACert bool     `json:"tlsAuthWithCACert,omitempty"`
+	ServerName        string   `json:"serverName,omitempty"`
+	Timeout           int64    `json:"timeout,omitempty"`
+	KeepCookies       []string `json:"keepCookies,omitempty"`
+
+	TLSCACert     string            `json:"-" secure:"tlsCACert"`
+	TLSClientCert string            `json:"-" secure:"tlsClientCert"`
+	TLSClientKey  string            `json:"-" secure:"tlsClientKey"`
+	Headers       map[string]string `json:"-"`
+}
+
+func (o DataSourceHTTPOptions) httpHeaders() map[string]string {
+	return o.Headers
+}
+
+func (o DataSourceHTTPOptions) validate() error {
+	switch {
+	case o.TLSAuth && (o.TLSClientCert == "" || o.TLSClientKey == ""):
+		return errors.New("TLS client authentication requires a client certificate and key")
+	case !o.TLSAuth && (o.TLSClientCert != "" || o.TLSClientKey != ""):
+		return errors.New("a TLS client certificate or key is set without TLS client authentication")
+	case o.TLSAuthWithCACert && o.TLSCACert == "":
+		return errors.New("TLS authentication with CA certificate requires a CA certificate")
+	case !o.TLSAuthWithCACert && o.TLSCACert != "":
+		return errors.New("a TLS CA certificate is set without TLS authentication with CA certificate")
+	case o.Timeout < 0:
+		return errors.New("timeout must not be negative")
+	}
+	return nil
+}
+
+// SigV4Options are the settings of AWS Signature Version 4 authentication, used by Prometheus and
+// Elasticsearch to reach managed services of AWS.
+type SigV4Options struct {
+	SigV4Auth          bool   `json:"sigV4Auth,omitempty"`
+	SigV4AuthType      string `json:"sigV4AuthType,omitempty"`
+	SigV4Region        string `json:"sigV4Region,omitempty"`
+	SigV4AssumeRoleArn string `json:"sigV4AssumeRoleArn,omitempty"`
+	SigV4ExternalID    string `json:"sigV4ExternalID,omitempty"`
+	SigV4Profile       string `json:"sigV4Profile,omitempty"`
+
+	SigV4AccessKey string `json:"-" secure:"sigV4AccessKey"`
+	SigV4SecretKey string `json:"-" secure:"sigV4SecretKey"`
+}
+
+func (o SigV4Options) validate() error {
+	if !o.SigV4Auth {
+		if o != (SigV4Options{}) {
+			return errors.New("SigV4 settings are set without SigV4 authentication")
+		}
+		return nil
+	}
+	if o.SigV4Region == "" {
+		return errors.New("SigV4 authentication requires a region")
+	}
+	return validateAWSAuth(o.SigV4AuthType, o.SigV4AccessKey, o.SigV4SecretKey, o.SigV4Profile, o.SigV4AssumeRoleArn, o.SigV4ExternalID)
+}
+
+// validateAWSAuth validates the settings of the authentication to AWS, whose type is keys, credentials,
+// default or ec2_iam_role.
+func validateAWSAuth(authType, accessKey, secretKey, profile, assumeRoleArn, externalID string) error {
+	switch authType {
+	case "keys":
+		if accessKey == "" || secretKey == "" {
+			return errors.New("keys authentication requires an access key and a secret key")
+		}
+	case "", "default", "credentials", "ec2_iam_role":
+		if accessKey != "" || secretKey != "" {
+			return fmt.Errorf("access keys are set with %q authentication", authType)
+		}
+	default:
+		return fmt.Errorf("unknown authentication type %q", authType)
+	}
+	if profile != "" && authType != "credentials" {
+		return fmt.Errorf("a credentials profile is set with %q authentication", authType)
+	}
+	if externalID != "" && assumeRoleArn == "" {
+		return errors.New("an external ID is set without role to assume")
+	}
+	return nil
+}
+
+// intervalPattern matches the intervals of data source settings, e.g. 15s or 1m.
+var intervalPattern = regexp.MustCompile(`^\d+(ms|s|m|h|d|w|y)$`)
+
+func validateInterval(name, interval string) error {
+	if interval != "" && !intervalPattern.MatchString(interval) {
+		return fmt.Errorf("invalid %s %q: expected a number and a unit, e.g. 15s", name, interval)
+	}
+	return nil
+}
+
+func validateHTTPMethod(name, method string) error {
+	if method != "" && method != "GET" && method != "POST" {
+		return fmt.Errorf("%s must be GET or POST, not %q", name, method)
+	}
+	return nil
+}
+
+// PrometheusOptions are the settings of Prometheus data sources.
+type PrometheusOptions struct {
+	DataSourceHTTPOptions
+	SigV4Options
+
+	HTTPMethod   string `json:"httpMethod,omitempty"`
+	TimeInterval string `json:"timeInterval,omitempty"`
+	QueryTimeout string `json:"queryTimeout,omitempty"`
+	// PrometheusType is the flavor of the backend: Prometheus, Cortex, Mimir or Thanos.
+	PrometheusType        string `json:"prometheusType,omitempty"`
+	PrometheusVersion     string `json:"prometheusVersion,omitempty"`
+	CustomQueryParameters string `json:"customQueryParameters,omitempty"`
+	// ManageAlerts defaults to true.
+	ManageAlerts    *bool  `json:"manageAlerts,omitempty"`
+	AlertmanagerUID string `json:"alertmanagerUid,omitempty"`
+}
+
+// DataSourceType implements DataSourceOptions.
+func (o PrometheusOptions) DataSourceType() string {
+	return "prometheus"
+}
+
+// Validate implements DataSourceOptions.
+func (o PrometheusOptions) Validate() error {
+	switch o.PrometheusType {
+	case "", "Prometheus", "Cortex", "Mimir", "Thanos":
+	default:
+		return fmt.Errorf("unknown prometheusType %q", o.PrometheusType)
+	}
+	if o.PrometheusVersion != "" && o.PrometheusType == "" {
+		return errors.New("prometheusVersion requires prometheusType")
+	}
+	return firstError(
+		o.DataSourceHTTPOptions.validate(),
+		o.SigV4Options.validate(),
+		validateHTTPMethod("httpMethod", o.HTTPMethod),
+		validateInterval("timeInterval", o.TimeInterval),
+		validateInterval("queryTimeout", o.QueryTimeout),
+	)
+}
+
+// LokiOptions are the settings of Loki data sources.
+type LokiOptions struct {
+	DataSourceHTTPOptions
+
+	MaxLines      int64              `json:"maxLines,omitempty"`
+	DerivedFields []LokiDerivedField `json:"derivedFields,omitempty"`
+	// ManageAlerts defaults to true.
+	ManageAlerts    *bool  `json:"manageAlerts,omitempty"`
+	AlertmanagerUID string `json:"alertmanagerUid,omitempty"`
+}
+
+// DataSourceType implements DataSourceOptions.
+func (o LokiOptions) DataSourceType() string {
+	return "loki"
+}
+
+// Validate implements DataSourceOptions.
+func (o LokiOptions) Validate() error {
+	if o.MaxLines < 0 {
+		return errors.New("maxLines must not be negative")
+	}
+	names := map[string]bool{}
+	for _, field := range o.DerivedFields {
+		if field.Name == "" || field.MatcherRegex == "" {
+			return errors.New("derived fields require a name and a matcherRegex")
+		}
+		if names[field.Name] {
+			return fmt.Errorf("duplicate derived field %s", field.Name)
+		}
+		names[field.Name] = true
+		if field.URL == "" {
+			return fmt.Errorf("derived field %s requires a url, or a query with datasourceUid", field.Name)
+		}
+	}
+	return o.DataSourceHTTPOptions.validate()
+}
+
+// ElasticsearchOptions are the settings of Elasticsearch data sources.
+type ElasticsearchOptions struct {
+	DataSourceHTTPOptions
+	SigV4Options
+
+	Index     string `json:"index"`
+	TimeField string `json:"timeField"`
+	// Interval is the pattern of the index names: Hourly, Daily, Weekly, Monthly or Yearly. It's empty for
+	// indexes without pattern.
+	Interval                   string `json:"interval,omitempty"`
+	EsVersion                  string `json:"esVersion,omitempty"`
+	MaxConcurrentShardRequests int64  `json:"maxConcurrentShardRequests,omitempty"`
+	TimeInterval               string `json:"timeInterval,omitempty"`
+	LogMessageField            string `json:"logMessageField,omitempty"`
+	LogLevelField              string `json:"logLevelField,omitempty"`
+	XpackEnabled               bool   `json:"xpack,omitempty"`
+	IncludeFrozen              bool   `json:"includeFrozen,omitempty"`
+}
+
+// DataSourceType implements DataSourceOptions.
+func (o ElasticsearchOptions) DataSourceType() string {
+	return "elasticsearch"
+}
+
+// Validate implements DataSourceOptions.
+func (o ElasticsearchOptions) Validate() error {
+	switch {
+	case o.Index == "":
+		return errors.New("index is required")
+	case o.TimeField == "":
+		return errors.New("timeField is required")
+	case o.MaxConcurrentShardRequests < 0:
+		return errors.New("maxConcurrentShardRequests must not be negative")
+	case o.IncludeFrozen && !o.XpackEnabled:
+		return errors.New("includeFrozen requires xpack")
+	}
+	switch o.Interval {
+	case "", "Hourly", "Daily", "Weekly", "Monthly", "Yearly":
+	default:
+		return fmt.Errorf("unknown index interval %q", o.Interval)
+	}
+	return firstError(
+		o.DataSourceHTTPOptions.validate(),
+		o.SigV4Options.validate(),
+		validateInterval("timeInterval", o.TimeInterval),
+	)
+}
+
+// InfluxDBFluxOptions are the settings of InfluxDB data sources queried with Flux, from InfluxDB 2.
+type InfluxDBFluxOptions struct {
+	DataSourceHTTPOptions
+
+	Organization  string `json:"organization"`
+	DefaultBucket string `json:"defaultBucket"`
+	TimeInterval  string `json:"timeInterval,omitempty"`
+	MaxSeries     int64  `json:"maxSeries,omitempty"`
+
+	Token string `json:"-" secure:"token"`
+}
+
+// DataSourceType implements DataSourceOptions.
+func (o InfluxDBFluxOptions) DataSourceType() string {
+	return "influxdb"
+}
+
+func (o InfluxDBFluxOptions) fixedJSONData() map[string]interface{} {
+	return map[string]interface{}{"version": "Flux"}
+}
+
+// Validate implements DataSourceOptions.
+func (o InfluxDBFluxOptions) Validate() error {
+	switch {
+	case o.Organization == "":
+		return errors.New("organization is required")
+	case o.DefaultBucket == "":
+		return errors.New("defaultBucket is required")
+	case o.Token == "":
+		return errors.New("token is required")
+	case o.MaxSeries < 0:
+		return errors.New("maxSeries must not be negative")
+	}
+	return firstError(o.DataSourceHTTPOptions.validate(), validateInterval("timeInterval", o.TimeInterval))
+}
+
+// InfluxDBInfluxQLOptions are the settings of InfluxDB data sources queried with InfluxQL. The user is the user
+// of the data source, and the password is kept in secureJsonData.
+type InfluxDBInfluxQLOptions struct {
+	DataSourceHTTPOptions
+
+	DBName       string `json:"dbName"`
+	HTTPMode     string `json:"httpMode,omitempty"`
+	TimeInterval string `json:"timeInterval,omitempty"`
+	MaxSeries    int64  `json:"maxSeries,omitempty"`
+
+	Password string `json:"-" secure:"password"`
+}
+
+// DataSourceType implements DataSourceOptions.
+func (o InfluxDBInfluxQLOptions) DataSourceType() string {
+	return "influxdb"
+}
+
+func (o InfluxDBInfluxQLOptions) fixedJSONData() map[string]interface{} {
+	return map[string]interface{}{"version": "InfluxQL"}
+}
+
+// Validate implements DataSourceOptions.
+func (o InfluxDBInfluxQLOptions) Validate() error {
+	switch {
+	case o.DBName == "":
+		return errors.New("dbName is required")
+	case o.MaxSeries < 0:
+		return errors.New("maxSeries must not be negative")
+	}
+	return firstError(
+		o.DataSourceHTTPOptions.validate(),
+		validateHTTPMethod("httpMode", o.HTTPMode),
+		validateInterval("timeInterval", o.TimeInterval),
+	)
+}
+
+// SQLConnectionOptions are the settings of the connection pools of SQL data sources. ConnMaxLifetime is in
+// seconds.
+type SQLConnectionOptions struct {
+	MaxOpenConns    int64 `json:"maxOpenConns,omitempty"`
+	MaxIdleConns    int64 `json:"maxIdleConns,omitempty"`
+	ConnMaxLifetime int64 `json:"connMaxLifetime,omitempty"`
+}
+
+func (o SQLConnectionOptions) validate() error {
+	switch {
+	case o.MaxOpenConns < 0 || o.MaxIdleConns < 0 || o.ConnMaxLifetime < 0:
+		return errors.New("connection settings must not be negative")
+	case o.MaxOpenConns > 0 && o.MaxIdleConns > o.MaxOpenConns:
+		return fmt.Errorf("maxIdleConns (%d) must not exceed maxOpenConns (%d)", o.MaxIdleConns, o.MaxOpenConns)
+	}
+	return nil
+}
+
+// PostgreSQLOptions are the settings of PostgreSQL data sources. The user is the user of the data source, and
+// the password is kept in secureJsonData.
+type PostgreSQLOptions struct {
+	SQLConnectionOptions
+
+	Database string `json:"database"`
+	// SSLMode is disable, require, verify-ca or verify-full.
+	SSLMode string `json:"sslmode,omitempty"`
+	// PostgresVersion is the version of PostgreSQL as a number, e.g. 1500 for 15.
+	PostgresVersion int64  `json:"postgresVersion,omitempty"`
+	TimescaleDB     bool   `json:"timescaledb,omitempty"`
+	TimeInterval    string `json:"timeInterval,omitempty"`
+
+	Password string `json:"-" secure:"password"`
+}
+
+// DataSourceType implements DataSourceOptions.
+func (o PostgreSQLOptions) DataSourceType() string {
+	return "postgres"
+}
+
+// Validate implements DataSourceOptions.
+func (o PostgreSQLOptions) Validate() error {
+	if o.Database == "" {
+		return errors.New("database is required")
+	}
+	switch o.SSLMode {
+	case "", "disable", "require", "verify-ca", "verify-full":
+	default:
+		return fmt.Errorf("unknown sslmode %q", o.SSLMode)
+	}
+	return firstError(o.SQLConnectionOptions.validate(), validateInterval("timeInterval", o.TimeInterval))
+}
+
+// MySQLOptions are the settings of MySQL data sources. The user is the user of the data source, and the
+// password is kept in secureJsonData.
+type MySQLOptions struct {
+	SQLConnectionOptions
+
+	Database string `json:"database"`
+	// Timezone is the time zone of the session, e.g. +02:00.
+	Timezone      string `json:"timezone,omitempty"`
+	TimeInterval  string `json:"timeInterval,omitempty"`
+	TLSAuth       bool   `json:"tlsAuth,omitempty"`
+	TLSSkipVerify bool   `json:"tlsSkipVerify,omitempty"`
+
+	Password      string `json:"-" secure:"password"`
+	TLSClientCert string `json:"-" secure:"tlsClientCert"`
+	TLSClientKey  string `json:"-" secure:"tlsClientKey"`
+}
+
+// DataSourceType implements DataSourceOptions.
+func (o MySQLOptions) DataSourceType() string {
+	return "mysql"
+}
+
+// Validate implements DataSourceOptions.
+func (o MySQLOptions) Validate() error {
+	switch {
+	case o.Database == "":
+		return errors.New("database is required")
+	case o.TLSAuth && (o.TLSClientCert == "" || o.TLSClientKey == ""):
+		return errors.New("TLS client authentication requires a client certificate and key")
+	case !o.TLSAuth && (o.TLSClientCert != "" || o.TLSClientKey != ""):
+		return errors.New("a TLS client certificate or key is set without TLS client authentication")
+	}
+	return firstError(o.SQLConnectionOptions.validate(), validateInterval("timeInterval", o.TimeInterval))
+}
+
+// CloudWatchOptions are the settings of CloudWatch data sources. AuthType is keys, credentials, default or
+// ec2_iam_role.
+type CloudWatchOptions struct {
+	AuthType                string `json:"authType,omitempty"`
+	DefaultRegion           string `json:"defaultRegion"`
+	AssumeRoleArn           string `json:"assumeRoleArn,omitempty"`
+	ExternalID              string `json:"externalId,omitempty"`
+	Profile                 string `json:"profile,omitempty"`
+	Endpoint                string `json:"endpoint,omitempty"`
+	CustomMetricsNamespaces string `json:"customMetricsNamespaces,omitempty"`
+	TracingDatasourceUID    string `json:"tracingDatasourceUid,omitempty"`
+
+	AccessKey string `json:"-" secure:"accessKey"`
+	SecretKey string `json:"-" secure:"secretKey"`
+}
+
+// DataSourceType implements DataSourceOptions.
+func (o CloudWatchOptions) DataSourceType() string {
+	return "cloudwatch"
+}
+
+// Validate implements DataSourceOptions.
+func (o CloudWatchOptions) Validate() error {
+	if o.DefaultRegion == "" {
+		return errors.New("defaultRegion is required")
+	}
+	return validateAWSAuth(o.AuthType, o.AccessKey, o.SecretKey, o.Profile, o.AssumeRoleArn, o.ExternalID)
+}
+
+// TempoOptions are the settings of Tempo data sources, mostly links from traces to the other data sources.
+type TempoOptions struct {
+	DataSourceHTTPOptions
+
+	TracesToLogs    *TempoTracesToLogs    `json:"tracesToLogsV2,omitempty"`
+	TracesToMetrics *TempoTracesToMetrics `json:"tracesToMetrics,omitempty"`
+	ServiceMap      *TempoServiceMap      `json:"serviceMap,omitempty"`
+	NodeGraph       *TempoNodeGraph       `json:"nodeGraph,omitempty"`
+}
+
+// TempoTracesToLogs links the spans of traces to the logs of a Loki, Elasticsearch or Splunk data source.
+// Query is only used with CustomQuery.
+type TempoTracesToLogs struct {
+	DatasourceUID      string     `json:"datasourceUid"`
+	Tags               []TempoTag `json:"tags,omitempty"`
+	SpanStartTimeShift string     `json:"spanStartTimeShift,omitempty"`
+	SpanEndTimeShift   string     `json:"spanEndTimeShift,omitempty"`
+	FilterByTraceID    bool       `json:"filterByTraceID,omitempty"`
+	FilterBySpanID     bool       `json:"filterBySpanID,omitempty"`
+	CustomQuery        bool       `json:"customQuery,omitempty"`
+	Query              string     `json:"query,omitempty"`
+}
+
+// TempoTracesToMetrics links the spans of traces to queries of a Prometheus data source.
+type TempoTracesToMetrics struct {
+	DatasourceUID      string       `json:"datasourceUid"`
+	Tags               []TempoTag   `json:"tags,omitempty"`
+	Queries            []TempoQuery `json:"queries,omitempty"`
+	SpanStartTimeShift string       `json:"spanStartTimeShift,omitempty"`
+	SpanEndTimeShift   string       `json:"spanEndTimeShift,omitempty"`
+}
+
+// TempoTag maps a span attribute to a label, which is the attribute itself if Value is empty.
+type TempoTag struct {
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+}
+
+// TempoQuery is a query linked from spans.
+type TempoQuery struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+}
+
+// TempoServiceMap sets the Prometheus data source with the metrics of the service graph.
+type TempoServiceMap struct {
+	DatasourceUID string `json:"datasourceUid"`
+}
+
+// TempoNodeGraph enables the node graph of traces.
+type TempoNodeGraph struct {
+	Enabled bool `json:"enabled"`
+}
+
+// DataSourceType implements DataSourceOptions.
+func (o TempoOptions) DataSourceType() string {
+	return "tempo"
+}
+
+// Validate implements DataSourceOptions.
+func (o TempoOptions) Validate() error {
+	if logs := o.TracesToLogs; logs != nil {
+		switch {
+		case logs.DatasourceUID == "":
+			return errors.New("traces to logs require a datasourceUid")
+		case logs.CustomQuery && logs.Query == "":
+			return errors.New("traces to logs with a custom query require a query")
+		case !logs.CustomQuery && logs.Query != "":
+			return errors.New("a traces to logs query is set without customQuery")
+		}
+	}
+	if metrics := o.TracesToMetrics; metrics != nil {
+		if metrics.DatasourceUID == "" {
+			return errors.New("traces to metrics require a datasourceUid")
+		}
+		for _, query := range metrics.Queries {
+			if query.Query == "" {
+				return fmt.Errorf("traces to metrics query %q is empty", query.Name)
+			}
+		}
+	}
+	if o.ServiceMap != nil && o.ServiceMap.DatasourceUID == "" {
+		return errors.New("the service map requires a datasourceUid")
+	}
+	return o.DataSourceHTTPOptions.validate()
+}
+
+// AzureMonitorOptions are the settings of Azure Monitor data sources. AzureAuthType is clientsecret, msi,
+// workloadidentity or currentuser.
+type AzureMonitorOptions struct {
+	AzureAuthType string `json:"azureAuthType"`
+	// CloudName is azuremonitor, chinaazuremonitor or govazuremonitor.
+	CloudName      string `json:"cloudName,omitempty"`
+	TenantID       string `json:"tenantId,omitempty"`
+	ClientID       string `json:"clientId,omitempty"`
+	SubscriptionID string `json:"subscriptionId,omitempty"`
+
+	ClientSecret string `json:"-" secure:"clientSecret"`
+}
+
+// DataSourceType implements DataSourceOptions.
+func (o AzureMonitorOptions) DataSourceType() string {
+	return "grafana-azure-monitor-datasource"
+}
+
+// Validate implements DataSourceOptions.
+func (o AzureMonitorOptions) Validate() error {
+	switch o.CloudName {
+	case "", "azuremonitor", "chinaazuremonitor", "govazuremonitor":
+	default:
+		return fmt.Errorf("unknown cloudName %q", o.CloudName)
+	}
+	switch o.AzureAuthType {
+	case "clientsecret":
+		if o.TenantID == "" || o.ClientID == "" || o.ClientSecret == "" {
+			return errors.New("clientsecret authentication requires a tenantId, a clientId and a clientSecret")
+		}
+	case "msi", "workloadidentity", "currentuser":
+		if o.ClientSecret != "" {
+			return fmt.Errorf("a clientSecret is set with %s authentication", o.AzureAuthType)
+		}
+	default:
+		return fmt.Errorf("unknown azureAuthType %q", o.AzureAuthType)
+	}
+	return nil
+}
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package gapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetOptions(t *testing.T) {
+	manageAlerts := false
+	ds := &DataSource{Name: "prometheus", JSONData: map[string]interface{}{"maxLines": 1000}}
+	err := ds.SetOptions(PrometheusOptions{
+		DataSourceHTTPOptions: DataSourceHTTPOptions{
+			TLSAuthWithCACert: true,
+			TLSCACert:         "ca",
+			Headers:           map[string]string{"X-Scope-OrgID": "tenant"},
+		},
+		SigV4Options: SigV4Options{
+			SigV4Auth:      true,
+			SigV4AuthType:  "keys",
+			SigV4Region:    "us-east-1",
+			SigV4AccessKey: "access",
+			SigV4SecretKey: "secret",
+		},
+		HTTPMethod:   "POST",
+		TimeInterval: "15s",
+		ManageAlerts: &manageAlerts,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedJSONData := map[string]interface{}{
+		"tlsAuthWithCACert": true,
+		"sigV4Auth":         true,
+		"sigV4AuthType":     "keys",
+		"sigV4Region":       "us-east-1",
+		"httpMethod":        "POST",
+		"timeInterval":      "15s",
+		"manageAlerts":      false,
+		"httpHeaderName1":   "X-Scope-OrgID",
+	}
+	expectedSecureJSONData := map[string]interface{}{
+		"tlsCACert":        "ca",
+		"sigV4AccessKey":   "access",
+		"sigV4SecretKey":   "secret",
+		"httpHeaderValue1": "tenant",
+	}
+	if ds.Type != "prometheus" {
+		t.Errorf("unexpected type: %s", ds.Type)
+	}
+	if !reflect.DeepEqual(ds.JSONData, expectedJSONData) {
+		t.Errorf("expected jsonData:\n%v\ngot:\n%v", expectedJSONData, ds.JSONData)
+	}
+	if !reflect.DeepEqual(ds.SecureJSONData, expectedSecureJSONData) {
+		t.Errorf("expected secureJsonData:\n%v\ngot:\n%v", expectedSecureJSONData, ds.SecureJSONData)
+	}
+}
+
+func TestDataSourceOptionsMaps(t *testing.T) {
+	jsonData, secureJSONData, err := DataSourceOptionsMaps(InfluxDBFluxOptions{
+		Organization:  "grafana",
+		DefaultBucket: "metrics",
+		Token:         "token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{"organization": "grafana", "defaultBucket": "metrics", "version": "Flux"}
+	if !reflect.DeepEqual(jsonData, expected) {
+		t.Errorf("expected jsonData:\n%v\ngot:\n%v", expected, jsonData)
+	}
+	if !reflect.DeepEqual(secureJSONData, map[string]interface{}{"token": "token"}) {
+		t.Errorf("unexpected secureJsonData: %v", secureJSONData)
+	}
+}
+
+func TestDataSourceOptionsValidate(t *testing.T) {
+	for name, test := range map[string]struct {
+		options DataSourceOptions
+		valid   bool
+	}{
+		"prometheus": {
+			options: PrometheusOptions{HTTPMethod: "POST", PrometheusType: "Mimir"},
+			valid:   true,
+		},
+		"prometheus http method": {
+			options: PrometheusOptions{HTTPMethod: "PUT"},
+		},
+		"prometheus interval": {
+			options: PrometheusOptions{TimeInterval: "15 seconds"},
+		},
+		"prometheus sigv4 without auth": {
+			options: PrometheusOptions{SigV4Options: SigV4Options{SigV4Region: "us-east-1"}},
+		},
+		"prometheus tls client key without auth": {
+			options: PrometheusOptions{DataSourceHTTPOptions: DataSourceHTTPOptions{TLSClientKey: "key"}},
+		},
+		"loki": {
+			options: LokiOptions{MaxLines: 1000, DerivedFields: []LokiDerivedField{{Name: "traceID", MatcherRegex: "trace_id=(\\w+)", URL: "${__value.raw}", DatasourceUID: "tempo"}}},
+			valid:   true,
+		},
+		"loki derived field without regex": {
+			options: LokiOptions{DerivedFields: []LokiDerivedField{{Name: "traceID", URL: "${__value.raw}"}}},
+		},
+		"elasticsearch": {
+			options: ElasticsearchOptions{Index: "[logs-]YYYY.MM.DD", TimeField: "@timestamp", Interval: "Daily"},
+			valid:   true,
+		},
+		"elasticsearch without time field": {
+			options: ElasticsearchOptions{Index: "logs"},
+		},
+		"elasticsearch frozen indexes without xpack": {
+			options: ElasticsearchOptions{Index: "logs", TimeField: "@timestamp", IncludeFrozen: true},
+		},
+		"influxdb flux without token": {
+			options: InfluxDBFluxOptions{Organization: "grafana", DefaultBucket: "metrics"},
+		},
+		"influxdb influxql": {
+			options: InfluxDBInfluxQLOptions{DBName: "telegraf", HTTPMode: "GET", Password: "secret"},
+			valid:   true,
+		},
+		"postgres": {
+			options: PostgreSQLOptions{Database: "grafana", SSLMode: "verify-full", SQLConnectionOptions: SQLConnectionOptions{MaxOpenConns: 10, MaxIdleConns: 5}},
+			valid:   true,
+		},
+		"postgres sslmode": {
+			options: PostgreSQLOptions{Database: "grafana", SSLMode: "prefer-not"},
+		},
+		"mysql idle connections": {
+			options: MySQLOptions{Database: "grafana", SQLConnectionOptions: SQLConnectionOptions{MaxOpenConns: 2, MaxIdleConns: 5}},
+		},
+		"cloudwatch": {
+			options: CloudWatchOptions{AuthType: "keys", DefaultRegion: "us-east-1", AccessKey: "access", SecretKey: "secret"},
+			valid:   true,
+		},
+		"cloudwatch keys with default auth": {
+			options: CloudWatchOptions{AuthType: "default", DefaultRegion: "us-east-1", AccessKey: "access"},
+		},
+		"cloudwatch external id without role": {
+			options: CloudWatchOptions{DefaultRegion: "us-east-1", ExternalID: "id"},
+		},
+		"tempo": {
+			options: TempoOptions{TracesToLogs: &TempoTracesToLogs{DatasourceUID: "loki", FilterByTraceID: true}, ServiceMap: &TempoServiceMap{DatasourceUID: "prom"}},
+			valid:   true,
+		},
+		"tempo custom query without query": {
+			options: TempoOptions{TracesToLogs: &TempoTracesToLogs{DatasourceUID: "loki", CustomQuery: true}},
+		},
+		"azure monitor": {
+			options: AzureMonitorOptions{AzureAuthType: "clientsecret", TenantID: "tenant", ClientID: "client", ClientSecret: "secret"},
+			valid:   true,
+		},
+		"azure monitor secret with managed identity": {
+			options: AzureMonitorOptions{AzureAuthType: "msi", ClientSecret: "secret"},
+		},
+	} {
+		err := test.options.Validate()
+		if test.valid && err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	ds := &DataSource{}
+	if err := ds.SetOptions(ElasticsearchOptions{}); err == nil || err.Error() != "invalid elasticsearch options: index is required" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if ds.Type != "" {
+		t.Errorf("expected invalid options not to be set; got type %s", ds.Type)
+	}
+}
@@ -0,0 +1,336 @@
+package gapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DataQueryRequest is a request of /api/ds/query, running queries against data sources over a time range.
+// From and To are either relative times, like now-1h, or epoch milliseconds.
+type DataQueryRequest struct {
+	Queries []DataQuery `json:"queries"`
+	From    string      `json:"from"`
+	To      string      `json:"to"`
+}
+
+// NewDataQueryRequest returns a request running queries over an absolute time range.
+func NewDataQueryRequest(from, to time.Time, queries ...DataQuery) DataQueryRequest {
+	return DataQueryRequest{
+		Queries: queries,
+		From:    strconv.FormatInt(from.UnixNano()/int64(time.Millisecond), 10),
+		To:      strconv.FormatInt(to.UnixNano()/int64(time.Millisecond), 10),
+	}
+}
+
+// DataQuery is a query of a data source. Like for the targets of dashboard panels, the query itself depends on
+// the data source and is kept in Extra, e.g. Extra["expr"] for Prometheus.
+type DataQuery struct {
+	RefID         string                     `json:"refId"`
+	Datasource    *DashboardDataSourceRef    `json:"datasource"`
+	QueryType     string                     `json:"queryType,omitempty"`
+	IntervalMS    int64                      `json:"intervalMs,omitempty"`
+	MaxDataPoints int64                      `json:"maxDataPoints,omitempty"`
+	Hide          bool                       `json:"hide,omitempty"`
+	Extra         map[string]json.RawMessage `json:"-"`
+}
+
+// NewDataQuery returns a query of the data source with the given UID, whose fields specific to the data source
+// are given by model.
+func NewDataQuery(refID, dataSourceUID string, model map[string]interface{}) (DataQuery, error) {
+	query := DataQuery{
+		RefID:      refID,
+		Datasource: &DashboardDataSourceRef{UID: dataSourceUID},
+		Extra:      map[string]json.RawMessage{},
+	}
+	for key, value := range model {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return DataQuery{}, fmt.Errorf("invalid query field %s: %w", key, err)
+		}
+		query.Extra[key] = data
+	}
+	return query, nil
+}
+
+// MarshalJSON adds the fields kept in Extra.
+func (q DataQuery) MarshalJSON() ([]byte, error) {
+	type plain DataQuery
+	return marshalWithExtra(plain(q), q.Extra)
+}
+
+// UnmarshalJSON keeps the fields specific to the data source in Extra.
+func (q *DataQuery) UnmarshalJSON(data []byte) error {
+	type plain DataQuery
+	extra, err := unmarshalWithExtra(data, (*plain)(q))
+	q.Extra = extra
+	return err
+}
+
+// DataQueryResponse holds the results of the queries of a request, by refId.
+type DataQueryResponse struct {
+	Results map[string]DataResponse `json:"results"`
+}
+
+// Err returns the errors of the queries which failed, if any.
+func (r *DataQueryResponse) Err() error {
+	var failed []string
+	for refID, result := range r.Results {
+		if result.Error != "" {
+			failed = append(failed, fmt.Sprintf("query %s: %s", refID, result.Error))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	sort.Strings(failed)
+	return fmt.Errorf("queries failed: %s", strings.Join(failed, "; "))
+}
+
+// DataResponse is the result of a query: the data frames it returned, or its error.
+type DataResponse struct {
+	Status      int         `json:"status,omitempty"`
+	Frames      []DataFrame `json:"frames"`
+	Error       string      `json:"error,omitempty"`
+	ErrorSource string      `json:"errorSource,omitempty"`
+}
+
+// DataFrame is a table of data returned by a query, made of columns called fields. Time series are frames of a
+// time field and a number field, whose labels identify the series.
+type DataFrame struct {
+	Name   string
+	RefID  string
+	Meta   *FrameMeta
+	Fields []*Field
+}
+
+// FrameMeta is the metadata of a data frame, set by the data source.
+type FrameMeta struct {
+	Type                       string                   `json:"type,omitempty"`
+	TypeVersion                []int                    `json:"typeVersion,omitempty"`
+	ExecutedQueryString        string                   `json:"executedQueryString,omitempty"`
+	PreferredVisualisationType string                   `json:"preferredVisualisationType,omitempty"`
+	Notices                    []Notice                 `json:"notices,omitempty"`
+	Stats                      []map[string]interface{} `json:"stats,omitempty"`
+	Custom                     interface{}              `json:"custom,omitempty"`
+}
+
+// Notice is a message attached to a data frame, e.g. a warning about the query.
+type Notice struct {
+	// Severity is info, warning or error.
+	Severity string `json:"severity"`
+	Text     string `json:"text"`
+	Link     string `json:"link,omitempty"`
+	Inspect  string `json:"inspect,omitempty"`
+}
+
+// FieldType is the type of the values of a field.
+type FieldType string
+
+const (
+	FieldTypeTime    FieldType = "time"
+	FieldTypeNumber  FieldType = "number"
+	FieldTypeString  FieldType = "string"
+	FieldTypeBoolean FieldType = "boolean"
+	FieldTypeEnum    FieldType = "enum"
+	FieldTypeOther   FieldType = "other"
+)
+
+// Field is a column of a data frame. Its values are decoded according to the Go type of the field in Grafana:
+// times as time.Time, integers as int64 or uint64, floats as float64, strings, booleans, and other values as
+// json.RawMessage. Null values are nil.
+type Field struct {
+	Name   string
+	Type   FieldType
+	Labels map[string]string
+	Config map[string]interface{}
+	Values []interface{}
+}
+
+// Len returns the number of values of the field.
+func (f *Field) Len() int {
+	return len(f.Values)
+}
+
+// Float returns a number value of the field as a float64, and false if it's null or not a number.
+func (f *Field) Float(i int) (float64, bool) {
+	switch v := f.Values[i].(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// Time returns a time value of the field, and false if it's null or not a time.
+func (f *Field) Time(i int) (time.Time, bool) {
+	t, ok := f.Values[i].(time.Time)
+	return t, ok
+}
+
+// Field returns the field of the frame with the given name, or nil.
+func (f *DataFrame) Field(name string) *Field {
+	for _, field := range f.Fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+type frameJSON struct {
+	Schema struct {
+		Name   string      `json:"name,omitempty"`
+		RefID  string      `json:"refId,omitempty"`
+		Meta   *FrameMeta  `json:"meta,omitempty"`
+		Fields []fieldJSON `json:"fields"`
+	} `json:"schema"`
+	Data struct {
+		Values   [][]json.RawMessage `json:"values"`
+		Entities []*fieldEntities    `json:"entities,omitempty"`
+		Nanos    [][]int64           `json:"nanos,omitempty"`
+	} `json:"data"`
+}
+
+type fieldJSON struct {
+	Name     string    `json:"name"`
+	Type     FieldType `json:"type"`
+	TypeInfo struct {
+		Frame    string `json:"frame"`
+		Nullable bool   `json:"nullable,omitempty"`
+	} `json:"typeInfo"`
+	Labels map[string]string      `json:"labels,omitempty"`
+	Config map[string]interface{} `json:"config,omitempty"`
+}
+
+// fieldEntities lists the indexes of the values which JSON can't represent, and which are null in the values.
+type fieldEntities struct {
+	NaN    []int `json:"NaN,omitempty"`
+	Inf    []int `json:"Inf,omitempty"`
+	NegInf []int `json:"NegInf,omitempty"`
+}
+
+// restore sets the values which JSON can't represent.
+func (e *fieldEntities) restore(values []interface{}) {
+	set := func(indexes []int, value float64) {
+		for _, i := range indexes {
+			if i >= 0 && i < len(values) {
+				values[i] = value
+			}
+		}
+	}
+	set(e.NaN, math.NaN())
+	set(e.Inf, math.Inf(1))
+	set(e.NegInf, math.Inf(-1))
+}
+
+// UnmarshalJSON decodes the JSON format of data frames, which has the schema and the values of the fields
+// apart.
+func (f *DataFrame) UnmarshalJSON(data []byte) error {
+	frame := frameJSON{}
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return err
+	}
+
+	*f = DataFrame{Name: frame.Schema.Name, RefID: frame.Schema.RefID, Meta: frame.Schema.Meta}
+	for i, schema := range frame.Schema.Fields {
+		field := &Field{Name: schema.Name, Type: schema.Type, Labels: schema.Labels, Config: schema.Config}
+		goType := strings.TrimPrefix(schema.TypeInfo.Frame, "*")
+
+		var values []json.RawMessage
+		if i < len(frame.Data.Values) {
+			values = frame.Data.Values[i]
+		}
+		var nanos []int64
+		if i < len(frame.Data.Nanos) {
+			nanos = frame.Data.Nanos[i]
+		}
+		field.Values = make([]interface{}, len(values))
+		for j, raw := range values {
+			value, err := decodeFieldValue(raw, goType, schema.Type)
+			if err != nil {
+				return fmt.Errorf("invalid value %d of field %s: %w", j, schema.Name, err)
+			}
+			if t, ok := value.(time.Time); ok && j < len(nanos) {
+				value = t.Add(time.Duration(nanos[j]))
+			}
+			field.Values[j] = value
+		}
+
+		if i < len(frame.Data.Entities) && frame.Data.Entities[i] != nil {
+			frame.Data.Entities[i].restore(field.Values)
+		}
+		f.Fields = append(f.Fields, field)
+	}
+	return nil
+}
+
+// decodeFieldValue decodes a value according to the Go type of its field, or to its field type if Grafana
+// doesn't give the Go type.
+func decodeFieldValue(raw json.RawMessage, goType string, fieldType FieldType) (interface{}, error) {
+	if string(raw) == "null" {
+		return nil, nil
+	}
+	if goType == "" {
+		switch fieldType {
+		case FieldTypeTime:
+			goType = "time.Time"
+		case FieldTypeNumber:
+			goType = "float64"
+		case FieldTypeString:
+			goType = "string"
+		case FieldTypeBoolean:
+			goType = "bool"
+		}
+	}
+
+	switch goType {
+	case "time.Time":
+		// Times are epoch milliseconds, whose nanoseconds are given apart.
+		ms, err := strconv.ParseInt(string(raw), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return time.Unix(0, ms*int64(time.Millisecond)).UTC(), nil
+	case "float32", "float64":
+		return strconv.ParseFloat(string(raw), 64)
+	case "int8", "int16", "int32", "int64", "enum":
+		return strconv.ParseInt(string(raw), 10, 64)
+	case "uint8", "uint16", "uint32", "uint64":
+		return strconv.ParseUint(string(raw), 10, 64)
+	case "string":
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return s, err
+	case "bool":
+		var b bool
+		err := json.Unmarshal(raw, &b)
+		return b, err
+	}
+	return json.RawMessage(raw), nil
+}
+
+// QueryData runs queries against data sources through /api/ds/query. When a query fails, Grafana fails the
+// request with the status of the query, unless it's configured to respond with 207 Multi-Status: the errors
+// of the queries are then returned by the Err method of the response.
+func (c *Client) QueryData(request DataQueryRequest) (*DataQueryResponse, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DataQueryResponse{}
+	err = c.request("POST", "/api/ds/query", nil, bytes.NewBuffer(data), response)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
@@ -0,0 +1,139 @@
+package gapi
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+const dataQueryResponseJSON = `{
+	"results": {
+		"A": {
+			"status": 200,
+			"frames": [{
+				"schema": {
+					"refId": "A",
+					"meta": {
+						"type": "timeseries-multi",
+						"typeVersion": [0, 1],
+						"executedQueryString": "Expr: up\nStep: 15s",
+						"notices": [{"severity": "warning", "text": "Step was raised"}]
+					},
+					"fields": [
+						{"name": "Time", "type": "time", "typeInfo": {"frame": "time.Time"}},
+						{"name": "Value", "type": "number", "typeInfo": {"frame": "*float64", "nullable": true}, "labels": {"job": "grafana"}}
+					]
+				},
+				"data": {
+					"values": [
+						[1700000000000, 1700000015000, 1700000030000, 1700000045000],
+						[1, null, null, 0.5]
+					],
+					"entities": [null, {"NaN": [2]}],
+					"nanos": [[0, 0, 0, 500]]
+				}
+			}]
+		},
+		"B": {
+			"status": 400,
+			"error": "parse error at char 4",
+			"errorSource": "downstream"
+		},
+		"C": {
+			"frames": [{
+				"schema": {
+					"name": "logs",
+					"fields": [
+						{"name": "count", "type": "number", "typeInfo": {"frame": "int64"}},
+						{"name": "line", "type": "string"},
+						{"name": "ok", "type": "boolean", "typeInfo": {"frame": "bool"}},
+						{"name": "raw", "type": "other", "typeInfo": {"frame": "json.RawMessage"}}
+					]
+				},
+				"data": {"values": [[9007199254740993], ["level=info"], [true], [{"a": 1}]]}
+			}]
+		}
+	}
+}`
+
+func TestQueryData(t *testing.T) {
+	client := gapiTestTools(t, 200, dataQueryResponseJSON)
+
+	query, err := NewDataQuery("A", "prom", map[string]interface{}{"expr": "up"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.QueryData(NewDataQueryRequest(time.Unix(1700000000, 0), time.Unix(1700003600, 0), query))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frame := resp.Results["A"].Frames[0]
+	if frame.RefID != "A" || frame.Meta.Type != "timeseries-multi" || frame.Meta.Notices[0].Severity != "warning" {
+		t.Errorf("unexpected frame: %+v", frame)
+	}
+	times, values := frame.Field("Time"), frame.Field("Value")
+	if times.Len() != 4 || values.Labels["job"] != "grafana" {
+		t.Fatalf("unexpected fields: %+v, %+v", times, values)
+	}
+	if tm, ok := times.Time(0); !ok || !tm.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("unexpected time: %v", tm)
+	}
+	if tm, _ := times.Time(3); !tm.Equal(time.Unix(1700000045, 500)) {
+		t.Errorf("expected the nanoseconds to be added; got: %v", tm)
+	}
+	if v, ok := values.Float(0); !ok || v != 1 {
+		t.Errorf("unexpected value: %v", v)
+	}
+	if _, ok := values.Float(1); ok {
+		t.Error("expected a null value")
+	}
+	if v, _ := values.Float(2); !math.IsNaN(v) {
+		t.Errorf("expected NaN; got: %v", v)
+	}
+
+	logs := resp.Results["C"].Frames[0]
+	if count := logs.Field("count").Values[0]; count != int64(9007199254740993) {
+		t.Errorf("expected an exact int64; got: %#v", count)
+	}
+	if line := logs.Field("line").Values[0]; line != "level=info" {
+		t.Errorf("unexpected string: %#v", line)
+	}
+	if ok := logs.Field("ok").Values[0]; ok != true {
+		t.Errorf("unexpected boolean: %#v", ok)
+	}
+	if raw, _ := logs.Field("raw").Values[0].(json.RawMessage); string(raw) != `{"a": 1}` {
+		t.Errorf("unexpected raw value: %s", raw)
+	}
+
+	if err := resp.Err(); err == nil || err.Error() != "queries failed: query B: parse error at char 4" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDataQueryJSON(t *testing.T) {
+	query, err := NewDataQuery("A", "prom", map[string]interface{}{"expr": "up", "instant": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	query.MaxDataPoints = 100
+	request := NewDataQueryRequest(time.Unix(1700000000, 0), time.Unix(1700003600, 0), query)
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"queries":[{"datasource":{"uid":"prom"},"expr":"up","instant":true,"maxDataPoints":100,"refId":"A"}],"from":"1700000000000","to":"1700003600000"}`
+	if string(data) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, data)
+	}
+
+	parsed := DataQueryRequest{}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if string(parsed.Queries[0].Extra["expr"]) != `"up"` || parsed.Queries[0].Datasource.UID != "prom" {
+		t.Errorf("unexpected parsed query: %+v", parsed.Queries[0])
+	}
+}
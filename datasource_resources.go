@@ -0,0 +1,128 @@
+package gapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+)
+
+// DataSourceResource calls a resource endpoint of the plugin of the data source with the given UID, e.g.
+// api/v1/labels for Prometheus, and decodes its JSON response into responseStruct. A *[]byte responseStruct
+// gets the response as is.
+func (c *Client) DataSourceResource(method, uid, resourcePath string, query url.Values, body io.Reader, responseStruct interface{}) error {
+	path := fmt.Sprintf("/api/datasources/uid/%s/resources/%s", uid, resourcePath)
+	return c.request(method, path, query, body, responseStruct)
+}
+
+// DataSourceProxy calls the backend of the data source with the given UID through the data source proxy of
+// Grafana, which adds the authentication of the data source, and decodes the JSON response into
+// responseStruct. A *[]byte responseStruct gets the response as is. Resource endpoints are preferred, since
+// plugins may not support the proxy.
+func (c *Client) DataSourceProxy(method, uid, proxyPath string, query url.Values, body io.Reader, responseStruct interface{}) error {
+	path := fmt.Sprintf("/api/datasources/proxy/uid/%s/%s", uid, proxyPath)
+	return c.request(method, path, query, body, responseStruct)
+}
+
+// LabelOptions restrict the labels and series found by the Prometheus and Loki helpers. Matchers are series
+// selectors, e.g. {job="grafana"}. Start and End are ignored if they're zero.
+type LabelOptions struct {
+	Matchers []string
+	Start    time.Time
+	End      time.Time
+}
+
+func (o LabelOptions) query(matcherParam string) (url.Values, error) {
+	// The label APIs of Loki take a single selector as query.
+	if matcherParam == "query" && len(o.Matchers) > 1 {
+		return nil, errors.New("labels of Loki can only be restricted by a single matcher")
+	}
+	query := url.Values{}
+	for _, matcher := range o.Matchers {
+		query.Add(matcherParam, matcher)
+	}
+	if !o.Start.IsZero() {
+		query.Set("start", o.Start.UTC().Format(time.RFC3339Nano))
+	}
+	if !o.End.IsZero() {
+		query.Set("end", o.End.UTC().Format(time.RFC3339Nano))
+	}
+	return query, nil
+}
+
+// PrometheusLabels returns the names of the labels of a Prometheus data source.
+func (c *Client) PrometheusLabels(uid string, opts LabelOptions) ([]string, error) {
+	labels := []string{}
+	err := c.labelsAPI(uid, "api/v1/labels", "match[]", opts, &labels)
+	return labels, err
+}
+
+// PrometheusLabelValues returns the values of a label of a Prometheus data source.
+func (c *Client) PrometheusLabelValues(uid, label string, opts LabelOptions) ([]string, error) {
+	values := []string{}
+	err := c.labelsAPI(uid, fmt.Sprintf("api/v1/label/%s/values", url.PathEscape(label)), "match[]", opts, &values)
+	return values, err
+}
+
+// PrometheusSeries returns the label sets of the series of a Prometheus data source matching the matchers of
+// opts, which are required.
+func (c *Client) PrometheusSeries(uid string, opts LabelOptions) ([]map[string]string, error) {
+	if len(opts.Matchers) == 0 {
+		return nil, errors.New("finding series requires at least one matcher")
+	}
+	series := []map[string]string{}
+	err := c.labelsAPI(uid, "api/v1/series", "match[]", opts, &series)
+	return series, err
+}
+
+// LokiLabels returns the names of the labels of a Loki data source. opts may have a single matcher.
+func (c *Client) LokiLabels(uid string, opts LabelOptions) ([]string, error) {
+	labels := []string{}
+	err := c.labelsAPI(uid, "labels", "query", opts, &labels)
+	return labels, err
+}
+
+// LokiLabelValues returns the values of a label of a Loki data source. opts may have a single matcher.
+func (c *Client) LokiLabelValues(uid, label string, opts LabelOptions) ([]string, error) {
+	values := []string{}
+	err := c.labelsAPI(uid, fmt.Sprintf("label/%s/values", url.PathEscape(label)), "query", opts, &values)
+	return values, err
+}
+
+// LokiSeries returns the label sets of the streams of a Loki data source matching the matchers of opts, which
+// are required.
+func (c *Client) LokiSeries(uid string, opts LabelOptions) ([]map[string]string, error) {
+	if len(opts.Matchers) == 0 {
+		return nil, errors.New("finding series requires at least one matcher")
+	}
+	series := []map[string]string{}
+	err := c.labelsAPI(uid, "series", "match[]", opts, &series)
+	return series, err
+}
+
+// labelsAPI calls an endpoint of the label APIs of Prometheus and Loki through the resources of the data source,
+// decoding the data of the response.
+func (c *Client) labelsAPI(uid, resourcePath, matcherParam string, opts LabelOptions, data interface{}) error {
+	query, err := opts.query(matcherParam)
+	if err != nil {
+		return err
+	}
+
+	resp := struct {
+		Status string          `json:"status"`
+		Data   json.RawMessage `json:"data"`
+		Error  string          `json:"error"`
+	}{}
+	if err := c.DataSourceResource("GET", uid, resourcePath, query, nil, &resp); err != nil {
+		return err
+	}
+	if resp.Status != "success" {
+		return fmt.Errorf("%s failed: %s", resourcePath, resp.Error)
+	}
+	if len(resp.Data) == 0 || string(resp.Data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(resp.Data, data)
+}
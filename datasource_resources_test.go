@@ -0,0 +1,187 @@
+package gapi
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// requestPaths returns the paths of recorded requests, without their queries.
+func requestPaths(requests []mockRequest) []string {
+	paths := make([]string, len(requests))
+	for i, request := range requests {
+		paths[i] = strings.SplitN(request.uri, "?", 2)[0]
+	}
+	return paths
+}
+
+func TestDataSourceResourceAndProxy(t *testing.T) {
+	client := gapiTestToolsFromCalls(t, []mockServerCall{
+		{200, `{"tags": ["service.name"]}`},
+		{200, `{"logs": {}}`},
+		{200, "not json"},
+		{404, `{"message": "not found"}`},
+	})
+	requests := recordRequests(t, client)
+
+	tags := struct {
+		Tags []string `json:"tags"`
+	}{}
+	if err := client.DataSourceResource("GET", "tempo", "tags", nil, nil, &tags); err != nil {
+		t.Fatal(err)
+	}
+	if len(tags.Tags) != 1 || tags.Tags[0] != "service.name" {
+		t.Errorf("unexpected tags: %+v", tags)
+	}
+
+	var raw []byte
+	if err := client.DataSourceProxy("GET", "es", "logs/_mapping", url.Values{"pretty": {"true"}}, nil, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if uri := (*requests)[1].uri; string(raw) != `{"logs": {}}` || uri != "/api/datasources/proxy/uid/es/logs/_mapping?pretty=true" {
+		t.Errorf("unexpected response %s to %s", raw, uri)
+	}
+
+	if err := client.DataSourceResource("GET", "prom", "api/v1/status/tsdb", nil, nil, &tags); err == nil {
+		t.Error("expected an error decoding the response")
+	}
+	if err := client.DataSourceResource("GET", "missing", "tags", nil, nil, &tags); !IsNotFound(err) {
+		t.Errorf("expected a not found error; got: %v", err)
+	}
+
+	expected := []string{
+		"/api/datasources/uid/tempo/resources/tags",
+		"/api/datasources/proxy/uid/es/logs/_mapping",
+		"/api/datasources/uid/prom/resources/api/v1/status/tsdb",
+		"/api/datasources/uid/missing/resources/tags",
+	}
+	if paths := requestPaths(*requests); !reflect.DeepEqual(paths, expected) {
+		t.Errorf("unexpected requests: %v", paths)
+	}
+}
+
+func TestPrometheusLabels(t *testing.T) {
+	client := gapiTestToolsFromCalls(t, []mockServerCall{
+		{200, `{"status": "success", "data": ["__name__", "job"]}`},
+		{200, `{"status": "success", "data": ["grafana", "prometheus"]}`},
+		{200, `{"status": "error", "error": "invalid label name"}`},
+		{200, `{"status": "success", "data": [{"__name__": "up", "job": "grafana"}]}`},
+		{200, `{"status": "success", "data": ["app"]}`},
+		{200, `{"status": "success"}`},
+		{200, `{"status": "success", "data": [{"app": "grafana"}]}`},
+	})
+	requests := recordRequests(t, client)
+
+	start := time.Date(2023, 11, 14, 22, 0, 0, 0, time.UTC)
+	labels, err := client.PrometheusLabels("prom", LabelOptions{Matchers: []string{`{job="grafana"}`, "up"}, Start: start})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(labels, []string{"__name__", "job"}) {
+		t.Errorf("unexpected labels: %v", labels)
+	}
+	query, _ := url.ParseQuery(strings.SplitN((*requests)[0].uri, "?", 2)[1])
+	if !reflect.DeepEqual(query["match[]"], []string{`{job="grafana"}`, "up"}) || query.Get("start") != "2023-11-14T22:00:00Z" || query.Get("end") != "" {
+		t.Errorf("unexpected request: %s", (*requests)[0].uri)
+	}
+
+	values, err := client.PrometheusLabelValues("prom", "job", LabelOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, []string{"grafana", "prometheus"}) {
+		t.Errorf("unexpected values: %v", values)
+	}
+	if _, err := client.PrometheusLabelValues("prom", "bad", LabelOptions{}); err == nil || !strings.Contains(err.Error(), "invalid label name") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	series, err := client.PrometheusSeries("prom", LabelOptions{Matchers: []string{"up"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 1 || series[0]["job"] != "grafana" {
+		t.Errorf("unexpected series: %v", series)
+	}
+	if _, err := client.PrometheusSeries("prom", LabelOptions{}); err == nil {
+		t.Error("expected an error without matchers")
+	}
+
+	labels, err = client.LokiLabels("loki", LabelOptions{Matchers: []string{`{app="grafana"}`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri := (*requests)[4].uri; !reflect.DeepEqual(labels, []string{"app"}) || uri != "/api/datasources/uid/loki/resources/labels?query=%7Bapp%3D%22grafana%22%7D" {
+		t.Errorf("unexpected labels %v from %s", labels, uri)
+	}
+	if _, err := client.LokiLabels("loki", LabelOptions{Matchers: []string{"{a=\"b\"}", "{c=\"d\"}"}}); err == nil {
+		t.Error("expected an error with several matchers")
+	}
+	values, err = client.LokiLabelValues("loki", "app", LabelOptions{})
+	if err != nil || len(values) != 0 {
+		t.Errorf("expected no values; got: %v, %v", values, err)
+	}
+	series, err = client.LokiSeries("loki", LabelOptions{Matchers: []string{`{app="grafana"}`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 1 || series[0]["app"] != "grafana" {
+		t.Errorf("unexpected series: %v", series)
+	}
+
+	expected := []string{
+		"/api/datasources/uid/prom/resources/api/v1/labels",
+		"/api/datasources/uid/prom/resources/api/v1/label/job/values",
+		"/api/datasources/uid/prom/resources/api/v1/label/bad/values",
+		"/api/datasources/uid/prom/resources/api/v1/series",
+		"/api/datasources/uid/loki/resources/labels",
+		"/api/datasources/uid/loki/resources/label/app/values",
+		"/api/datasources/uid/loki/resources/series",
+	}
+	if paths := requestPaths(*requests); !reflect.DeepEqual(paths, expected) {
+		t.Errorf("unexpected requests: %v", paths)
+	}
+}
+
+func TestLabelValuesEscaping(t *testing.T) {
+	client := gapiTestToolsFromCalls(t, []mockServerCall{
+		{200, `{"status": "success", "data": []}`},
+		{200, `{"status": "success", "data": []}`},
+	})
+	requests := recordRequests(t, client)
+
+	if _, err := client.PrometheusLabelValues("prom", "a/b?c%", LabelOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.LokiLabelValues("loki", "a/b?c%", LabelOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"/api/datasources/uid/prom/resources/api/v1/label/a%2Fb%3Fc%25/values",
+		"/api/datasources/uid/loki/resources/label/a%2Fb%3Fc%25/values",
+	}
+	if paths := requestPaths(*requests); !reflect.DeepEqual(paths, expected) {
+		t.Errorf("unexpected requests: %v", paths)
+	}
+}
+
+func TestDataSourceResourceOrg(t *testing.T) {
+	client := gapiTestTools(t, 200, `{"status": "success"}`)
+	requests := recordRequests(t, client)
+
+	var raw []byte
+	err := client.WithOrgID(3).DataSourceResource("POST", "prom", "api/v1/query", nil, strings.NewReader(`{"query": "up"}`), &raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := (*requests)[0]
+	if request.method != "POST" || request.header.Get("X-Grafana-Org-Id") != "3" || string(request.body) != `{"query": "up"}` {
+		t.Errorf("unexpected request: %+v", request)
+	}
+	if string(raw) != `{"status": "success"}` {
+		t.Errorf("unexpected response: %s", raw)
+	}
+}
@@ -0,0 +1,95 @@
+package gapi
+
+import (
+	"fmt"
+	"sort"
+)
+
+// HasSecret reports whether a secret of secureJsonData is set, according to the secureJsonFields returned by the
+// API.
+func (ds *DataSource) HasSecret(name string) bool {
+	return ds.SecureJSONFields[name]
+}
+
+// MissingSecrets returns the names of the secrets of the SecureJSONData of ds which aren't set on existing, a
+// data source returned by the API, sorted. These secrets have to be sent by an update. Secrets set to an empty
+// string, which remove them, aren't missing. A nil existing has no secrets set.
+func (ds *DataSource) MissingSecrets(existing *DataSource) []string {
+	missing := []string{}
+	for name, value := range ds.secrets() {
+		if value != "" && (existing == nil || !existing.HasSecret(name)) {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// secrets returns the secrets of the data source, including the deprecated password fields, which Grafana
+// stores in secureJsonData.
+func (ds *DataSource) secrets() map[string]interface{} {
+	secrets := map[string]interface{}{}
+	for name, value := range ds.SecureJSONData {
+		secrets[name] = value
+	}
+	if ds.Password != "" {
+		secrets["password"] = ds.Password
+	}
+	if ds.BasicAuthPassword != "" {
+		secrets["basicAuthPassword"] = ds.BasicAuthPassword
+	}
+	return secrets
+}
+
+// DataSourceSecretsUpdate tells UpdateDataSourceSecrets what to do with the secrets of a data source.
+type DataSourceSecretsUpdate struct {
+	// KeepExisting doesn't send the secrets of SecureJSONData which are already set, which are left untouched.
+	// Only missing secrets are sent.
+	KeepExisting bool
+	// Reset lists secrets to remove.
+	Reset []string
+	// ResetUnlisted removes the secrets which are set but which SecureJSONData doesn't have.
+	ResetUnlisted bool
+}
+
+// UpdateDataSourceSecrets updates a data source, identified by its UID, like UpdateDataSourceByUID, but sends
+// its secrets according to opts. ds isn't modified.
+func (c *Client) UpdateDataSourceSecrets(ds *DataSource, opts DataSourceSecretsUpdate) error {
+	secrets := ds.secrets()
+	for _, name := range opts.Reset {
+		if value := secrets[name]; value != nil && value != "" {
+			return fmt.Errorf("secret %s of data source %s is both set and reset", name, ds.UID)
+		}
+	}
+
+	update := *ds
+	update.Password = ""
+	update.BasicAuthPassword = ""
+	update.SecureJSONData = secrets
+
+	if opts.KeepExisting || opts.ResetUnlisted {
+		existing, err := c.DataSourceByUID(ds.UID)
+		if err != nil {
+			return err
+		}
+		for name, set := range existing.SecureJSONFields {
+			if !set {
+				continue
+			}
+			value, listed := update.SecureJSONData[name]
+			if listed && value != "" && opts.KeepExisting {
+				delete(update.SecureJSONData, name)
+			} else if !listed && opts.ResetUnlisted {
+				update.SecureJSONData[name] = ""
+			}
+		}
+	}
+
+	for _, name := range opts.Reset {
+		update.SecureJSONData[name] = ""
+	}
+	if len(update.SecureJSONData) == 0 {
+		update.SecureJSONData = nil
+	}
+	return c.UpdateDataSourceByUID(&update)
+}
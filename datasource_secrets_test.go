@@ -0,0 +1,96 @@
+package gapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// existingSecretsJSON is a data source whose password and basic auth password are set.
+const existingSecretsJSON = `{"uid": "pg", "name": "PostgreSQL", "secureJsonFields": {"password": true, "basicAuthPassword": true, "tlsClientKey": false}}`
+
+func TestDataSourceSecureFields(t *testing.T) {
+	client := gapiTestTools(t, 200, `{"id": 1, "uid": "pg", "secureJsonFields": {"password": true, "tlsCACert": false}}`)
+
+	existing, err := client.DataSourceByUID("pg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !existing.HasSecret("password") || existing.HasSecret("tlsCACert") || existing.HasSecret("token") {
+		t.Errorf("unexpected secure fields: %v", existing.SecureJSONFields)
+	}
+
+	desired := &DataSource{
+		UID:               "pg",
+		BasicAuthPassword: "basic",
+		SecureJSONData:    map[string]interface{}{"password": "secret", "tlsCACert": "ca", "tlsClientKey": ""},
+	}
+	if missing := desired.MissingSecrets(existing); !reflect.DeepEqual(missing, []string{"basicAuthPassword", "tlsCACert"}) {
+		t.Errorf("unexpected missing secrets: %v", missing)
+	}
+	if missing := desired.MissingSecrets(nil); !reflect.DeepEqual(missing, []string{"basicAuthPassword", "password", "tlsCACert"}) {
+		t.Errorf("unexpected missing secrets: %v", missing)
+	}
+}
+
+func TestUpdateDataSourceSecrets(t *testing.T) {
+	ds := &DataSource{
+		UID:            "pg",
+		Name:           "PostgreSQL",
+		Password:       "secret",
+		SecureJSONData: map[string]interface{}{"tlsCACert": "ca"},
+	}
+	for name, test := range map[string]struct {
+		opts     DataSourceSecretsUpdate
+		expected map[string]interface{}
+	}{
+		"all secrets": {
+			expected: map[string]interface{}{"password": "secret", "tlsCACert": "ca"},
+		},
+		"keep existing": {
+			opts:     DataSourceSecretsUpdate{KeepExisting: true},
+			expected: map[string]interface{}{"tlsCACert": "ca"},
+		},
+		"reset unlisted": {
+			opts:     DataSourceSecretsUpdate{KeepExisting: true, ResetUnlisted: true},
+			expected: map[string]interface{}{"tlsCACert": "ca", "basicAuthPassword": ""},
+		},
+		"reset": {
+			opts:     DataSourceSecretsUpdate{KeepExisting: true, Reset: []string{"tlsClientCert"}},
+			expected: map[string]interface{}{"tlsCACert": "ca", "tlsClientCert": ""},
+		},
+	} {
+		calls := []mockServerCall{{200, `{"message": "Datasource updated"}`}}
+		if test.opts.KeepExisting || test.opts.ResetUnlisted {
+			calls = append([]mockServerCall{{200, existingSecretsJSON}}, calls...)
+		}
+		client := gapiTestToolsFromCalls(t, calls)
+		requests := recordRequests(t, client)
+
+		if err := client.UpdateDataSourceSecrets(ds, test.opts); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		update := (*requests)[len(*requests)-1]
+		sent := map[string]interface{}{}
+		if err := json.Unmarshal(update.body, &sent); err != nil {
+			t.Fatal(err)
+		}
+		if update.method != "PUT" || update.uri != "/api/datasources/uid/pg" {
+			t.Errorf("%s: unexpected request: %s %s", name, update.method, update.uri)
+		}
+		if _, exists := sent["password"]; exists {
+			t.Errorf("%s: expected the deprecated password not to be sent; got: %s", name, update.body)
+		}
+		if secrets, _ := sent["secureJsonData"].(map[string]interface{}); !reflect.DeepEqual(secrets, test.expected) {
+			t.Errorf("%s: expected secrets %v; got: %v", name, test.expected, secrets)
+		}
+	}
+	if ds.Password != "secret" || len(ds.SecureJSONData) != 1 {
+		t.Errorf("expected the data source not to be modified; got: %+v", ds)
+	}
+
+	client := gapiTestTools(t, 200, existingSecretsJSON)
+	if err := client.UpdateDataSourceSecrets(ds, DataSourceSecretsUpdate{Reset: []string{"password"}}); err == nil {
+		t.Error("expected an error resetting a secret which is set")
+	}
+}
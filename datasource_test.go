@@ -1,6 +1,8 @@
 package gapi
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/gobs/pretty"
@@ -278,6 +280,27 @@ func TestNewAzureDataSource(t *testing.T) {
 	}
 }
 
+func TestDataSourceJSON(t *testing.T) {
+	data, err := json.Marshal(&DataSource{Name: "foo", Type: "prometheus", SecureJSONFields: map[string]bool{"password": true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "withCredentials") {
+		t.Errorf("expected optional fields to be omitted; got: %s", data)
+	}
+	if strings.Contains(string(data), "secureJsonFields") {
+		t.Errorf("expected the read-only secureJsonFields not to be sent; got: %s", data)
+	}
+
+	ds := DataSource{}
+	if err := json.Unmarshal([]byte(`{"name": "foo", "secureJsonFields": {"password": true}}`), &ds); err != nil {
+		t.Fatal(err)
+	}
+	if ds.Name != "foo" || !ds.HasSecret("password") {
+		t.Errorf("unexpected data source: %+v", ds)
+	}
+}
+
 func TestDataSources(t *testing.T) {
 	client := gapiTestTools(t, 200, getDataSourcesJSON)
 
@@ -296,6 +319,19 @@ func TestDataSources(t *testing.T) {
 	}
 }
 
+func TestDataSourceByName(t *testing.T) {
+	client := gapiTestTools(t, 200, `{"id":1,"uid":"abc","name":"foo bar","type":"prometheus"}`)
+
+	datasource, err := client.DataSourceByName("foo bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if datasource.UID != "abc" || datasource.Name != "foo bar" {
+		t.Error("Not correctly parsing returned datasource.")
+	}
+}
+
 func TestDataSourceIDByName(t *testing.T) {
 	client := gapiTestTools(t, 200, getDataSourceJSON)
 
@@ -0,0 +1,100 @@
+package gapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrNotFound is returned when a resource looked up among the results of a list endpoint doesn't exist, rather
+// than being reported by the Grafana API. IsNotFound and errors.Is recognize it as well as 404 APIErrors.
+var ErrNotFound = errors.New("not found")
+
+// APIError is returned when the Grafana API responds with a non-successful status code.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Method and Path identify the request which failed.
+	Method string
+	Path   string
+	// Message, MessageID, Status and TraceID are parsed from Grafana's JSON error body, when present.
+	Message   string
+	MessageID string
+	Status    string
+	TraceID   string
+	// Body is the raw response body.
+	Body []byte
+}
+
+// newAPIError builds an APIError from a response, parsing Grafana's error fields out of the body.
+func newAPIError(method, path string, statusCode int, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: statusCode,
+		Method:     method,
+		Path:       path,
+		Body:       body,
+	}
+
+	fields := struct {
+		Message   string `json:"message"`
+		MessageID string `json:"messageId"`
+		Status    string `json:"status"`
+		TraceID   string `json:"traceID"`
+	}{}
+	if err := json.Unmarshal(body, &fields); err == nil {
+		e.Message = fields.Message
+		e.MessageID = fields.MessageID
+		e.Status = fields.Status
+		e.TraceID = fields.TraceID
+	}
+
+	return e
+}
+
+func (e *APIError) Error() string {
+	if len(e.Body) == 0 && e.Message != "" {
+		return fmt.Sprintf("status: %d, message: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("status: %d, body: %v", e.StatusCode, string(e.Body))
+}
+
+// Is makes errors.Is(err, ErrNotFound) true for APIErrors with a 404 status code.
+func (e *APIError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// IsNotFound reports whether err is an APIError with a 404 status code, or ErrNotFound.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsConflict reports whether err is an APIError with a 409 status code.
+func IsConflict(err error) bool {
+	return hasStatusCode(err, http.StatusConflict)
+}
+
+// IsForbidden reports whether err is an APIError with a 403 status code.
+func IsForbidden(err error) bool {
+	return hasStatusCode(err, http.StatusForbidden)
+}
+
+// IsUnauthorized reports whether err is an APIError with a 401 status code.
+func IsUnauthorized(err error) bool {
+	return hasStatusCode(err, http.StatusUnauthorized)
+}
+
+// IsVersionMismatch reports whether err is an APIError caused by saving a resource
+// based on an outdated version, such as a dashboard which was changed in the meantime.
+func IsVersionMismatch(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusPreconditionFailed || apiErr.Status == "version-mismatch"
+}
+
+func hasStatusCode(err error, code int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == code
+}
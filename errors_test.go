@@ -0,0 +1,68 @@
+package gapi
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+)
+
+func TestAPIError(t *testing.T) {
+	client := gapiTestTools(t, 404, `{"message":"Dashboard not found","messageId":"dashboards.notFound","traceID":"abc123"}`)
+
+	err := client.request("GET", "/api/dashboards/uid/foo", url.Values{}, nil, nil)
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		t.Fatalf("expected *APIError; got: %T", err)
+	}
+
+	if apiErr.StatusCode != 404 || apiErr.Method != "GET" || apiErr.Path != "/api/dashboards/uid/foo" {
+		t.Errorf("unexpected request details: %d %s %s", apiErr.StatusCode, apiErr.Method, apiErr.Path)
+	}
+	if apiErr.Message != "Dashboard not found" {
+		t.Errorf("expected message: %s; got: %s", "Dashboard not found", apiErr.Message)
+	}
+	if apiErr.MessageID != "dashboards.notFound" {
+		t.Errorf("expected message ID: %s; got: %s", "dashboards.notFound", apiErr.MessageID)
+	}
+	if apiErr.TraceID != "abc123" {
+		t.Errorf("expected trace ID: %s; got: %s", "abc123", apiErr.TraceID)
+	}
+}
+
+func TestAPIError_helpers(t *testing.T) {
+	cases := []struct {
+		err             error
+		notFound        bool
+		conflict        bool
+		forbidden       bool
+		versionMismatch bool
+	}{
+		{err: newAPIError("GET", "/", 404, nil), notFound: true},
+		{err: newAPIError("POST", "/", 409, nil), conflict: true},
+		{err: newAPIError("GET", "/", 403, nil), forbidden: true},
+		{err: newAPIError("POST", "/", 412, []byte(`{"status":"version-mismatch"}`)), versionMismatch: true},
+		{err: fmt.Errorf("wrapped: %w", newAPIError("GET", "/", 404, nil)), notFound: true},
+		{err: fmt.Errorf("team 1: %w", ErrNotFound), notFound: true},
+		{err: fmt.Errorf("status: 404")},
+		{err: nil},
+	}
+
+	for _, c := range cases {
+		if IsNotFound(c.err) != c.notFound {
+			t.Errorf("IsNotFound(%v): expected %t", c.err, c.notFound)
+		}
+		if errors.Is(c.err, ErrNotFound) != c.notFound {
+			t.Errorf("errors.Is(%v, ErrNotFound): expected %t", c.err, c.notFound)
+		}
+		if IsConflict(c.err) != c.conflict {
+			t.Errorf("IsConflict(%v): expected %t", c.err, c.conflict)
+		}
+		if IsForbidden(c.err) != c.forbidden {
+			t.Errorf("IsForbidden(%v): expected %t", c.err, c.forbidden)
+		}
+		if IsVersionMismatch(c.err) != c.versionMismatch {
+			t.Errorf("IsVersionMismatch(%v): expected %t", c.err, c.versionMismatch)
+		}
+	}
+}
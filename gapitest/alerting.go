@@ -0,0 +1,487 @@
+package gapitest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+const defaultRuleGroupInterval = 60
+
+func defaultPolicies() gapi.NotificationPolicyTree {
+	return gapi.NotificationPolicyTree{
+		Receiver: "default-email",
+		GroupBy:  []string{"grafana_folder", "alertname"},
+	}
+}
+
+// provenance returns the provenance of resources provisioned through the request. Like in Grafana,
+// they can't be edited in the UI unless the X-Disable-Provenance header is set.
+func provenance(r *request) string {
+	if r.Header.Get("X-Disable-Provenance") != "" {
+		return ""
+	}
+	return "api"
+}
+
+func ruleGroupKey(folderUID, group string) string {
+	return folderUID + "/" + group
+}
+
+func (s *Server) registerAlertingRoutes() {
+	s.handle("GET", "/api/v1/provisioning/contact-points", s.listContactPoints)
+	s.handle("POST", "/api/v1/provisioning/contact-points", s.createContactPoint)
+	s.handle("PUT", "/api/v1/provisioning/contact-points/:uid", s.updateContactPoint)
+	s.handle("DELETE", "/api/v1/provisioning/contact-points/:uid", s.deleteContactPoint)
+
+	s.handle("GET", "/api/v1/provisioning/templates", s.listTemplates)
+	s.handle("GET", "/api/v1/provisioning/templates/:name", s.getTemplate)
+	s.handle("PUT", "/api/v1/provisioning/templates/:name", s.setTemplate)
+	s.handle("DELETE", "/api/v1/provisioning/templates/:name", s.deleteTemplate)
+
+	s.handle("GET", "/api/v1/provisioning/mute-timings", s.listMuteTimings)
+	s.handle("POST", "/api/v1/provisioning/mute-timings", s.createMuteTiming)
+	s.handle("GET", "/api/v1/provisioning/mute-timings/:name", s.getMuteTiming)
+	s.handle("PUT", "/api/v1/provisioning/mute-timings/:name", s.updateMuteTiming)
+	s.handle("DELETE", "/api/v1/provisioning/mute-timings/:name", s.deleteMuteTiming)
+
+	s.handle("GET", "/api/v1/provisioning/policies", s.getPolicies)
+	s.handle("PUT", "/api/v1/provisioning/policies", s.setPolicies)
+	s.handle("DELETE", "/api/v1/provisioning/policies", s.resetPolicies)
+
+	s.handle("GET", "/api/v1/provisioning/alert-rules", s.listAlertRules)
+	s.handle("POST", "/api/v1/provisioning/alert-rules", s.createAlertRule)
+	s.handle("GET", "/api/v1/provisioning/alert-rules/:uid", s.getAlertRule)
+	s.handle("PUT", "/api/v1/provisioning/alert-rules/:uid", s.updateAlertRule)
+	s.handle("DELETE", "/api/v1/provisioning/alert-rules/:uid", s.deleteAlertRule)
+	s.handle("GET", "/api/v1/provisioning/folder/:folderUid/rule-groups/:group", s.getRuleGroup)
+	s.handle("PUT", "/api/v1/provisioning/folder/:folderUid/rule-groups/:group", s.setRuleGroup)
+}
+
+func (s *Server) listContactPoints(r *request) response {
+	name := r.URL.Query().Get("name")
+
+	points := make([]gapi.ContactPoint, 0, len(r.org.contactPoints))
+	for _, p := range r.org.contactPoints {
+		if name == "" || p.Name == name {
+			points = append(points, *p)
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].Name != points[j].Name {
+			return points[i].Name < points[j].Name
+		}
+		return points[i].UID < points[j].UID
+	})
+	return ok(points)
+}
+
+func validateContactPoint(p *gapi.ContactPoint) *response {
+	if p.Name == "" || p.Type == "" {
+		resp := errorResponse(http.StatusBadRequest, "contact point name and type are required")
+		return &resp
+	}
+	return nil
+}
+
+func (s *Server) createContactPoint(r *request) response {
+	p := &gapi.ContactPoint{}
+	if err := r.decode(p); err != nil {
+		return badRequest(err)
+	}
+	if resp := validateContactPoint(p); resp != nil {
+		return *resp
+	}
+	if p.UID == "" {
+		p.UID = s.uid()
+	} else if _, exists := r.org.contactPoints[p.UID]; exists {
+		return errorResponse(http.StatusConflict, "contact point with uid %s already exists", p.UID)
+	}
+
+	p.Provenance = provenance(r)
+	r.org.contactPoints[p.UID] = p
+	return response{code: http.StatusAccepted, body: p}
+}
+
+func (s *Server) updateContactPoint(r *request) response {
+	existing, exists := r.org.contactPoints[r.param("uid")]
+	if !exists {
+		return notFound("contact point")
+	}
+
+	p := &gapi.ContactPoint{}
+	if err := r.decode(p); err != nil {
+		return badRequest(err)
+	}
+	if resp := validateContactPoint(p); resp != nil {
+		return *resp
+	}
+
+	if existing.Name != p.Name {
+		renameReceiver(&r.org.policies.Receiver, r.org.policies.Routes, existing.Name, p.Name)
+	}
+	p.UID = existing.UID
+	p.Provenance = provenance(r)
+	r.org.contactPoints[p.UID] = p
+	return response{code: http.StatusAccepted, body: map[string]interface{}{"message": "contactpoint updated"}}
+}
+
+func renameReceiver(receiver *string, routes []gapi.SpecificPolicy, from, to string) {
+	if *receiver == from {
+		*receiver = to
+	}
+	for i := range routes {
+		renameReceiver(&routes[i].Receiver, routes[i].Routes, from, to)
+	}
+}
+
+func (s *Server) deleteContactPoint(r *request) response {
+	p, exists := r.org.contactPoints[r.param("uid")]
+	if !exists {
+		return notFound("contact point")
+	}
+
+	if receiverInUse(r.org, p.Name, p.UID) {
+		return errorResponse(http.StatusConflict, "contact point '%s' is currently used by a notification policy", p.Name)
+	}
+
+	delete(r.org.contactPoints, p.UID)
+	return response{code: http.StatusNoContent}
+}
+
+// receiverInUse reports whether the last contact point of a receiver, which is identified by its name, is used by the policy tree.
+func receiverInUse(o *org, name, uid string) bool {
+	for _, other := range o.contactPoints {
+		if other.Name == name && other.UID != uid {
+			return false
+		}
+	}
+	return o.policies.Receiver == name || routesUse(o.policies.Routes, func(p gapi.SpecificPolicy) bool { return p.Receiver == name })
+}
+
+func routesUse(routes []gapi.SpecificPolicy, uses func(gapi.SpecificPolicy) bool) bool {
+	for _, route := range routes {
+		if uses(route) || routesUse(route.Routes, uses) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) listTemplates(r *request) response {
+	templates := make([]gapi.AlertingMessageTemplate, 0, len(r.org.templates))
+	for _, t := range r.org.templates {
+		templates = append(templates, *t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return ok(templates)
+}
+
+func (s *Server) getTemplate(r *request) response {
+	t, exists := r.org.templates[r.param("name")]
+	if !exists {
+		return notFound("template")
+	}
+	return ok(t)
+}
+
+func (s *Server) setTemplate(r *request) response {
+	body := gapi.AlertingMessageTemplate{}
+	if err := r.decode(&body); err != nil {
+		return badRequest(err)
+	}
+	if body.Template == "" {
+		return errorResponse(http.StatusBadRequest, "template must not be empty")
+	}
+
+	t := &gapi.AlertingMessageTemplate{Name: r.param("name"), Template: body.Template}
+	r.org.templates[t.Name] = t
+	return response{code: http.StatusAccepted, body: t}
+}
+
+func (s *Server) deleteTemplate(r *request) response {
+	delete(r.org.templates, r.param("name"))
+	return response{code: http.StatusNoContent}
+}
+
+func (s *Server) listMuteTimings(r *request) response {
+	timings := make([]gapi.MuteTiming, 0, len(r.org.muteTimings))
+	for _, mt := range r.org.muteTimings {
+		timings = append(timings, *mt)
+	}
+	sort.Slice(timings, func(i, j int) bool { return timings[i].Name < timings[j].Name })
+	return ok(timings)
+}
+
+func (s *Server) getMuteTiming(r *request) response {
+	mt, exists := r.org.muteTimings[r.param("name")]
+	if !exists {
+		return notFound("mute timing")
+	}
+	return ok(mt)
+}
+
+func (s *Server) createMuteTiming(r *request) response {
+	mt := &gapi.MuteTiming{}
+	if err := r.decode(mt); err != nil {
+		return badRequest(err)
+	}
+	if mt.Name == "" {
+		return errorResponse(http.StatusBadRequest, "mute timing name is required")
+	}
+	if _, exists := r.org.muteTimings[mt.Name]; exists {
+		return errorResponse(http.StatusConflict, "mute timing with name %s already exists", mt.Name)
+	}
+
+	mt.Provenance = provenance(r)
+	r.org.muteTimings[mt.Name] = mt
+	return response{code: http.StatusCreated, body: mt}
+}
+
+func (s *Server) updateMuteTiming(r *request) response {
+	name := r.param("name")
+	if _, exists := r.org.muteTimings[name]; !exists {
+		return notFound("mute timing")
+	}
+
+	mt := &gapi.MuteTiming{}
+	if err := r.decode(mt); err != nil {
+		return badRequest(err)
+	}
+
+	mt.Name = name
+	mt.Provenance = provenance(r)
+	r.org.muteTimings[name] = mt
+	return ok(mt)
+}
+
+func (s *Server) deleteMuteTiming(r *request) response {
+	name := r.param("name")
+	inUse := routesUse(r.org.policies.Routes, func(p gapi.SpecificPolicy) bool {
+		for _, n := range p.MuteTimeIntervals {
+			if n == name {
+				return true
+			}
+		}
+		return false
+	})
+	if inUse {
+		return errorResponse(http.StatusConflict, "mute timing '%s' is currently used by a notification policy", name)
+	}
+
+	delete(r.org.muteTimings, name)
+	return response{code: http.StatusNoContent}
+}
+
+func (s *Server) getPolicies(r *request) response {
+	return ok(r.org.policies)
+}
+
+func (s *Server) setPolicies(r *request) response {
+	tree := gapi.NotificationPolicyTree{}
+	if err := r.decode(&tree); err != nil {
+		return badRequest(err)
+	}
+
+	receivers := map[string]bool{}
+	for _, p := range r.org.contactPoints {
+		receivers[p.Name] = true
+	}
+	if !receivers[tree.Receiver] {
+		return errorResponse(http.StatusBadRequest, "receiver '%s' does not exist", tree.Receiver)
+	}
+	unknown := ""
+	routesUse(tree.Routes, func(p gapi.SpecificPolicy) bool {
+		if p.Receiver != "" && !receivers[p.Receiver] {
+			unknown = p.Receiver
+			return true
+		}
+		for _, name := range p.MuteTimeIntervals {
+			if _, exists := r.org.muteTimings[name]; !exists {
+				unknown = name
+				return true
+			}
+		}
+		return false
+	})
+	if unknown != "" {
+		return errorResponse(http.StatusBadRequest, "receiver or mute timing '%s' does not exist", unknown)
+	}
+
+	tree.Provenance = provenance(r)
+	r.org.policies = tree
+	return response{code: http.StatusAccepted, body: map[string]interface{}{"message": "policies updated"}}
+}
+
+func (s *Server) resetPolicies(r *request) response {
+	r.org.policies = defaultPolicies()
+	return response{code: http.StatusAccepted, body: r.org.policies}
+}
+
+// ruleResponse returns a rule, as returned by the API.
+func ruleResponse(rule *gapi.AlertRule) gapi.AlertRule {
+	view := *rule
+	view.ForDuration = 0
+	return view
+}
+
+func (s *Server) listAlertRules(r *request) response {
+	rules := make([]gapi.AlertRule, 0, len(r.org.alertRules))
+	for _, rule := range r.org.alertRules {
+		rules = append(rules, ruleResponse(rule))
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return ok(rules)
+}
+
+func (s *Server) getAlertRule(r *request) response {
+	rule, exists := r.org.alertRules[r.param("uid")]
+	if !exists {
+		return notFound("alert rule")
+	}
+	return ok(ruleResponse(rule))
+}
+
+// validateRule checks a rule the way Grafana does before storing it.
+func validateRule(o *org, rule *gapi.AlertRule) *response {
+	var resp response
+	switch {
+	case rule.Title == "":
+		resp = errorResponse(http.StatusBadRequest, "invalid alert rule: title is required")
+	case rule.RuleGroup == "":
+		resp = errorResponse(http.StatusBadRequest, "invalid alert rule: rule group is required")
+	case rule.Condition == "" || len(rule.Data) == 0:
+		resp = errorResponse(http.StatusBadRequest, "invalid alert rule: condition and data are required")
+	case o.folders[rule.FolderUID] == nil:
+		resp = errorResponse(http.StatusBadRequest, "invalid alert rule: folder %s does not exist", rule.FolderUID)
+	default:
+		return nil
+	}
+	return &resp
+}
+
+// storeRule creates or replaces a rule, filling in the fields computed by the server.
+func (s *Server) storeRule(r *request, rule *gapi.AlertRule, existing *gapi.AlertRule) {
+	if existing != nil {
+		rule.ID = existing.ID
+		rule.UID = existing.UID
+	} else {
+		rule.ID = s.id("alert_rule")
+		if rule.UID == "" {
+			rule.UID = s.uid()
+		}
+	}
+	if rule.For == "" {
+		rule.For = "0s"
+	}
+	rule.OrgID = r.org.ID
+	rule.Updated = time.Now().UTC()
+	rule.Provenance = provenance(r)
+	r.org.alertRules[rule.UID] = rule
+
+	key := ruleGroupKey(rule.FolderUID, rule.RuleGroup)
+	if _, exists := r.org.ruleGroupIntervals[key]; !exists {
+		r.org.ruleGroupIntervals[key] = defaultRuleGroupInterval
+	}
+}
+
+func (s *Server) createAlertRule(r *request) response {
+	rule := &gapi.AlertRule{}
+	if err := r.decode(rule); err != nil {
+		return badRequest(err)
+	}
+	if resp := validateRule(r.org, rule); resp != nil {
+		return *resp
+	}
+	if _, exists := r.org.alertRules[rule.UID]; rule.UID != "" && exists {
+		return errorResponse(http.StatusConflict, "alert rule with uid %s already exists", rule.UID)
+	}
+
+	s.storeRule(r, rule, nil)
+	return response{code: http.StatusCreated, body: ruleResponse(rule)}
+}
+
+func (s *Server) updateAlertRule(r *request) response {
+	existing, exists := r.org.alertRules[r.param("uid")]
+	if !exists {
+		return notFound("alert rule")
+	}
+
+	rule := &gapi.AlertRule{}
+	if err := r.decode(rule); err != nil {
+		return badRequest(err)
+	}
+	if resp := validateRule(r.org, rule); resp != nil {
+		return *resp
+	}
+
+	s.storeRule(r, rule, existing)
+	return ok(ruleResponse(rule))
+}
+
+func (s *Server) deleteAlertRule(r *request) response {
+	delete(r.org.alertRules, r.param("uid"))
+	return response{code: http.StatusNoContent}
+}
+
+func (s *Server) getRuleGroup(r *request) response {
+	folderUID, name := r.param("folderUid"), r.param("group")
+
+	group := gapi.RuleGroup{Title: name, FolderUID: folderUID, Rules: []gapi.AlertRule{}}
+	for _, rule := range r.org.alertRules {
+		if rule.FolderUID == folderUID && rule.RuleGroup == name {
+			group.Rules = append(group.Rules, ruleResponse(rule))
+		}
+	}
+	if len(group.Rules) == 0 {
+		return notFound("rule group")
+	}
+	sort.Slice(group.Rules, func(i, j int) bool { return group.Rules[i].ID < group.Rules[j].ID })
+
+	group.Interval = r.org.ruleGroupIntervals[ruleGroupKey(folderUID, name)]
+	return ok(group)
+}
+
+// setRuleGroup replaces all the rules of a group: rules are matched on their UID,
+// new ones are created and the ones which are missing are deleted.
+func (s *Server) setRuleGroup(r *request) response {
+	folderUID, name := r.param("folderUid"), r.param("group")
+
+	group := gapi.RuleGroup{}
+	if err := r.decode(&group); err != nil {
+		return badRequest(err)
+	}
+	if group.Interval <= 0 || group.Interval%10 != 0 {
+		return errorResponse(http.StatusBadRequest, "invalid rule group interval %d: must be a positive multiple of 10 seconds", group.Interval)
+	}
+
+	for i := range group.Rules {
+		rule := &group.Rules[i]
+		rule.FolderUID = folderUID
+		rule.RuleGroup = name
+		if resp := validateRule(r.org, rule); resp != nil {
+			return *resp
+		}
+		if existing, exists := r.org.alertRules[rule.UID]; exists && (existing.FolderUID != folderUID || existing.RuleGroup != name) {
+			return errorResponse(http.StatusBadRequest, "alert rule %s belongs to another rule group", rule.UID)
+		}
+	}
+
+	keep := map[string]bool{}
+	for i := range group.Rules {
+		rule := group.Rules[i]
+		s.storeRule(r, &rule, r.org.alertRules[rule.UID])
+		keep[rule.UID] = true
+	}
+	for uid, rule := range r.org.alertRules {
+		if rule.FolderUID == folderUID && rule.RuleGroup == name && !keep[uid] {
+			delete(r.org.alertRules, uid)
+		}
+	}
+	r.org.ruleGroupIntervals[ruleGroupKey(folderUID, name)] = group.Interval
+
+	return ok(map[string]interface{}{"message": fmt.Sprintf("rule group %s updated", name)})
+}
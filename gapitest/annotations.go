@@ -0,0 +1,193 @@
+package gapitest
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+func (s *Server) registerAnnotationRoutes() {
+	s.handle("GET", "/api/annotations", s.listAnnotations)
+	s.handle("POST", "/api/annotations", s.createAnnotation)
+	s.handle("POST", "/api/annotations/graphite", s.createGraphiteAnnotation)
+	s.handle("PUT", "/api/annotations/:id", s.updateAnnotation)
+	s.handle("PATCH", "/api/annotations/:id", s.patchAnnotation)
+	s.handle("DELETE", "/api/annotations/:id", s.deleteAnnotation)
+	s.handle("DELETE", "/api/annotations/region/:id", s.deleteAnnotationRegion)
+}
+
+func (s *Server) listAnnotations(r *request) response {
+	query := r.URL.Query()
+	intParam := func(name string) int64 {
+		v, _ := strconv.ParseInt(query.Get(name), 10, 64)
+		return v
+	}
+	dashboardID, panelID, from, to := intParam("dashboardId"), intParam("panelId"), intParam("from"), intParam("to")
+	dashboardUID, annotationType := query.Get("dashboardUID"), query.Get("type")
+	tags := query["tags"]
+	limit := int(intParam("limit"))
+	if limit <= 0 {
+		limit = 100
+	}
+
+	annotations := []gapi.Annotation{}
+	for _, a := range r.org.annotations {
+		switch {
+		case dashboardID != 0 && a.DashboardID != dashboardID,
+			dashboardUID != "" && a.DashboardUID != dashboardUID,
+			panelID != 0 && a.PanelID != panelID,
+			from != 0 && a.TimeEnd < from && a.Time < from,
+			to != 0 && a.Time > to,
+			annotationType == "alert" && a.AlertID == 0,
+			annotationType == "annotation" && a.AlertID != 0,
+			!hasTags(a.Tags, tags):
+			continue
+		}
+		annotations = append(annotations, *a)
+	}
+
+	sort.Slice(annotations, func(i, j int) bool {
+		if annotations[i].Time != annotations[j].Time {
+			return annotations[i].Time > annotations[j].Time
+		}
+		return annotations[i].ID > annotations[j].ID
+	})
+	if len(annotations) > limit {
+		annotations = annotations[:limit]
+	}
+	return ok(annotations)
+}
+
+func hasTags(tags, wanted []string) bool {
+	for _, w := range wanted {
+		found := false
+		for _, t := range tags {
+			found = found || t == w
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) addAnnotation(r *request, a *gapi.Annotation) response {
+	if a.Text == "" {
+		return errorResponse(http.StatusBadRequest, "Failed to save annotation: text is required")
+	}
+	if a.DashboardUID != "" {
+		d, exists := r.org.dashboards[a.DashboardUID]
+		if !exists {
+			return notFound("Dashboard")
+		}
+		a.DashboardID = d.id
+	}
+	if a.Time == 0 {
+		a.Time = time.Now().UnixNano() / int64(time.Millisecond)
+	}
+	if a.TimeEnd == 0 {
+		a.TimeEnd = a.Time
+	}
+
+	a.ID = s.id("annotation")
+	a.UserID = 1
+	a.UserName = "admin"
+	r.org.annotations[a.ID] = a
+	return ok(map[string]interface{}{"id": a.ID, "message": "Annotation added"})
+}
+
+func (s *Server) createAnnotation(r *request) response {
+	a := &gapi.Annotation{}
+	if err := r.decode(a); err != nil {
+		return badRequest(err)
+	}
+	return s.addAnnotation(r, a)
+}
+
+func (s *Server) createGraphiteAnnotation(r *request) response {
+	ga := gapi.GraphiteAnnotation{}
+	if err := r.decode(&ga); err != nil {
+		return badRequest(err)
+	}
+	if ga.What == "" {
+		return errorResponse(http.StatusBadRequest, "what field should not be empty")
+	}
+
+	text := ga.What
+	if ga.Data != "" {
+		text += "\n" + ga.Data
+	}
+	return s.addAnnotation(r, &gapi.Annotation{Text: text, Time: ga.When * 1000, Tags: ga.Tags})
+}
+
+func (s *Server) updateAnnotation(r *request) response {
+	existing, exists := r.org.annotations[r.intParam("id")]
+	if !exists {
+		return notFound("Annotation")
+	}
+
+	a := &gapi.Annotation{}
+	if err := r.decode(a); err != nil {
+		return badRequest(err)
+	}
+
+	existing.Text = a.Text
+	existing.Time = a.Time
+	existing.TimeEnd = a.TimeEnd
+	existing.Tags = a.Tags
+	return message("Annotation updated")
+}
+
+// patchAnnotation only updates the fields which are set in the request, like Grafana.
+func (s *Server) patchAnnotation(r *request) response {
+	existing, exists := r.org.annotations[r.intParam("id")]
+	if !exists {
+		return notFound("Annotation")
+	}
+
+	a := &gapi.Annotation{}
+	if err := r.decode(a); err != nil {
+		return badRequest(err)
+	}
+
+	if a.Text != "" {
+		existing.Text = a.Text
+	}
+	if a.Time != 0 {
+		existing.Time = a.Time
+	}
+	if a.TimeEnd != 0 {
+		existing.TimeEnd = a.TimeEnd
+	}
+	if a.Tags != nil {
+		existing.Tags = a.Tags
+	}
+	return message("Annotation patched")
+}
+
+func (s *Server) deleteAnnotation(r *request) response {
+	id := r.intParam("id")
+	if _, exists := r.org.annotations[id]; !exists {
+		return notFound("Annotation")
+	}
+	delete(r.org.annotations, id)
+	return message("Annotation deleted")
+}
+
+func (s *Server) deleteAnnotationRegion(r *request) response {
+	id := r.intParam("id")
+	deleted := false
+	for annotationID, a := range r.org.annotations {
+		if a.ID == id || a.RegionID == id {
+			delete(r.org.annotations, annotationID)
+			deleted = true
+		}
+	}
+	if !deleted {
+		return notFound("Annotation")
+	}
+	return message("Annotation region deleted")
+}
@@ -0,0 +1,527 @@
+package gapitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+type dashboard struct {
+	id        int64
+	uid       string
+	folderUID string
+	model     map[string]interface{}
+	version   int64
+	created   time.Time
+	updated   time.Time
+	versions  []*gapi.DashboardVersion
+	// permissions are nil until set, the dashboard then only inheriting the permissions of its folder.
+	permissions []gapi.PermissionItem
+}
+
+func (d *dashboard) title() string {
+	title, _ := d.model["title"].(string)
+	return title
+}
+
+func (d *dashboard) tags() []string {
+	tags := []string{}
+	raw, _ := d.model["tags"].([]interface{})
+	for _, tag := range raw {
+		if s, ok := tag.(string); ok {
+			tags = append(tags, s)
+		}
+	}
+	return tags
+}
+
+func (d *dashboard) findVersion(version int64) *gapi.DashboardVersion {
+	for _, v := range d.versions {
+		if v.Version == version {
+			return v
+		}
+	}
+	return nil
+}
+
+func (d *dashboard) url() string {
+	return fmt.Sprintf("/d/%s/%s", d.uid, slugify(d.title()))
+}
+
+func (o *org) dashboardByID(id int64) *dashboard {
+	for _, d := range o.dashboards {
+		if d.id == id {
+			return d
+		}
+	}
+	return nil
+}
+
+func (o *org) dashboardBySlug(slug string) *dashboard {
+	for _, d := range o.dashboards {
+		if slugify(d.title()) == slug {
+			return d
+		}
+	}
+	return nil
+}
+
+// modelInt returns a numeric field of a dashboard model decoded from JSON.
+func modelInt(model map[string]interface{}, key string) int64 {
+	switch v := model[key].(type) {
+	case float64:
+		return int64(v)
+	case json.Number:
+		n, _ := v.Int64()
+		return n
+	default:
+		return 0
+	}
+}
+
+// copyModel deep copies a dashboard model, so that stored versions aren't changed through shared maps.
+func copyModel(model map[string]interface{}) map[string]interface{} {
+	data, err := json.Marshal(model)
+	if err != nil {
+		return nil
+	}
+	copied := map[string]interface{}{}
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil
+	}
+	return copied
+}
+
+func (s *Server) registerDashboardRoutes() {
+	s.handle("POST", "/api/dashboards/db", s.saveDashboard)
+	s.handle("POST", "/api/dashboards/import", s.importDashboard)
+	s.handle("GET", "/api/dashboards/uid/:uid", s.getDashboard)
+	s.handle("DELETE", "/api/dashboards/uid/:uid", s.deleteDashboard)
+	s.handle("GET", "/api/dashboards/db/:slug", s.getDashboardBySlug)
+	s.handle("DELETE", "/api/dashboards/db/:slug", s.deleteDashboardBySlug)
+	s.handle("GET", "/api/dashboards/uid/:uid/versions", s.listDashboardVersions)
+	s.handle("GET", "/api/dashboards/uid/:uid/versions/:version", s.getDashboardVersion)
+	s.handle("POST", "/api/dashboards/uid/:uid/restore", s.restoreDashboardVersion)
+	s.handle("GET", "/api/search", s.search)
+}
+
+type saveDashboardRequest struct {
+	Dashboard map[string]interface{} `json:"dashboard"`
+	FolderID  int64                  `json:"folderId"`
+	FolderUID string                 `json:"folderUid"`
+	Overwrite bool                   `json:"overwrite"`
+	Message   string                 `json:"message"`
+}
+
+func (s *Server) saveDashboard(r *request) response {
+	body := saveDashboardRequest{}
+	if err := r.decode(&body); err != nil {
+		return badRequest(err)
+	}
+	return s.storeDashboard(r.org, body, 0)
+}
+
+// storeDashboard creates or updates a dashboard, checking versions and titles the way Grafana does.
+// restoredFrom is the version a dashboard is restored from, if any.
+func (s *Server) storeDashboard(o *org, body saveDashboardRequest, restoredFrom int64) response {
+	model := body.Dashboard
+	if model == nil {
+		return errorResponse(http.StatusBadRequest, "dashboard is required")
+	}
+	title, _ := model["title"].(string)
+	if strings.TrimSpace(title) == "" {
+		return errorResponse(http.StatusBadRequest, "Dashboard title cannot be empty")
+	}
+
+	folderUID := body.FolderUID
+	if folderUID == "" && body.FolderID != 0 {
+		f := o.folderByID(body.FolderID)
+		if f == nil {
+			return notFound("folder")
+		}
+		folderUID = f.UID
+	}
+	if _, exists := o.folders[folderUID]; folderUID != "" && !exists {
+		return notFound("folder")
+	}
+
+	uid, _ := model["uid"].(string)
+	var existing *dashboard
+	if uid != "" {
+		existing = o.dashboards[uid]
+	} else if id := modelInt(model, "id"); id != 0 {
+		if existing = o.dashboardByID(id); existing == nil {
+			return notFound("Dashboard")
+		}
+	}
+
+	if existing != nil && !body.Overwrite && modelInt(model, "version") != existing.version {
+		return versionMismatch("dashboard")
+	}
+	for _, other := range o.dashboards {
+		if other != existing && other.folderUID == folderUID && other.title() == title {
+			if !body.Overwrite {
+				return response{
+					code: http.StatusPreconditionFailed,
+					body: map[string]interface{}{
+						"status":  "name-exists",
+						"message": "A dashboard with the same name in the folder already exists",
+					},
+				}
+			}
+			delete(o.dashboards, other.uid)
+		}
+	}
+
+	now := time.Now().UTC()
+	if existing == nil {
+		if uid == "" {
+			uid = s.uid()
+		}
+		existing = &dashboard{id: s.id("dashboard"), uid: uid, created: now}
+		o.dashboards[uid] = existing
+	}
+
+	parent := existing.version
+	existing.version++
+	existing.folderUID = folderUID
+	existing.updated = now
+
+	model = copyModel(model)
+	model["id"] = existing.id
+	model["uid"] = existing.uid
+	model["version"] = existing.version
+	existing.model = model
+
+	existing.versions = append(existing.versions, &gapi.DashboardVersion{
+		ID:            s.id("dashboard_version"),
+		DashboardID:   existing.id,
+		DashboardUID:  existing.uid,
+		ParentVersion: parent,
+		RestoredFrom:  restoredFrom,
+		Version:       existing.version,
+		Created:       now,
+		CreatedBy:     "admin",
+		Message:       body.Message,
+		Data:          copyModel(model),
+	})
+
+	return ok(gapi.DashboardSaveResponse{
+		ID:      existing.id,
+		UID:     existing.uid,
+		Slug:    slugify(title),
+		Status:  "success",
+		Version: existing.version,
+	})
+}
+
+// importDashboard substitutes the inputs of a dashboard and saves it, like Grafana's import API.
+func (s *Server) importDashboard(r *request) response {
+	body := gapi.DashboardImport{}
+	if err := r.decode(&body); err != nil {
+		return badRequest(err)
+	}
+
+	inputs, err := gapi.DashboardInputs(body.Dashboard)
+	if err != nil {
+		return badRequest(err)
+	}
+	for _, input := range inputs {
+		found := false
+		for _, value := range body.Inputs {
+			found = found || value.Name == input.Name
+		}
+		if !found {
+			return errorResponse(http.StatusBadRequest, fmt.Sprintf("Input %s is missing", input.Name))
+		}
+	}
+
+	model, err := gapi.SubstituteDashboardInputs(body.Dashboard, body.Inputs)
+	if err != nil {
+		return badRequest(err)
+	}
+	res := s.storeDashboard(r.org, saveDashboardRequest{
+		Dashboard: model,
+		FolderUID: body.FolderUID,
+		Overwrite: body.Overwrite,
+	}, 0)
+	saved, isSaved := res.body.(gapi.DashboardSaveResponse)
+	if !isSaved {
+		return res
+	}
+
+	d := r.org.dashboards[saved.UID]
+	result := gapi.DashboardImportResponse{
+		UID:         d.uid,
+		Title:       d.title(),
+		Imported:    true,
+		ImportedURI: "db/" + saved.Slug,
+		ImportedURL: d.url(),
+		Slug:        saved.Slug,
+		DashboardID: d.id,
+		FolderUID:   d.folderUID,
+	}
+	if f, exists := r.org.folders[d.folderUID]; exists {
+		result.FolderID = f.ID
+	}
+	return ok(result)
+}
+
+func (s *Server) dashboardResponse(o *org, d *dashboard) response {
+	meta := map[string]interface{}{
+		"isStarred": false,
+		"slug":      slugify(d.title()),
+		"url":       d.url(),
+		"version":   d.version,
+		"created":   d.created,
+		"updated":   d.updated,
+		"createdBy": "admin",
+		"updatedBy": "admin",
+		"folderId":  0,
+		"folderUid": "",
+	}
+	if f, exists := o.folders[d.folderUID]; exists {
+		meta["folderId"] = f.ID
+		meta["folderUid"] = f.UID
+		meta["folderTitle"] = f.Title
+		meta["folderUrl"] = f.URL
+	}
+
+	return ok(map[string]interface{}{
+		"dashboard": d.model,
+		"meta":      meta,
+	})
+}
+
+func (s *Server) getDashboard(r *request) response {
+	d, exists := r.org.dashboards[r.param("uid")]
+	if !exists {
+		return notFound("Dashboard")
+	}
+	return s.dashboardResponse(r.org, d)
+}
+
+func (s *Server) getDashboardBySlug(r *request) response {
+	d := r.org.dashboardBySlug(r.param("slug"))
+	if d == nil {
+		return notFound("Dashboard")
+	}
+	return s.dashboardResponse(r.org, d)
+}
+
+func (s *Server) removeDashboard(o *org, d *dashboard) response {
+	delete(o.dashboards, d.uid)
+	return ok(map[string]interface{}{
+		"id":      d.id,
+		"title":   d.title(),
+		"message": fmt.Sprintf("Dashboard %s deleted", d.title()),
+	})
+}
+
+func (s *Server) deleteDashboard(r *request) response {
+	d, exists := r.org.dashboards[r.param("uid")]
+	if !exists {
+		return notFound("Dashboard")
+	}
+	return s.removeDashboard(r.org, d)
+}
+
+func (s *Server) deleteDashboardBySlug(r *request) response {
+	d := r.org.dashboardBySlug(r.param("slug"))
+	if d == nil {
+		return notFound("Dashboard")
+	}
+	return s.removeDashboard(r.org, d)
+}
+
+// listDashboardVersions lists the versions of a dashboard the newest first, without their data.
+func (s *Server) listDashboardVersions(r *request) response {
+	d, exists := r.org.dashboards[r.param("uid")]
+	if !exists {
+		return notFound("Dashboard")
+	}
+
+	query := r.URL.Query()
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit < 1 {
+		limit = 1000
+	}
+	start, _ := strconv.Atoi(query.Get("start"))
+
+	versions := []gapi.DashboardVersion{}
+	for i := len(d.versions) - 1 - start; i >= 0 && len(versions) < limit; i-- {
+		version := *d.versions[i]
+		version.Data = nil
+		versions = append(versions, version)
+	}
+	return ok(versions)
+}
+
+func (s *Server) getDashboardVersion(r *request) response {
+	d, exists := r.org.dashboards[r.param("uid")]
+	if !exists {
+		return notFound("Dashboard")
+	}
+	version := d.findVersion(r.intParam("version"))
+	if version == nil {
+		return notFound("Dashboard version")
+	}
+	return ok(version)
+}
+
+func (s *Server) restoreDashboardVersion(r *request) response {
+	d, exists := r.org.dashboards[r.param("uid")]
+	if !exists {
+		return notFound("Dashboard")
+	}
+
+	body := struct {
+		Version int64 `json:"version"`
+	}{}
+	if err := r.decode(&body); err != nil {
+		return badRequest(err)
+	}
+	version := d.findVersion(body.Version)
+	if version == nil {
+		return notFound("Dashboard version")
+	}
+
+	model := copyModel(version.Data)
+	model["version"] = d.version
+	return s.storeDashboard(r.org, saveDashboardRequest{
+		Dashboard: model,
+		FolderUID: d.folderUID,
+		Overwrite: true,
+		Message:   fmt.Sprintf("Restored from version %d", version.Version),
+	}, version.Version)
+}
+
+// int64Params parses repeated numeric query parameters, also accepting a JSON list as value.
+func int64Params(values []string) map[int64]bool {
+	ids := map[int64]bool{}
+	for _, v := range values {
+		var list []int64
+		if err := json.Unmarshal([]byte(v), &list); err == nil {
+			for _, id := range list {
+				ids[id] = true
+			}
+			continue
+		}
+		if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+func (s *Server) search(r *request) response {
+	query := r.URL.Query()
+	searchType := query.Get("type")
+	title := strings.ToLower(query.Get("query"))
+	dashboardIDs := int64Params(query["dashboardIds"])
+	folderIDs := int64Params(query["folderIds"])
+	dashboardUIDs := map[string]bool{}
+	for _, uid := range query["dashboardUIDs"] {
+		dashboardUIDs[uid] = true
+	}
+	tags := query["tag"]
+
+	results := []gapi.FolderDashboardSearchResponse{}
+
+	if searchType == "" || searchType == "dash-folder" {
+		for _, f := range r.org.folders {
+			if len(dashboardIDs) > 0 || len(dashboardUIDs) > 0 || len(folderIDs) > 0 || len(tags) > 0 {
+				continue
+			}
+			if title != "" && !strings.Contains(strings.ToLower(f.Title), title) {
+				continue
+			}
+			results = append(results, gapi.FolderDashboardSearchResponse{
+				ID:    uint(f.ID),
+				UID:   f.UID,
+				Title: f.Title,
+				URI:   "db/" + slugify(f.Title),
+				URL:   f.URL,
+				Slug:  slugify(f.Title),
+				Type:  "dash-folder",
+				Tags:  []string{},
+			})
+		}
+	}
+
+	if searchType == "" || searchType == "dash-db" {
+		for _, d := range r.org.dashboards {
+			if !matchesDashboardSearch(r.org, d, title, dashboardIDs, dashboardUIDs, folderIDs, tags) {
+				continue
+			}
+			result := gapi.FolderDashboardSearchResponse{
+				ID:    uint(d.id),
+				UID:   d.uid,
+				Title: d.title(),
+				URI:   "db/" + slugify(d.title()),
+				URL:   d.url(),
+				Slug:  slugify(d.title()),
+				Type:  "dash-db",
+				Tags:  d.tags(),
+			}
+			if f, exists := r.org.folders[d.folderUID]; exists {
+				result.FolderID = uint(f.ID)
+				result.FolderUID = f.UID
+				result.FolderTitle = f.Title
+				result.FolderURL = f.URL
+			}
+			results = append(results, result)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Type != results[j].Type {
+			return results[i].Type == "dash-folder"
+		}
+		return strings.ToLower(results[i].Title) < strings.ToLower(results[j].Title)
+	})
+
+	page, limit := pagination(r, "limit", 1000)
+	paged := []gapi.FolderDashboardSearchResponse{}
+	for i := (page - 1) * limit; i < len(results) && i < page*limit; i++ {
+		paged = append(paged, results[i])
+	}
+	return ok(paged)
+}
+
+func matchesDashboardSearch(o *org, d *dashboard, title string, ids map[int64]bool, uids map[string]bool, folderIDs map[int64]bool, tags []string) bool {
+	if title != "" && !strings.Contains(strings.ToLower(d.title()), title) {
+		return false
+	}
+	if len(ids) > 0 && !ids[d.id] {
+		return false
+	}
+	if len(uids) > 0 && !uids[d.uid] {
+		return false
+	}
+	if len(folderIDs) > 0 {
+		folderID := int64(0)
+		if f, exists := o.folders[d.folderUID]; exists {
+			folderID = f.ID
+		}
+		if !folderIDs[folderID] {
+			return false
+		}
+	}
+	for _, tag := range tags {
+		found := false
+		for _, t := range d.tags() {
+			found = found || t == tag
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
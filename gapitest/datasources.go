@@ -0,0 +1,214 @@
+package gapitest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+type dataSource struct {
+	gapi.DataSource
+	// secureJSONData holds the secrets of the data source, which are never returned by the API.
+	secureJSONData map[string]interface{}
+}
+
+// view returns the data source the way the API returns it: without secrets, but telling which ones are set.
+func (ds *dataSource) view() map[string]interface{} {
+	view := map[string]interface{}{}
+	data, _ := json.Marshal(ds.DataSource)
+	_ = json.Unmarshal(data, &view)
+
+	delete(view, "secureJsonData")
+	delete(view, "password")
+	delete(view, "basicAuthPassword")
+
+	fields := map[string]bool{}
+	for k := range ds.secureJSONData {
+		fields[k] = true
+	}
+	view["secureJsonFields"] = fields
+	return view
+}
+
+// setSecrets merges secrets into the data source's. Secrets which aren't given are left untouched,
+// like in Grafana, and setting a secret to an empty string removes it.
+func (ds *dataSource) setSecrets(secrets map[string]interface{}) {
+	if ds.Password != "" {
+		secrets["password"] = ds.Password
+	}
+	if ds.BasicAuthPassword != "" {
+		secrets["basicAuthPassword"] = ds.BasicAuthPassword
+	}
+	for k, v := range secrets {
+		if v == "" {
+			delete(ds.secureJSONData, k)
+			continue
+		}
+		ds.secureJSONData[k] = v
+	}
+	ds.Password = ""
+	ds.BasicAuthPassword = ""
+	ds.SecureJSONData = nil
+}
+
+func (o *org) dataSourceByUID(uid string) *dataSource {
+	for _, ds := range o.dataSources {
+		if ds.UID == uid {
+			return ds
+		}
+	}
+	return nil
+}
+
+func (o *org) dataSourceByName(name string) *dataSource {
+	for _, ds := range o.dataSources {
+		if ds.Name == name {
+			return ds
+		}
+	}
+	return nil
+}
+
+func (s *Server) registerDataSourceRoutes() {
+	s.handle("GET", "/api/datasources", s.listDataSources)
+	s.handle("POST", "/api/datasources", s.createDataSource)
+	s.handle("GET", "/api/datasources/uid/:uid", s.dataSourceHandler(byUID, s.getDataSource))
+	s.handle("PUT", "/api/datasources/uid/:uid", s.dataSourceHandler(byUID, s.updateDataSource))
+	s.handle("DELETE", "/api/datasources/uid/:uid", s.dataSourceHandler(byUID, s.deleteDataSource))
+	s.handle("GET", "/api/datasources/name/:name", s.dataSourceHandler(byName, s.getDataSource))
+	s.handle("DELETE", "/api/datasources/name/:name", s.dataSourceHandler(byName, s.deleteDataSource))
+	s.handle("GET", "/api/datasources/id/:name", s.dataSourceHandler(byName, s.getDataSourceID))
+	s.handle("GET", "/api/datasources/:id", s.dataSourceHandler(byID, s.getDataSource))
+	s.handle("PUT", "/api/datasources/:id", s.dataSourceHandler(byID, s.updateDataSource))
+	s.handle("DELETE", "/api/datasources/:id", s.dataSourceHandler(byID, s.deleteDataSource))
+}
+
+// dataSourceLookup finds the data source a request refers to.
+type dataSourceLookup func(r *request) *dataSource
+
+func byID(r *request) *dataSource {
+	return r.org.dataSources[r.intParam("id")]
+}
+
+func byUID(r *request) *dataSource {
+	return r.org.dataSourceByUID(r.param("uid"))
+}
+
+func byName(r *request) *dataSource {
+	return r.org.dataSourceByName(r.param("name"))
+}
+
+// dataSourceHandler wraps a handler of a single data source, responding with a 404 if it doesn't exist.
+func (s *Server) dataSourceHandler(lookup dataSourceLookup, h func(r *request, ds *dataSource) response) handler {
+	return func(r *request) response {
+		ds := lookup(r)
+		if ds == nil {
+			return notFound("Data source")
+		}
+		return h(r, ds)
+	}
+}
+
+func (s *Server) listDataSources(r *request) response {
+	dataSources := make([]*dataSource, 0, len(r.org.dataSources))
+	for _, ds := range r.org.dataSources {
+		dataSources = append(dataSources, ds)
+	}
+	sort.Slice(dataSources, func(i, j int) bool { return dataSources[i].Name < dataSources[j].Name })
+
+	views := make([]map[string]interface{}, 0, len(dataSources))
+	for _, ds := range dataSources {
+		views = append(views, ds.view())
+	}
+	return ok(views)
+}
+
+func (s *Server) createDataSource(r *request) response {
+	body := gapi.DataSource{}
+	if err := r.decode(&body); err != nil {
+		return badRequest(err)
+	}
+	if body.Name == "" {
+		return errorResponse(http.StatusBadRequest, "Name is required")
+	}
+	if r.org.dataSourceByName(body.Name) != nil {
+		return errorResponse(http.StatusConflict, "data source with the same name already exists")
+	}
+	if body.UID == "" {
+		body.UID = s.uid()
+	} else if r.org.dataSourceByUID(body.UID) != nil {
+		return errorResponse(http.StatusConflict, "data source with the same uid already exists")
+	}
+	if body.Access == "" {
+		body.Access = "proxy"
+	}
+	if len(r.org.dataSources) == 0 {
+		body.IsDefault = true
+	}
+
+	body.ID = s.id("data_source")
+	body.OrgID = r.org.ID
+	ds := &dataSource{DataSource: body, secureJSONData: map[string]interface{}{}}
+	ds.setSecrets(copySecrets(body.SecureJSONData))
+	r.org.dataSources[ds.ID] = ds
+
+	return ok(map[string]interface{}{
+		"id":         ds.ID,
+		"name":       ds.Name,
+		"message":    "Datasource added",
+		"datasource": ds.view(),
+	})
+}
+
+func copySecrets(secrets map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(secrets))
+	for k, v := range secrets {
+		copied[k] = v
+	}
+	return copied
+}
+
+func (s *Server) getDataSource(r *request, ds *dataSource) response {
+	return ok(ds.view())
+}
+
+func (s *Server) getDataSourceID(r *request, ds *dataSource) response {
+	return ok(map[string]interface{}{"id": ds.ID})
+}
+
+func (s *Server) updateDataSource(r *request, ds *dataSource) response {
+	body := gapi.DataSource{}
+	if err := r.decode(&body); err != nil {
+		return badRequest(err)
+	}
+	if other := r.org.dataSourceByName(body.Name); other != nil && other != ds {
+		return errorResponse(http.StatusConflict, "data source with the same name already exists")
+	}
+	if ds.ReadOnly {
+		return errorResponse(http.StatusForbidden, "Cannot update read-only data source")
+	}
+
+	secrets := copySecrets(body.SecureJSONData)
+	body.ID = ds.ID
+	body.UID = ds.UID
+	body.OrgID = ds.OrgID
+	ds.DataSource = body
+	ds.setSecrets(secrets)
+
+	return ok(map[string]interface{}{
+		"id":         ds.ID,
+		"name":       ds.Name,
+		"message":    "Datasource updated",
+		"datasource": ds.view(),
+	})
+}
+
+func (s *Server) deleteDataSource(r *request, ds *dataSource) response {
+	if ds.ReadOnly {
+		return errorResponse(http.StatusForbidden, "Cannot delete read-only data source")
+	}
+	delete(r.org.dataSources, ds.ID)
+	return ok(map[string]interface{}{"id": ds.ID, "message": "Data source deleted"})
+}
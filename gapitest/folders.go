@@ -0,0 +1,187 @@
+package gapitest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+type folder struct {
+	gapi.Folder
+	Version int64 `json:"version"`
+	// permissions are nil until set, the folder then having the default permissions.
+	permissions []gapi.PermissionItem
+}
+
+func (o *org) folderByID(id int64) *folder {
+	for _, f := range o.folders {
+		if f.ID == id {
+			return f
+		}
+	}
+	return nil
+}
+
+func (o *org) folderByTitle(title string) *folder {
+	for _, f := range o.folders {
+		if f.Title == title {
+			return f
+		}
+	}
+	return nil
+}
+
+func (s *Server) registerFolderRoutes() {
+	s.handle("GET", "/api/folders", s.listFolders)
+	s.handle("POST", "/api/folders", s.createFolder)
+	s.handle("GET", "/api/folders/id/:id", s.getFolderByID)
+	s.handle("GET", "/api/folders/:uid", s.getFolder)
+	s.handle("PUT", "/api/folders/:uid", s.updateFolder)
+	s.handle("DELETE", "/api/folders/:uid", s.deleteFolder)
+}
+
+func (s *Server) listFolders(r *request) response {
+	folders := make([]gapi.Folder, 0, len(r.org.folders))
+	for _, f := range r.org.folders {
+		folders = append(folders, f.Folder)
+	}
+	sort.Slice(folders, func(i, j int) bool { return folders[i].Title < folders[j].Title })
+	return ok(folders)
+}
+
+func (s *Server) createFolder(r *request) response {
+	body := gapi.FolderPayload{}
+	if err := r.decode(&body); err != nil {
+		return badRequest(err)
+	}
+	if body.Title == "" {
+		return errorResponse(http.StatusBadRequest, "folder title cannot be empty")
+	}
+	if body.UID == "" {
+		body.UID = s.uid()
+	}
+	if _, exists := r.org.folders[body.UID]; exists {
+		return errorResponse(http.StatusConflict, "a folder with the same uid already exists")
+	}
+	if r.org.folderByTitle(body.Title) != nil {
+		return errorResponse(http.StatusConflict, "a folder or dashboard in the general folder with the same name already exists")
+	}
+
+	f := &folder{
+		Folder: gapi.Folder{
+			ID:    s.id("dashboard"),
+			UID:   body.UID,
+			Title: body.Title,
+			URL:   fmt.Sprintf("/dashboards/f/%s/%s", body.UID, slugify(body.Title)),
+		},
+		Version: 1,
+	}
+	r.org.folders[f.UID] = f
+	return ok(f)
+}
+
+func (s *Server) getFolderByID(r *request) response {
+	f := r.org.folderByID(r.intParam("id"))
+	if f == nil {
+		return notFound("folder")
+	}
+	return ok(f)
+}
+
+func (s *Server) getFolder(r *request) response {
+	f, exists := r.org.folders[r.param("uid")]
+	if !exists {
+		return notFound("folder")
+	}
+	return ok(f)
+}
+
+func (s *Server) updateFolder(r *request) response {
+	f, exists := r.org.folders[r.param("uid")]
+	if !exists {
+		return notFound("folder")
+	}
+
+	body := struct {
+		gapi.FolderPayload
+		Version int64 `json:"version"`
+	}{}
+	if err := r.decode(&body); err != nil {
+		return badRequest(err)
+	}
+	if !body.Overwrite && body.Version != f.Version {
+		return versionMismatch("folder")
+	}
+	if other := r.org.folderByTitle(body.Title); other != nil && other != f {
+		return errorResponse(http.StatusConflict, "a folder or dashboard in the general folder with the same name already exists")
+	}
+
+	if body.UID != "" && body.UID != f.UID {
+		if _, exists := r.org.folders[body.UID]; exists {
+			return errorResponse(http.StatusConflict, "a folder with the same uid already exists")
+		}
+		delete(r.org.folders, f.UID)
+		for _, d := range r.org.dashboards {
+			if d.folderUID == f.UID {
+				d.folderUID = body.UID
+			}
+		}
+		for _, panel := range r.org.libraryPanels {
+			if panel.FolderUID == f.UID {
+				panel.FolderUID = body.UID
+				panel.Meta.FolderUID = body.UID
+			}
+		}
+		f.UID = body.UID
+		r.org.folders[f.UID] = f
+	}
+	if body.Title != "" {
+		f.Title = body.Title
+	}
+	f.URL = fmt.Sprintf("/dashboards/f/%s/%s", f.UID, slugify(f.Title))
+	f.Version++
+	return ok(f)
+}
+
+// deleteFolder deletes a folder along with its dashboards, alert rules and library panels, like Grafana.
+func (s *Server) deleteFolder(r *request) response {
+	f, exists := r.org.folders[r.param("uid")]
+	if !exists {
+		return notFound("folder")
+	}
+
+	for uid, d := range r.org.dashboards {
+		if d.folderUID == f.UID {
+			delete(r.org.dashboards, uid)
+		}
+	}
+	for uid, rule := range r.org.alertRules {
+		if rule.FolderUID == f.UID {
+			delete(r.org.alertRules, uid)
+		}
+	}
+	for uid, panel := range r.org.libraryPanels {
+		if panel.FolderUID == f.UID {
+			delete(r.org.libraryPanels, uid)
+		}
+	}
+	delete(r.org.folders, f.UID)
+
+	return ok(map[string]interface{}{
+		"id":      f.ID,
+		"title":   f.Title,
+		"message": fmt.Sprintf("Folder %s deleted", f.Title),
+	})
+}
+
+func versionMismatch(kind string) response {
+	return response{
+		code: http.StatusPreconditionFailed,
+		body: map[string]interface{}{
+			"status":  "version-mismatch",
+			"message": fmt.Sprintf("The %s has been changed by someone else", kind),
+		},
+	}
+}
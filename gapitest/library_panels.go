@@ -0,0 +1,229 @@
+package gapitest
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+func (s *Server) registerLibraryPanelRoutes() {
+	s.handle("GET", "/api/library-elements", s.listLibraryPanels)
+	s.handle("POST", "/api/library-elements", s.createLibraryPanel)
+	s.handle("GET", "/api/library-elements/name/:name", s.getLibraryPanelsByName)
+	s.handle("GET", "/api/library-elements/:uid", s.libraryPanelHandler(s.getLibraryPanel))
+	s.handle("PATCH", "/api/library-elements/:uid", s.libraryPanelHandler(s.patchLibraryPanel))
+	s.handle("DELETE", "/api/library-elements/:uid", s.libraryPanelHandler(s.deleteLibraryPanel))
+	s.handle("GET", "/api/library-elements/:uid/connections", s.libraryPanelHandler(s.getLibraryPanelConnections))
+}
+
+// libraryPanelHandler wraps a handler of a single library panel, responding with a 404 if it doesn't exist.
+func (s *Server) libraryPanelHandler(h func(r *request, panel *gapi.LibraryPanel) response) handler {
+	return func(r *request) response {
+		panel, exists := r.org.libraryPanels[r.param("uid")]
+		if !exists {
+			return notFound("library element")
+		}
+		return h(r, panel)
+	}
+}
+
+// result wraps a response body in a result field, like the library elements API does.
+func result(v interface{}) response {
+	return ok(map[string]interface{}{"result": v})
+}
+
+// libraryPanelFolder resolves the folder of a library panel, given by UID or ID.
+func libraryPanelFolder(o *org, panel *gapi.LibraryPanel) (*folder, *response) {
+	if panel.FolderUID != "" {
+		f, exists := o.folders[panel.FolderUID]
+		if !exists {
+			resp := notFound("folder")
+			return nil, &resp
+		}
+		return f, nil
+	}
+	if panel.Folder != 0 {
+		f := o.folderByID(panel.Folder)
+		if f == nil {
+			resp := notFound("folder")
+			return nil, &resp
+		}
+		return f, nil
+	}
+	return nil, nil
+}
+
+// libraryPanelConnections returns the dashboards using a library panel.
+func (o *org) libraryPanelConnections(uid string) []*dashboard {
+	var connected []*dashboard
+	for _, d := range o.dashboards {
+		model, err := gapi.NewDashboardModel(d.model)
+		if err != nil {
+			continue
+		}
+		for _, panel := range model.AllPanels() {
+			if panel.LibraryPanel != nil && panel.LibraryPanel.UID == uid {
+				connected = append(connected, d)
+				break
+			}
+		}
+	}
+	sort.Slice(connected, func(i, j int) bool { return connected[i].id < connected[j].id })
+	return connected
+}
+
+func (s *Server) storeLibraryPanel(o *org, panel *gapi.LibraryPanel, f *folder) {
+	panel.Folder = 0
+	panel.FolderUID = ""
+	panel.Meta.FolderUID = ""
+	panel.Meta.FolderName = "General"
+	if f != nil {
+		panel.Folder = f.ID
+		panel.FolderUID = f.UID
+		panel.Meta.FolderUID = f.UID
+		panel.Meta.FolderName = f.Title
+	}
+	panel.Type, _ = panel.Model["type"].(string)
+	panel.Meta.ConnectedDashboards = int64(len(o.libraryPanelConnections(panel.UID)))
+	panel.Meta.Updated = time.Now().UTC()
+	panel.Meta.UpdatedBy = gapi.LibraryPanelMetaUser{ID: 1, Name: "admin"}
+}
+
+func (s *Server) listLibraryPanels(r *request) response {
+	panels := make([]*gapi.LibraryPanel, 0, len(r.org.libraryPanels))
+	for _, panel := range r.org.libraryPanels {
+		panels = append(panels, panel)
+	}
+	sort.Slice(panels, func(i, j int) bool { return panels[i].Name < panels[j].Name })
+
+	page, perPage := pagination(r, "perPage", 100)
+	elements := []gapi.LibraryPanel{}
+	for i := (page - 1) * perPage; i < len(panels) && i < page*perPage; i++ {
+		elements = append(elements, *panels[i])
+	}
+
+	return result(gapi.LibraryPanelGetAllResponse{
+		TotalCount: int64(len(panels)),
+		Page:       int64(page),
+		PerPage:    int64(perPage),
+		Elements:   elements,
+	})
+}
+
+func (s *Server) createLibraryPanel(r *request) response {
+	panel := &gapi.LibraryPanel{}
+	if err := r.decode(panel); err != nil {
+		return badRequest(err)
+	}
+	if panel.Name == "" {
+		return errorResponse(http.StatusBadRequest, "library element name is required")
+	}
+	f, resp := libraryPanelFolder(r.org, panel)
+	if resp != nil {
+		return *resp
+	}
+	if panel.UID == "" {
+		panel.UID = s.uid()
+	} else if _, exists := r.org.libraryPanels[panel.UID]; exists {
+		return errorResponse(http.StatusBadRequest, "library element with that name or UID already exists")
+	}
+	for _, other := range r.org.libraryPanels {
+		if other.Name == panel.Name && other.FolderUID == folderUID(f) {
+			return errorResponse(http.StatusBadRequest, "library element with that name or UID already exists")
+		}
+	}
+
+	panel.ID = s.id("library_panel")
+	panel.OrgID = r.org.ID
+	panel.Kind = 1
+	panel.Version = 1
+	panel.Meta.Created = time.Now().UTC()
+	panel.Meta.CreatedBy = gapi.LibraryPanelMetaUser{ID: 1, Name: "admin"}
+	s.storeLibraryPanel(r.org, panel, f)
+	r.org.libraryPanels[panel.UID] = panel
+
+	return result(panel)
+}
+
+func folderUID(f *folder) string {
+	if f == nil {
+		return ""
+	}
+	return f.UID
+}
+
+func (s *Server) getLibraryPanelsByName(r *request) response {
+	panels := []*gapi.LibraryPanel{}
+	for _, panel := range r.org.libraryPanels {
+		if panel.Name == r.param("name") {
+			panels = append(panels, panel)
+		}
+	}
+	if len(panels) == 0 {
+		return notFound("library element")
+	}
+	sort.Slice(panels, func(i, j int) bool { return panels[i].ID < panels[j].ID })
+	return result(panels)
+}
+
+func (s *Server) getLibraryPanel(r *request, panel *gapi.LibraryPanel) response {
+	panel.Meta.ConnectedDashboards = int64(len(r.org.libraryPanelConnections(panel.UID)))
+	return result(panel)
+}
+
+// patchLibraryPanel updates a library panel, checking its version like Grafana.
+func (s *Server) patchLibraryPanel(r *request, panel *gapi.LibraryPanel) response {
+	body := &gapi.LibraryPanel{}
+	if err := r.decode(body); err != nil {
+		return badRequest(err)
+	}
+	if body.Version != panel.Version {
+		return errorResponse(http.StatusPreconditionFailed, "the library element has been changed by someone else")
+	}
+
+	f := r.org.folders[panel.FolderUID]
+	if body.FolderUID != "" || body.Folder != 0 {
+		var resp *response
+		if f, resp = libraryPanelFolder(r.org, body); resp != nil {
+			return *resp
+		}
+	}
+	if body.Name != "" {
+		panel.Name = body.Name
+	}
+	if body.Model != nil {
+		panel.Model = body.Model
+	}
+	if body.Description != "" {
+		panel.Description = body.Description
+	}
+	panel.Version++
+	s.storeLibraryPanel(r.org, panel, f)
+
+	return result(panel)
+}
+
+func (s *Server) deleteLibraryPanel(r *request, panel *gapi.LibraryPanel) response {
+	if len(r.org.libraryPanelConnections(panel.UID)) > 0 {
+		return errorResponse(http.StatusForbidden, "the library element has connections")
+	}
+	delete(r.org.libraryPanels, panel.UID)
+	return ok(gapi.LibraryPanelDeleteResponse{Message: "Library element deleted", ID: panel.ID})
+}
+
+func (s *Server) getLibraryPanelConnections(r *request, panel *gapi.LibraryPanel) response {
+	connections := []gapi.LibraryPanelConnection{}
+	for _, d := range r.org.libraryPanelConnections(panel.UID) {
+		connections = append(connections, gapi.LibraryPanelConnection{
+			ID:          s.id("library_panel_connection"),
+			Kind:        1,
+			PanelID:     panel.ID,
+			DashboardID: d.id,
+			Created:     d.created,
+			CreatedBy:   gapi.LibraryPanelMetaUser{ID: 1, Name: "admin"},
+		})
+	}
+	return result(connections)
+}
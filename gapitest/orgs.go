@@ -0,0 +1,238 @@
+package gapitest
+
+import (
+	"net/http"
+	"sort"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+// org holds the resources of an organization.
+type org struct {
+	gapi.Org
+
+	// members maps user IDs to their role in the org.
+	members map[int64]string
+
+	folders       map[string]*folder
+	dashboards    map[string]*dashboard
+	dataSources   map[int64]*dataSource
+	teams         map[int64]*team
+	annotations   map[int64]*gapi.Annotation
+	contactPoints map[string]*gapi.ContactPoint
+	templates     map[string]*gapi.AlertingMessageTemplate
+	muteTimings   map[string]*gapi.MuteTiming
+	policies      gapi.NotificationPolicyTree
+	alertRules    map[string]*gapi.AlertRule
+	libraryPanels map[string]*gapi.LibraryPanel
+	playlists     map[string]*gapi.Playlist
+	// ruleGroupIntervals maps rule group keys, as returned by ruleGroupKey, to their evaluation interval in seconds.
+	ruleGroupIntervals map[string]int64
+}
+
+func (s *Server) newOrg(name string) *org {
+	o := &org{
+		Org:                gapi.Org{ID: s.id("org"), Name: name},
+		members:            map[int64]string{},
+		folders:            map[string]*folder{},
+		dashboards:         map[string]*dashboard{},
+		dataSources:        map[int64]*dataSource{},
+		teams:              map[int64]*team{},
+		annotations:        map[int64]*gapi.Annotation{},
+		contactPoints:      map[string]*gapi.ContactPoint{},
+		templates:          map[string]*gapi.AlertingMessageTemplate{},
+		muteTimings:        map[string]*gapi.MuteTiming{},
+		policies:           defaultPolicies(),
+		alertRules:         map[string]*gapi.AlertRule{},
+		ruleGroupIntervals: map[string]int64{},
+		libraryPanels:      map[string]*gapi.LibraryPanel{},
+		playlists:          map[string]*gapi.Playlist{},
+	}
+
+	// Like Grafana, every org starts with a default email contact point.
+	cp := &gapi.ContactPoint{
+		UID:      s.uid(),
+		Name:     "default-email",
+		Type:     "email",
+		Settings: map[string]interface{}{"addresses": "<example@email.com>"},
+	}
+	o.contactPoints[cp.UID] = cp
+
+	s.orgs[o.ID] = o
+	return o
+}
+
+func (s *Server) orgByName(name string) *org {
+	for _, o := range s.orgs {
+		if o.Name == name {
+			return o
+		}
+	}
+	return nil
+}
+
+func (s *Server) registerOrgRoutes() {
+	s.handle("GET", "/api/orgs", s.listOrgs)
+	s.handle("POST", "/api/orgs", s.createOrg)
+	s.handle("GET", "/api/orgs/name/:name", s.getOrgByName)
+	s.handle("GET", "/api/orgs/:id", s.getOrg)
+	s.handle("PUT", "/api/orgs/:id", s.updateOrg)
+	s.handle("DELETE", "/api/orgs/:id", s.deleteOrg)
+	s.handle("GET", "/api/org/users", s.listCurrentOrgUsers)
+	s.handle("GET", "/api/orgs/:id/users", s.listOrgUsers)
+	s.handle("POST", "/api/orgs/:id/users", s.addOrgUser)
+	s.handle("PATCH", "/api/orgs/:id/users/:userId", s.updateOrgUser)
+	s.handle("DELETE", "/api/orgs/:id/users/:userId", s.removeOrgUser)
+}
+
+func (s *Server) listOrgs(r *request) response {
+	orgs := make([]gapi.Org, 0, len(s.orgs))
+	for _, o := range s.orgs {
+		orgs = append(orgs, o.Org)
+	}
+	sort.Slice(orgs, func(i, j int) bool { return orgs[i].ID < orgs[j].ID })
+	return ok(orgs)
+}
+
+func (s *Server) createOrg(r *request) response {
+	body := struct {
+		Name string `json:"name"`
+	}{}
+	if err := r.decode(&body); err != nil {
+		return badRequest(err)
+	}
+	if s.orgByName(body.Name) != nil {
+		return errorResponse(http.StatusConflict, "Organization name taken")
+	}
+
+	o := s.newOrg(body.Name)
+	return ok(map[string]interface{}{"orgId": o.ID, "message": "Organization created"})
+}
+
+func (s *Server) getOrgByName(r *request) response {
+	o := s.orgByName(r.param("name"))
+	if o == nil {
+		return notFound("Organization")
+	}
+	return ok(o.Org)
+}
+
+func (s *Server) getOrg(r *request) response {
+	o, exists := s.orgs[r.intParam("id")]
+	if !exists {
+		return notFound("Organization")
+	}
+	return ok(o.Org)
+}
+
+func (s *Server) updateOrg(r *request) response {
+	o, exists := s.orgs[r.intParam("id")]
+	if !exists {
+		return notFound("Organization")
+	}
+
+	body := struct {
+		Name string `json:"name"`
+	}{}
+	if err := r.decode(&body); err != nil {
+		return badRequest(err)
+	}
+	if other := s.orgByName(body.Name); other != nil && other != o {
+		return errorResponse(http.StatusConflict, "Organization name taken")
+	}
+
+	o.Name = body.Name
+	return message("Organization updated")
+}
+
+func (s *Server) deleteOrg(r *request) response {
+	id := r.intParam("id")
+	if _, exists := s.orgs[id]; !exists {
+		return notFound("Organization")
+	}
+	delete(s.orgs, id)
+	return message("Organization deleted")
+}
+
+func (s *Server) orgUsers(o *org) []gapi.OrgUser {
+	users := make([]gapi.OrgUser, 0, len(o.members))
+	for id, role := range o.members {
+		u := s.users[id]
+		users = append(users, gapi.OrgUser{OrgID: o.ID, UserID: id, Email: u.Email, Login: u.Login, Role: role})
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].UserID < users[j].UserID })
+	return users
+}
+
+func (s *Server) listCurrentOrgUsers(r *request) response {
+	return ok(s.orgUsers(r.org))
+}
+
+func (s *Server) listOrgUsers(r *request) response {
+	o, exists := s.orgs[r.intParam("id")]
+	if !exists {
+		return notFound("Organization")
+	}
+	return ok(s.orgUsers(o))
+}
+
+func (s *Server) addOrgUser(r *request) response {
+	o, exists := s.orgs[r.intParam("id")]
+	if !exists {
+		return notFound("Organization")
+	}
+
+	body := struct {
+		LoginOrEmail string `json:"loginOrEmail"`
+		Role         string `json:"role"`
+	}{}
+	if err := r.decode(&body); err != nil {
+		return badRequest(err)
+	}
+
+	u := s.userByLoginOrEmail(body.LoginOrEmail)
+	if u == nil {
+		return notFound("User")
+	}
+	if _, member := o.members[u.ID]; member {
+		return errorResponse(http.StatusConflict, "User is already member of this organization")
+	}
+
+	o.members[u.ID] = body.Role
+	return ok(map[string]interface{}{"message": "User added to organization", "userId": u.ID})
+}
+
+func (s *Server) updateOrgUser(r *request) response {
+	o, exists := s.orgs[r.intParam("id")]
+	if !exists {
+		return notFound("Organization")
+	}
+	userID := r.intParam("userId")
+	if _, member := o.members[userID]; !member {
+		return notFound("User")
+	}
+
+	body := struct {
+		Role string `json:"role"`
+	}{}
+	if err := r.decode(&body); err != nil {
+		return badRequest(err)
+	}
+
+	o.members[userID] = body.Role
+	return message("Organization user updated")
+}
+
+func (s *Server) removeOrgUser(r *request) response {
+	o, exists := s.orgs[r.intParam("id")]
+	if !exists {
+		return notFound("Organization")
+	}
+	userID := r.intParam("userId")
+	if _, member := o.members[userID]; !member {
+		return notFound("User")
+	}
+
+	delete(o.members, userID)
+	return message("User removed from organization")
+}
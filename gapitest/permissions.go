@@ -0,0 +1,158 @@
+package gapitest
+
+import (
+	"net/http"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+// defaultFolderPermissions are the permissions of folders whose permissions weren't set, like in Grafana.
+var defaultFolderPermissions = []gapi.PermissionItem{
+	{Role: "Viewer", Permission: 1},
+	{Role: "Editor", Permission: 2},
+}
+
+var permissionNames = map[int64]string{1: "View", 2: "Edit", 4: "Admin"}
+
+func (s *Server) registerPermissionRoutes() {
+	s.handle("GET", "/api/folders/:uid/permissions", s.folderHandler(s.getFolderPermissions))
+	s.handle("POST", "/api/folders/:uid/permissions", s.folderHandler(s.updateFolderPermissions))
+	s.handle("GET", "/api/dashboards/uid/:uid/permissions", s.dashboardHandler(dashboardByUID, s.getDashboardPermissions))
+	s.handle("POST", "/api/dashboards/uid/:uid/permissions", s.dashboardHandler(dashboardByUID, s.updateDashboardPermissions))
+	s.handle("GET", "/api/dashboards/id/:id/permissions", s.dashboardHandler(dashboardByID, s.getDashboardPermissions))
+	s.handle("POST", "/api/dashboards/id/:id/permissions", s.dashboardHandler(dashboardByID, s.updateDashboardPermissions))
+}
+
+// folderHandler wraps a handler of a single folder, responding with a 404 if it doesn't exist.
+func (s *Server) folderHandler(h func(r *request, f *folder) response) handler {
+	return func(r *request) response {
+		f, exists := r.org.folders[r.param("uid")]
+		if !exists {
+			return notFound("folder")
+		}
+		return h(r, f)
+	}
+}
+
+// dashboardLookup finds the dashboard a request refers to.
+type dashboardLookup func(r *request) *dashboard
+
+func dashboardByID(r *request) *dashboard {
+	return r.org.dashboardByID(r.intParam("id"))
+}
+
+func dashboardByUID(r *request) *dashboard {
+	return r.org.dashboards[r.param("uid")]
+}
+
+// dashboardHandler wraps a handler of a single dashboard, responding with a 404 if it doesn't exist.
+func (s *Server) dashboardHandler(lookup dashboardLookup, h func(r *request, d *dashboard) response) handler {
+	return func(r *request) response {
+		d := lookup(r)
+		if d == nil {
+			return notFound("Dashboard")
+		}
+		return h(r, d)
+	}
+}
+
+func (f *folder) effectivePermissions() []gapi.PermissionItem {
+	if f.permissions == nil {
+		return defaultFolderPermissions
+	}
+	return f.permissions
+}
+
+func (s *Server) getFolderPermissions(r *request, f *folder) response {
+	permissions := []gapi.FolderPermission{}
+	for _, item := range f.effectivePermissions() {
+		permissions = append(permissions, gapi.FolderPermission{
+			FolderUID:      f.UID,
+			FolderID:       f.ID,
+			UserID:         item.UserID,
+			TeamID:         item.TeamID,
+			Role:           item.Role,
+			IsFolder:       true,
+			Permission:     item.Permission,
+			PermissionName: permissionNames[item.Permission],
+		})
+	}
+	return ok(permissions)
+}
+
+func (s *Server) updateFolderPermissions(r *request, f *folder) response {
+	items, resp := s.decodePermissions(r)
+	if resp != nil {
+		return *resp
+	}
+	f.permissions = items
+	return message("Folder permissions updated")
+}
+
+// getDashboardPermissions lists the permissions of a dashboard, including the ones it inherits from its folder.
+func (s *Server) getDashboardPermissions(r *request, d *dashboard) response {
+	permissions := []gapi.DashboardPermission{}
+	add := func(items []gapi.PermissionItem, inherited bool) {
+		for _, item := range items {
+			permissions = append(permissions, gapi.DashboardPermission{
+				DashboardID:    d.id,
+				DashboardUID:   d.uid,
+				UserID:         item.UserID,
+				TeamID:         item.TeamID,
+				Role:           item.Role,
+				Inherited:      inherited,
+				Permission:     item.Permission,
+				PermissionName: permissionNames[item.Permission],
+			})
+		}
+	}
+	if f, exists := r.org.folders[d.folderUID]; exists {
+		add(f.effectivePermissions(), true)
+	} else {
+		add(defaultFolderPermissions, true)
+	}
+	add(d.permissions, false)
+	return ok(permissions)
+}
+
+func (s *Server) updateDashboardPermissions(r *request, d *dashboard) response {
+	items, resp := s.decodePermissions(r)
+	if resp != nil {
+		return *resp
+	}
+	d.permissions = items
+	return message("Dashboard permissions updated")
+}
+
+// decodePermissions decodes and validates permission items: each must grant a valid permission
+// to a role, or to a team or user of the org.
+func (s *Server) decodePermissions(r *request) ([]gapi.PermissionItem, *response) {
+	body := gapi.PermissionItems{}
+	if err := r.decode(&body); err != nil {
+		resp := badRequest(err)
+		return nil, &resp
+	}
+
+	items := []gapi.PermissionItem{}
+	for _, item := range body.Items {
+		if item == nil {
+			continue
+		}
+		var resp response
+		switch {
+		case permissionNames[item.Permission] == "":
+			resp = errorResponse(http.StatusBadRequest, "invalid permission %d", item.Permission)
+		case (item.Role != "") == (item.TeamID != 0 || item.UserID != 0) || (item.TeamID != 0 && item.UserID != 0):
+			resp = errorResponse(http.StatusBadRequest, "permission items must have exactly one of role, teamId and userId")
+		case item.TeamID != 0 && r.org.teams[item.TeamID] == nil:
+			resp = errorResponse(http.StatusBadRequest, "team %d not found", item.TeamID)
+		case item.UserID != 0 && r.org.members[item.UserID] == "":
+			resp = errorResponse(http.StatusBadRequest, "user %d not found", item.UserID)
+		default:
+			items = append(items, *item)
+			continue
+		}
+		return nil, &resp
+	}
+	return items, nil
+}
@@ -0,0 +1,90 @@
+package gapitest
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+func (s *Server) registerPlaylistRoutes() {
+	s.handle("GET", "/api/playlists", s.listPlaylists)
+	s.handle("POST", "/api/playlists", s.createPlaylist)
+	s.handle("GET", "/api/playlists/:uid", s.playlistHandler(s.getPlaylist))
+	s.handle("PUT", "/api/playlists/:uid", s.playlistHandler(s.updatePlaylist))
+	s.handle("DELETE", "/api/playlists/:uid", s.playlistHandler(s.deletePlaylist))
+}
+
+// playlistHandler wraps a handler of a single playlist, responding with a 404 if it doesn't exist.
+// Like Grafana 9+, playlists are identified by UID, but IDs are accepted as well.
+func (s *Server) playlistHandler(h func(r *request, p *gapi.Playlist) response) handler {
+	return func(r *request) response {
+		p, exists := r.org.playlists[r.param("uid")]
+		if !exists {
+			id, _ := strconv.Atoi(r.param("uid"))
+			for _, other := range r.org.playlists {
+				if other.ID == id {
+					p, exists = other, true
+				}
+			}
+		}
+		if !exists {
+			return notFound("playlist")
+		}
+		return h(r, p)
+	}
+}
+
+// listPlaylists lists the playlists without their items, like Grafana.
+func (s *Server) listPlaylists(r *request) response {
+	query := strings.ToLower(r.URL.Query().Get("query"))
+	playlists := []gapi.Playlist{}
+	for _, p := range r.org.playlists {
+		if query == "" || strings.Contains(strings.ToLower(p.Name), query) {
+			playlists = append(playlists, gapi.Playlist{ID: p.ID, UID: p.UID, Name: p.Name, Interval: p.Interval})
+		}
+	}
+	sort.Slice(playlists, func(i, j int) bool { return playlists[i].Name < playlists[j].Name })
+	return ok(playlists)
+}
+
+func (s *Server) createPlaylist(r *request) response {
+	p := &gapi.Playlist{}
+	if err := r.decode(p); err != nil {
+		return badRequest(err)
+	}
+	if p.Name == "" {
+		return errorResponse(http.StatusBadRequest, "playlist name is required")
+	}
+	if p.UID == "" {
+		p.UID = s.uid()
+	} else if _, exists := r.org.playlists[p.UID]; exists {
+		return errorResponse(http.StatusConflict, "a playlist with the same uid already exists")
+	}
+
+	p.ID = int(s.id("playlist"))
+	r.org.playlists[p.UID] = p
+	return ok(gapi.Playlist{ID: p.ID, UID: p.UID, Name: p.Name, Interval: p.Interval})
+}
+
+func (s *Server) getPlaylist(r *request, p *gapi.Playlist) response {
+	return ok(p)
+}
+
+func (s *Server) updatePlaylist(r *request, p *gapi.Playlist) response {
+	body := gapi.Playlist{}
+	if err := r.decode(&body); err != nil {
+		return badRequest(err)
+	}
+	p.Name = body.Name
+	p.Interval = body.Interval
+	p.Items = body.Items
+	return ok(p)
+}
+
+func (s *Server) deletePlaylist(r *request, p *gapi.Playlist) response {
+	delete(r.org.playlists, p.UID)
+	return ok(map[string]interface{}{})
+}
@@ -0,0 +1,63 @@
+package gapitest
+
+import (
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+// corePlugins are the plugins the server reports as installed: a subset of Grafana's core plugins.
+var corePlugins = []gapi.InstalledPlugin{
+	{ID: "alertmanager", Name: "Alertmanager", Type: "datasource"},
+	{ID: "elasticsearch", Name: "Elasticsearch", Type: "datasource"},
+	{ID: "grafana-testdata-datasource", Name: "TestData", Type: "datasource"},
+	{ID: "graphite", Name: "Graphite", Type: "datasource"},
+	{ID: "influxdb", Name: "InfluxDB", Type: "datasource"},
+	{ID: "loki", Name: "Loki", Type: "datasource"},
+	{ID: "mysql", Name: "MySQL", Type: "datasource"},
+	{ID: "postgres", Name: "PostgreSQL", Type: "datasource"},
+	{ID: "prometheus", Name: "Prometheus", Type: "datasource"},
+	{ID: "tempo", Name: "Tempo", Type: "datasource"},
+	{ID: "barchart", Name: "Bar chart", Type: "panel"},
+	{ID: "bargauge", Name: "Bar gauge", Type: "panel"},
+	{ID: "gauge", Name: "Gauge", Type: "panel"},
+	{ID: "graph", Name: "Graph (old)", Type: "panel"},
+	{ID: "logs", Name: "Logs", Type: "panel"},
+	{ID: "row", Name: "Row", Type: "panel"},
+	{ID: "stat", Name: "Stat", Type: "panel"},
+	{ID: "table", Name: "Table", Type: "panel"},
+	{ID: "text", Name: "Text", Type: "panel"},
+	{ID: "timeseries", Name: "Time series", Type: "panel"},
+}
+
+// installed returns a core plugin the way the API returns it.
+func installed(plugin gapi.InstalledPlugin) gapi.InstalledPlugin {
+	plugin.Enabled = true
+	plugin.Info.Version = GrafanaVersion
+	return plugin
+}
+
+func (s *Server) registerPluginRoutes() {
+	s.handle("GET", "/api/plugins", s.listPlugins)
+	s.handle("GET", "/api/plugins/:id/settings", s.getPlugin)
+	s.handle("GET", "/api/health", s.health)
+}
+
+func (s *Server) health(r *request) response {
+	return ok(gapi.HealthResponse{Commit: "gapitest", Database: "ok", Version: GrafanaVersion})
+}
+
+func (s *Server) listPlugins(r *request) response {
+	plugins := make([]gapi.InstalledPlugin, 0, len(corePlugins))
+	for _, plugin := range corePlugins {
+		plugins = append(plugins, installed(plugin))
+	}
+	return ok(plugins)
+}
+
+func (s *Server) getPlugin(r *request) response {
+	for _, plugin := range corePlugins {
+		if plugin.ID == r.param("id") {
+			return ok(installed(plugin))
+		}
+	}
+	return notFound("Plugin")
+}
@@ -0,0 +1,239 @@
+// Package gapitest provides an in-memory fake Grafana server, to test code built on the Grafana API client
+// without a real Grafana instance.
+//
+// The fake is stateful: resources created through the API can be read, updated and deleted again, and
+// it mimics Grafana's behaviour for IDs, UIDs, dashboard versions, conflicts and missing resources.
+// It implements the endpoints used by the client for folders, dashboards and their permissions, data sources,
+// users, teams, orgs, annotations, alerting provisioning, library panels, playlists and plugins.
+// Resources are scoped to the org selected with the X-Grafana-Org-Id header, org 1 being the default.
+//
+//	server := gapitest.NewServer()
+//	defer server.Close()
+//
+//	client := server.Client()
+//	folder, err := client.NewFolder("My folder")
+package gapitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+// GrafanaVersion is the version of Grafana the server reports.
+const GrafanaVersion = "10.2.0"
+
+// Server is an in-memory fake Grafana server.
+type Server struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	routes []route
+	rand   *rand.Rand
+	nextID map[string]int64
+
+	orgs  map[int64]*org
+	users map[int64]*user
+}
+
+// NewServer starts a fake Grafana server with a single "Main Org." org and an admin user.
+// The caller should call Close when finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{
+		rand:   rand.New(rand.NewSource(1)), // nolint:gosec
+		nextID: map[string]int64{},
+		orgs:   map[int64]*org{},
+		users:  map[int64]*user{},
+	}
+
+	s.newOrg("Main Org.")
+	admin := s.newUser(gapi.User{Login: "admin", Email: "admin@localhost", Name: "admin", IsAdmin: true})
+	s.orgs[1].members[admin.ID] = "Admin"
+
+	s.registerFolderRoutes()
+	s.registerDashboardRoutes()
+	s.registerDataSourceRoutes()
+	s.registerUserRoutes()
+	s.registerTeamRoutes()
+	s.registerOrgRoutes()
+	s.registerAlertingRoutes()
+	s.registerAnnotationRoutes()
+	s.registerPluginRoutes()
+	s.registerLibraryPanelRoutes()
+	s.registerPlaylistRoutes()
+	s.registerPermissionRoutes()
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Client returns a Grafana API client talking to the server.
+func (s *Server) Client() *gapi.Client {
+	client, err := gapi.New(s.URL, gapi.Config{APIKey: "gapitest"})
+	if err != nil {
+		// The server URL is always valid.
+		panic(err)
+	}
+	return client
+}
+
+// request is an incoming request, along with the org it is scoped to and its path parameters.
+type request struct {
+	*http.Request
+	org    *org
+	params map[string]string
+}
+
+// param returns a path parameter.
+func (r *request) param(name string) string {
+	return r.params[name]
+}
+
+// intParam returns a numeric path parameter, or 0 if it isn't a number.
+func (r *request) intParam(name string) int64 {
+	id, _ := strconv.ParseInt(r.params[name], 10, 64)
+	return id
+}
+
+// decode decodes the JSON request body into v.
+func (r *request) decode(v interface{}) error {
+	return json.NewDecoder(r.Body).Decode(v)
+}
+
+// response is the status code and JSON body returned by a handler.
+type response struct {
+	code int
+	body interface{}
+}
+
+func ok(body interface{}) response {
+	return response{code: http.StatusOK, body: body}
+}
+
+func message(msg string) response {
+	return ok(map[string]interface{}{"message": msg})
+}
+
+func errorResponse(code int, format string, args ...interface{}) response {
+	return response{code: code, body: map[string]interface{}{"message": fmt.Sprintf(format, args...)}}
+}
+
+func notFound(kind string) response {
+	return errorResponse(http.StatusNotFound, "%s not found", kind)
+}
+
+func badRequest(err error) response {
+	return errorResponse(http.StatusBadRequest, "bad request data: %s", err)
+}
+
+type handler func(r *request) response
+
+type route struct {
+	method   string
+	segments []string
+	handler  handler
+}
+
+// handle registers a handler for a method and path pattern, where segments starting with a colon are parameters.
+// Routes are matched in registration order.
+func (s *Server) handle(method, pattern string, h handler) {
+	s.routes = append(s.routes, route{
+		method:   method,
+		segments: strings.Split(strings.Trim(pattern, "/"), "/"),
+		handler:  h,
+	})
+}
+
+func (rt route) match(method string, segments []string) (map[string]string, bool) {
+	if rt.method != method || len(rt.segments) != len(segments) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+	for i, segment := range rt.segments {
+		if strings.HasPrefix(segment, ":") {
+			params[segment[1:]] = segments[i]
+			continue
+		}
+		if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	resp := s.dispatch(r)
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(resp.code)
+	if resp.body != nil {
+		_ = json.NewEncoder(w).Encode(resp.body)
+	}
+}
+
+func (s *Server) dispatch(r *http.Request) response {
+	orgID := int64(1)
+	if header := r.Header.Get("X-Grafana-Org-Id"); header != "" {
+		var err error
+		if orgID, err = strconv.ParseInt(header, 10, 64); err != nil {
+			return errorResponse(http.StatusBadRequest, "invalid org ID %q", header)
+		}
+	}
+	o, exists := s.orgs[orgID]
+	if !exists {
+		return errorResponse(http.StatusUnauthorized, "user is not a member of org %d", orgID)
+	}
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	for _, rt := range s.routes {
+		if params, ok := rt.match(r.Method, segments); ok {
+			return rt.handler(&request{Request: r, org: o, params: params})
+		}
+	}
+	return errorResponse(http.StatusNotFound, "not found")
+}
+
+// id returns the next ID for a kind of resource. IDs are unique across orgs, like in Grafana.
+func (s *Server) id(kind string) int64 {
+	s.nextID[kind]++
+	return s.nextID[kind]
+}
+
+const uidChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// uid generates a Grafana style UID. UIDs are pseudo-random, but the same for every server.
+func (s *Server) uid() string {
+	b := make([]byte, 9)
+	for i := range b {
+		b[i] = uidChars[s.rand.Intn(len(uidChars))]
+	}
+	return string(b)
+}
+
+// slugify turns a title into a URL slug, the way Grafana does.
+func slugify(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
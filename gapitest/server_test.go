@@ -0,0 +1,551 @@
+package gapitest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+func TestFoldersAndDashboards(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	folder, err := client.NewFolder("Production", "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.NewFolder("Other", "prod"); !gapi.IsConflict(err) {
+		t.Errorf("expected conflict creating a folder with the same UID; got: %v", err)
+	}
+
+	saved, err := client.NewDashboard(gapi.Dashboard{
+		Model:     map[string]interface{}{"title": "Overview"},
+		FolderUID: folder.UID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.UID == "" || saved.ID == 0 || saved.Version != 1 {
+		t.Errorf("unexpected save response: %+v", saved)
+	}
+
+	dashboard, err := client.DashboardByUID(saved.UID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dashboard.FolderID != folder.ID {
+		t.Errorf("expected folder ID %d; got: %d", folder.ID, dashboard.FolderID)
+	}
+
+	// Saving an outdated version fails, unless overwriting.
+	dashboard.Model["version"] = float64(0)
+	if _, err := client.NewDashboard(gapi.Dashboard{Model: dashboard.Model, FolderUID: folder.UID}); !gapi.IsVersionMismatch(err) {
+		t.Errorf("expected version mismatch; got: %v", err)
+	}
+	dashboard.Model["version"] = float64(1)
+	saved, err = client.NewDashboard(gapi.Dashboard{Model: dashboard.Model, FolderUID: folder.UID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Version != 2 {
+		t.Errorf("expected version 2; got: %d", saved.Version)
+	}
+
+	dashboards, err := client.Dashboards()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dashboards) != 1 || dashboards[0].FolderUID != folder.UID {
+		t.Errorf("unexpected search results: %+v", dashboards)
+	}
+
+	if err := client.DeleteFolder(folder.UID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.DashboardByUID(saved.UID); !gapi.IsNotFound(err) {
+		t.Errorf("expected dashboard to be deleted along with its folder; got: %v", err)
+	}
+	if _, err := client.FolderByUID(folder.UID); !gapi.IsNotFound(err) {
+		t.Errorf("expected folder to be deleted; got: %v", err)
+	}
+}
+
+func TestDashboardVersions(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	saved, err := client.NewDashboard(gapi.Dashboard{Model: map[string]interface{}{"title": "Overview", "refresh": "5m"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.NewDashboard(gapi.Dashboard{
+		Model:   map[string]interface{}{"uid": saved.UID, "title": "Overview", "refresh": "1m", "version": 1},
+		Message: "Faster refresh",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := client.DashboardVersions(saved.UID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || versions[0].Version != 2 || versions[0].Message != "Faster refresh" || versions[0].Data != nil {
+		t.Fatalf("unexpected versions: %+v", versions)
+	}
+
+	changes, err := client.DiffDashboardVersions(saved.UID, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Path != "/refresh" || changes[0].New != "1m" || changes[1].Path != "/version" {
+		t.Errorf("unexpected changes: %+v", changes)
+	}
+
+	restored, err := client.RestoreDashboardVersion(saved.UID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Version != 3 {
+		t.Errorf("expected version 3; got: %d", restored.Version)
+	}
+	dashboard, err := client.DashboardByUID(saved.UID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dashboard.Model["refresh"] != "5m" {
+		t.Errorf("the dashboard wasn't restored: %v", dashboard.Model)
+	}
+
+	version, err := client.DashboardVersion(saved.UID, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version.RestoredFrom != 1 || version.ParentVersion != 2 {
+		t.Errorf("unexpected version: %+v", version)
+	}
+	if _, err := client.DashboardVersion(saved.UID, 10); !gapi.IsNotFound(err) {
+		t.Errorf("expected not found; got: %v", err)
+	}
+}
+
+func TestImportDashboard(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	folder, err := client.NewFolder("Imported", "imported")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.NewDataSource(&gapi.DataSource{Name: "Prometheus", Type: "prometheus", UID: "prom"}); err != nil {
+		t.Fatal(err)
+	}
+
+	model := map[string]interface{}{
+		"__inputs":   []interface{}{map[string]interface{}{"name": "DS_PROM", "type": "datasource", "pluginId": "prometheus"}},
+		"__requires": []interface{}{map[string]interface{}{"type": "panel", "id": "timeseries"}},
+		"title":      "Shared",
+		"panels":     []interface{}{map[string]interface{}{"type": "timeseries", "datasource": map[string]interface{}{"uid": "${DS_PROM}"}}},
+	}
+	imported, err := client.ImportSharedDashboard(model, gapi.DashboardImportOptions{FolderUID: folder.UID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !imported.Imported || imported.FolderUID != folder.UID || imported.FolderID != folder.ID {
+		t.Errorf("unexpected import response: %+v", imported)
+	}
+
+	dashboard, err := client.DashboardByUID(imported.UID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	panel := dashboard.Model["panels"].([]interface{})[0].(map[string]interface{})
+	if panel["datasource"].(map[string]interface{})["uid"] != "prom" {
+		t.Errorf("the input wasn't substituted: %v", panel)
+	}
+	if _, exists := dashboard.Model["__inputs"]; exists {
+		t.Error("__inputs should be removed")
+	}
+
+	model["__requires"] = []interface{}{map[string]interface{}{"type": "panel", "id": "not-installed"}}
+	if _, err := client.ImportSharedDashboard(model, gapi.DashboardImportOptions{}); err == nil {
+		t.Error("expected an error importing a dashboard requiring a missing plugin")
+	}
+}
+
+func TestExportAndImportDashboard(t *testing.T) {
+	source := NewServer()
+	defer source.Close()
+	sourceClient := source.Client()
+
+	if _, err := sourceClient.NewDataSource(&gapi.DataSource{Name: "Prometheus", Type: "prometheus", UID: "source-prom"}); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := sourceClient.NewDashboard(gapi.Dashboard{Model: map[string]interface{}{
+		"title":  "Shared",
+		"panels": []interface{}{map[string]interface{}{"type": "timeseries", "datasource": map[string]interface{}{"type": "prometheus", "uid": "source-prom"}}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := sourceClient.ExportDashboard(saved.UID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := NewServer()
+	defer target.Close()
+	targetClient := target.Client()
+
+	if _, err := targetClient.NewDataSource(&gapi.DataSource{Name: "Metrics", Type: "prometheus", UID: "target-prom"}); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := targetClient.ImportSharedDashboard(exported, gapi.DashboardImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dashboard, err := targetClient.DashboardByUID(imported.UID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err := dashboard.TypedModel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.Title != "Shared" || model.Panels[0].Datasource.UID != "target-prom" {
+		t.Errorf("unexpected imported dashboard: %v", dashboard.Model)
+	}
+}
+
+func TestLibraryPanelsAndPlaylists(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	folder, err := client.NewFolder("Shared", "shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	panel, err := client.NewLibraryPanel(gapi.LibraryPanel{
+		Name:      "CPU",
+		FolderUID: folder.UID,
+		Model:     map[string]interface{}{"type": "timeseries", "title": "CPU"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if panel.UID == "" || panel.Folder != folder.ID || panel.Type != "timeseries" || panel.Version != 1 {
+		t.Errorf("unexpected library panel: %+v", panel)
+	}
+
+	saved, err := client.NewDashboard(gapi.Dashboard{Model: map[string]interface{}{
+		"title":  "Uses library panel",
+		"panels": []interface{}{map[string]interface{}{"libraryPanel": map[string]interface{}{"uid": panel.UID, "name": "CPU"}}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dashboards, err := client.LibraryPanelConnectedDashboards(panel.UID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dashboards) != 1 || dashboards[0].UID != saved.UID {
+		t.Errorf("unexpected connected dashboards: %+v", dashboards)
+	}
+
+	panel.Model["title"] = "CPU usage"
+	patched, err := client.PatchLibraryPanel(panel.UID, *panel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patched.Version != 2 || patched.Model["title"] != "CPU usage" {
+		t.Errorf("unexpected patched library panel: %+v", patched)
+	}
+	panels, err := client.LibraryPanels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(panels) != 1 || panels[0].FolderUID != folder.UID {
+		t.Errorf("unexpected library panels: %+v", panels)
+	}
+	if _, err := client.DeleteLibraryPanel(panel.UID); !gapi.IsForbidden(err) {
+		t.Errorf("expected deleting a connected library panel to be forbidden; got: %v", err)
+	}
+
+	uid, err := client.NewPlaylist(gapi.Playlist{
+		Name:     "Rotation",
+		Interval: "5m",
+		Items:    []gapi.PlaylistItem{{Type: "dashboard_by_uid", Value: saved.UID, Order: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	playlists, err := client.Playlists()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(playlists) != 1 || playlists[0].UID != uid || playlists[0].Items != nil {
+		t.Errorf("unexpected playlists: %+v", playlists)
+	}
+	playlist, err := client.Playlist(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(playlist.Items) != 1 || playlist.Items[0].Value != saved.UID {
+		t.Errorf("unexpected playlist: %+v", playlist)
+	}
+	if err := client.DeletePlaylist(uid); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Playlist(uid); !gapi.IsNotFound(err) {
+		t.Errorf("expected not found; got: %v", err)
+	}
+}
+
+func TestDataSources(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	id, err := client.NewDataSource(&gapi.DataSource{
+		Name:           "prometheus",
+		Type:           "prometheus",
+		URL:            "http://prometheus:9090",
+		SecureJSONData: map[string]interface{}{"httpHeaderValue1": "secret"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.NewDataSource(&gapi.DataSource{Name: "prometheus", Type: "prometheus"}); !gapi.IsConflict(err) {
+		t.Errorf("expected name conflict; got: %v", err)
+	}
+
+	ds, err := client.DataSource(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ds.UID == "" || ds.SecureJSONData != nil || !ds.IsDefault {
+		t.Errorf("unexpected data source: %+v", ds)
+	}
+
+	ds.URL = "http://prometheus:9091"
+	if err := client.UpdateDataSourceByUID(ds); err != nil {
+		t.Fatal(err)
+	}
+	byName, err := client.DataSourceIDByName("prometheus")
+	if err != nil || byName != id {
+		t.Errorf("expected ID %d; got: %d, %v", id, byName, err)
+	}
+
+	if err := client.DeleteDataSourceByName("prometheus"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.DataSourceByUID(ds.UID); !gapi.IsNotFound(err) {
+		t.Errorf("expected not found; got: %v", err)
+	}
+}
+
+func TestUsersTeamsAndOrgs(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	userID, err := client.CreateUser(gapi.User{Login: "jane", Email: "jane@example.com", Password: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := client.Users()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 {
+		t.Errorf("expected admin and jane; got: %+v", users)
+	}
+
+	teamID, err := client.AddTeam("SRE", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.AddTeamMember(teamID, userID); err != nil {
+		t.Fatal(err)
+	}
+	search, err := client.SearchTeam("sr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if search.TotalCount != 1 || search.Teams[0].MemberCount != 1 {
+		t.Errorf("unexpected team search: %+v", search)
+	}
+
+	orgID, err := client.NewOrg("Second")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.AddOrgUser(orgID, "jane", "Editor"); err != nil {
+		t.Fatal(err)
+	}
+	orgUsers, err := client.OrgUsers(orgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orgUsers) != 1 || orgUsers[0].Role != "Editor" {
+		t.Errorf("unexpected org users: %+v", orgUsers)
+	}
+
+	// Resources are scoped to orgs.
+	if _, err := client.WithOrgID(orgID).Team(teamID); !gapi.IsNotFound(err) {
+		t.Errorf("expected team not to exist in the other org; got: %v", err)
+	}
+}
+
+func TestAlerting(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	folder, err := client.NewFolder("Alerts")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uid, err := client.NewContactPoint(&gapi.ContactPoint{Name: "slack", Type: "slack", Settings: map[string]interface{}{"url": "http://slack"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.NewMuteTiming(&gapi.MuteTiming{Name: "weekends", TimeIntervals: []gapi.TimeInterval{{Weekdays: []gapi.WeekdayRange{"saturday:sunday"}}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SetNotificationPolicyTree(&gapi.NotificationPolicyTree{
+		Receiver: "default-email",
+		Routes:   []gapi.SpecificPolicy{{Receiver: "slack", MuteTimeIntervals: []string{"weekends"}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.DeleteContactPoint(uid); !gapi.IsConflict(err) {
+		t.Errorf("expected contact point in use to be protected; got: %v", err)
+	}
+
+	rule := gapi.AlertRule{
+		Title:     "High CPU",
+		Condition: "A",
+		Data:      []*gapi.AlertQuery{{RefID: "A", DatasourceUID: "-100", Model: map[string]interface{}{}}},
+		For:       "5m",
+	}
+	if err := client.SetAlertRuleGroup(gapi.RuleGroup{Title: "cpu", FolderUID: folder.UID, Interval: 120, Rules: []gapi.AlertRule{rule}}); err != nil {
+		t.Fatal(err)
+	}
+	group, err := client.AlertRuleGroup(folder.UID, "cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.Interval != 120 || len(group.Rules) != 1 || group.Rules[0].UID == "" {
+		t.Errorf("unexpected rule group: %+v", group)
+	}
+
+	if err := client.SetMessageTemplate("footer", `{{ define "footer" }}bye{{ end }}`); err != nil {
+		t.Fatal(err)
+	}
+	templates, err := client.MessageTemplates()
+	if err != nil || len(templates) != 1 {
+		t.Errorf("expected 1 template; got: %v, %v", templates, err)
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	id, err := client.NewAnnotation(&gapi.Annotation{Text: "deploy", Time: 1000, Tags: []string{"deploy"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.PatchAnnotation(id, &gapi.Annotation{Text: "deploy v2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	annotations, err := client.Annotations(url.Values{"tags": {"deploy"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(annotations) != 1 || annotations[0].Text != "deploy v2" || annotations[0].Time != 1000 {
+		t.Errorf("unexpected annotations: %+v", annotations)
+	}
+
+	if _, err := client.DeleteAnnotation(id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.DeleteAnnotation(id); !gapi.IsNotFound(err) {
+		t.Errorf("expected not found; got: %v", err)
+	}
+}
+
+func TestTeamSearchPages(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	for _, name := range []string{"Backend", "Frontend", "SRE"} {
+		if _, err := client.AddTeam(name, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Like Grafana, the page size is read from perpage, and perPage is ignored.
+	for query, expected := range map[string][]string{
+		"perpage=2&page=2": {"SRE"},
+		"perPage=2&page=1": {"Backend", "Frontend", "SRE"},
+	} {
+		resp, err := http.Get(server.URL + "/api/teams/search?" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		search := gapi.SearchTeam{}
+		err = json.NewDecoder(resp.Body).Decode(&search)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, team := range search.Teams {
+			names = append(names, team.Name)
+		}
+		if !reflect.DeepEqual(names, expected) || search.TotalCount != 3 {
+			t.Errorf("%s: expected teams %v; got: %+v", query, expected, search)
+		}
+	}
+
+	calls := 0
+	client, err := gapi.New(server.URL, gapi.Config{CallMiddlewares: []gapi.CallMiddleware{
+		func(next gapi.CallHandler) gapi.CallHandler {
+			return func(ctx context.Context, call *gapi.Call) error {
+				calls++
+				return next(ctx, call)
+			}
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	err = client.ForEachTeam("", gapi.PageOptions{PageSize: 1}, func(team *gapi.Team) error {
+		names = append(names, team.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 3 || calls != 3 {
+		t.Errorf("expected 3 teams in 3 pages; got %v in %d calls", names, calls)
+	}
+}
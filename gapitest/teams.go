@@ -0,0 +1,207 @@
+package gapitest
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+type team struct {
+	gapi.Team
+	// members maps user IDs to their team permission.
+	members     map[int64]int64
+	preferences gapi.Preferences
+}
+
+func (o *org) teamByName(name string) *team {
+	for _, t := range o.teams {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+func (s *Server) registerTeamRoutes() {
+	s.handle("GET", "/api/teams/search", s.searchTeams)
+	s.handle("POST", "/api/teams", s.createTeam)
+	s.handle("GET", "/api/teams/:id", s.getTeam)
+	s.handle("PUT", "/api/teams/:id", s.updateTeam)
+	s.handle("DELETE", "/api/teams/:id", s.deleteTeam)
+	s.handle("GET", "/api/teams/:id/members", s.listTeamMembers)
+	s.handle("POST", "/api/teams/:id/members", s.addTeamMember)
+	s.handle("DELETE", "/api/teams/:id/members/:userId", s.removeTeamMember)
+	s.handle("GET", "/api/teams/:id/preferences", s.getTeamPreferences)
+	s.handle("PUT", "/api/teams/:id/preferences", s.updateTeamPreferences)
+}
+
+func (t *team) view() *gapi.Team {
+	view := t.Team
+	view.MemberCount = int64(len(t.members))
+	return &view
+}
+
+func (s *Server) searchTeams(r *request) response {
+	query := strings.ToLower(r.URL.Query().Get("query"))
+	name := r.URL.Query().Get("name")
+
+	matches := make([]*team, 0, len(r.org.teams))
+	for _, t := range r.org.teams {
+		if (query == "" || strings.Contains(strings.ToLower(t.Name), query)) && (name == "" || t.Name == name) {
+			matches = append(matches, t)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Name < matches[j].Name })
+
+	// Like Grafana, the page size is only read from perpage, as documented by the Team HTTP API.
+	page, perPage := pagination(r, "perpage", 1000)
+	teams := make([]*gapi.Team, 0, perPage)
+	for i := (page - 1) * perPage; i < len(matches) && i < page*perPage; i++ {
+		teams = append(teams, matches[i].view())
+	}
+
+	return ok(gapi.SearchTeam{
+		TotalCount: int64(len(matches)),
+		Teams:      teams,
+		Page:       int64(page),
+		PerPage:    int64(perPage),
+	})
+}
+
+func (s *Server) createTeam(r *request) response {
+	body := gapi.Team{}
+	if err := r.decode(&body); err != nil {
+		return badRequest(err)
+	}
+	if body.Name == "" {
+		return errorResponse(http.StatusBadRequest, "team name is required")
+	}
+	if r.org.teamByName(body.Name) != nil {
+		return errorResponse(http.StatusConflict, "Team name taken")
+	}
+
+	t := &team{
+		Team:    gapi.Team{ID: s.id("team"), OrgID: r.org.ID, Name: body.Name, Email: body.Email},
+		members: map[int64]int64{},
+	}
+	r.org.teams[t.ID] = t
+	return ok(map[string]interface{}{"teamId": t.ID, "message": "Team created"})
+}
+
+func (s *Server) getTeam(r *request) response {
+	t, exists := r.org.teams[r.intParam("id")]
+	if !exists {
+		return notFound("Team")
+	}
+	return ok(t.view())
+}
+
+func (s *Server) updateTeam(r *request) response {
+	t, exists := r.org.teams[r.intParam("id")]
+	if !exists {
+		return notFound("Team")
+	}
+
+	body := gapi.Team{}
+	if err := r.decode(&body); err != nil {
+		return badRequest(err)
+	}
+	if other := r.org.teamByName(body.Name); other != nil && other != t {
+		return errorResponse(http.StatusConflict, "Team name taken")
+	}
+
+	t.Name = body.Name
+	t.Email = body.Email
+	return message("Team updated")
+}
+
+func (s *Server) deleteTeam(r *request) response {
+	id := r.intParam("id")
+	if _, exists := r.org.teams[id]; !exists {
+		return notFound("Team")
+	}
+	delete(r.org.teams, id)
+	return message("Team deleted")
+}
+
+func (s *Server) listTeamMembers(r *request) response {
+	t, exists := r.org.teams[r.intParam("id")]
+	if !exists {
+		return notFound("Team")
+	}
+
+	members := make([]*gapi.TeamMember, 0, len(t.members))
+	for id, permission := range t.members {
+		u := s.users[id]
+		members = append(members, &gapi.TeamMember{
+			OrgID:      r.org.ID,
+			TeamID:     t.ID,
+			UserID:     id,
+			Email:      u.Email,
+			Login:      u.Login,
+			Permission: permission,
+		})
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].UserID < members[j].UserID })
+	return ok(members)
+}
+
+func (s *Server) addTeamMember(r *request) response {
+	t, exists := r.org.teams[r.intParam("id")]
+	if !exists {
+		return notFound("Team")
+	}
+
+	body := gapi.TeamMember{}
+	if err := r.decode(&body); err != nil {
+		return badRequest(err)
+	}
+	if _, exists := s.users[body.UserID]; !exists {
+		return notFound("User")
+	}
+	if _, member := t.members[body.UserID]; member {
+		return errorResponse(http.StatusBadRequest, "User is already added to this team")
+	}
+
+	t.members[body.UserID] = 0
+	return message("Member added to Team")
+}
+
+func (s *Server) removeTeamMember(r *request) response {
+	t, exists := r.org.teams[r.intParam("id")]
+	if !exists {
+		return notFound("Team")
+	}
+	userID := r.intParam("userId")
+	if _, member := t.members[userID]; !member {
+		return notFound("Team member")
+	}
+
+	delete(t.members, userID)
+	return message("Team Member removed")
+}
+
+func (s *Server) getTeamPreferences(r *request) response {
+	t, exists := r.org.teams[r.intParam("id")]
+	if !exists {
+		return notFound("Team")
+	}
+	return ok(t.preferences)
+}
+
+func (s *Server) updateTeamPreferences(r *request) response {
+	t, exists := r.org.teams[r.intParam("id")]
+	if !exists {
+		return notFound("Team")
+	}
+
+	preferences := gapi.Preferences{}
+	if err := r.decode(&preferences); err != nil {
+		return badRequest(err)
+	}
+
+	t.preferences = preferences
+	return message("Preferences updated")
+}
@@ -0,0 +1,209 @@
+package gapitest
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+type user struct {
+	gapi.User
+	password string
+}
+
+func (s *Server) newUser(u gapi.User) *user {
+	now := time.Now().UTC()
+	u.ID = s.id("user")
+	u.OrgID = 1
+	u.CreatedAt = now
+	u.UpdatedAt = now
+	if u.Login == "" {
+		u.Login = u.Email
+	}
+
+	created := &user{User: u, password: u.Password}
+	created.Password = ""
+	s.users[u.ID] = created
+	return created
+}
+
+func (s *Server) userByLoginOrEmail(loginOrEmail string) *user {
+	for _, u := range s.users {
+		if u.Login == loginOrEmail || u.Email == loginOrEmail {
+			return u
+		}
+	}
+	return nil
+}
+
+func (s *Server) registerUserRoutes() {
+	s.handle("POST", "/api/admin/users", s.createUser)
+	s.handle("DELETE", "/api/admin/users/:id", s.deleteUser)
+	s.handle("PUT", "/api/admin/users/:id/password", s.updateUserPassword)
+	s.handle("PUT", "/api/admin/users/:id/permissions", s.updateUserPermissions)
+	s.handle("GET", "/api/users", s.listUsers)
+	s.handle("GET", "/api/users/lookup", s.lookupUser)
+	s.handle("GET", "/api/users/:id", s.getUser)
+	s.handle("PUT", "/api/users/:id", s.updateUser)
+}
+
+func (s *Server) createUser(r *request) response {
+	u := gapi.User{}
+	if err := r.decode(&u); err != nil {
+		return badRequest(err)
+	}
+	if (u.Login != "" && s.userByLoginOrEmail(u.Login) != nil) || (u.Email != "" && s.userByLoginOrEmail(u.Email) != nil) {
+		return errorResponse(http.StatusPreconditionFailed, "user already exists")
+	}
+
+	created := s.newUser(u)
+	s.orgs[1].members[created.ID] = "Viewer"
+	return ok(map[string]interface{}{"id": created.ID, "message": "User created"})
+}
+
+func (s *Server) deleteUser(r *request) response {
+	id := r.intParam("id")
+	if _, exists := s.users[id]; !exists {
+		return notFound("User")
+	}
+
+	delete(s.users, id)
+	for _, o := range s.orgs {
+		delete(o.members, id)
+		for _, t := range o.teams {
+			delete(t.members, id)
+		}
+	}
+	return message("User deleted")
+}
+
+func (s *Server) updateUserPassword(r *request) response {
+	u, exists := s.users[r.intParam("id")]
+	if !exists {
+		return notFound("User")
+	}
+
+	body := struct {
+		Password string `json:"password"`
+	}{}
+	if err := r.decode(&body); err != nil {
+		return badRequest(err)
+	}
+	if len(body.Password) < 4 {
+		return errorResponse(http.StatusBadRequest, "New password too short")
+	}
+
+	u.password = body.Password
+	return message("User password updated")
+}
+
+func (s *Server) updateUserPermissions(r *request) response {
+	u, exists := s.users[r.intParam("id")]
+	if !exists {
+		return notFound("User")
+	}
+
+	body := struct {
+		IsGrafanaAdmin bool `json:"isGrafanaAdmin"`
+	}{}
+	if err := r.decode(&body); err != nil {
+		return badRequest(err)
+	}
+
+	u.IsAdmin = body.IsGrafanaAdmin
+	return message("User permissions updated")
+}
+
+func (s *Server) listUsers(r *request) response {
+	users := make([]*user, 0, len(s.users))
+	query := strings.ToLower(r.URL.Query().Get("query"))
+	for _, u := range s.users {
+		if query == "" || strings.Contains(strings.ToLower(u.Login+" "+u.Email+" "+u.Name), query) {
+			users = append(users, u)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Login < users[j].Login })
+
+	page, perPage := pagination(r, "perpage", 1000)
+	result := make([]gapi.UserSearch, 0, perPage)
+	for i := (page - 1) * perPage; i < len(users) && i < page*perPage; i++ {
+		u := users[i]
+		result = append(result, gapi.UserSearch{
+			ID:         u.ID,
+			Email:      u.Email,
+			Name:       u.Name,
+			Login:      u.Login,
+			IsAdmin:    u.IsAdmin,
+			IsDisabled: u.IsDisabled,
+			LastSeenAt: u.UpdatedAt,
+		})
+	}
+	return ok(result)
+}
+
+// pagination returns the page and page size requested through the page and perPage query parameters.
+// Pages start at 1. The name of the page size parameter isn't consistent across Grafana's API.
+func pagination(r *request, perPageParam string, defaultPerPage int) (page, perPage int) {
+	query := r.URL.Query()
+	page, err := strconv.Atoi(query.Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	perPage, err = strconv.Atoi(query.Get(perPageParam))
+	if err != nil || perPage < 1 {
+		perPage = defaultPerPage
+	}
+	return page, perPage
+}
+
+func (s *Server) lookupUser(r *request) response {
+	u := s.userByLoginOrEmail(r.URL.Query().Get("loginOrEmail"))
+	if u == nil {
+		return notFound("user")
+	}
+	return ok(u.User)
+}
+
+func (s *Server) getUser(r *request) response {
+	u, exists := s.users[r.intParam("id")]
+	if !exists {
+		return notFound("user")
+	}
+	return ok(u.User)
+}
+
+func (s *Server) updateUser(r *request) response {
+	u, exists := s.users[r.intParam("id")]
+	if !exists {
+		return notFound("user")
+	}
+
+	body := gapi.User{}
+	if err := r.decode(&body); err != nil {
+		return badRequest(err)
+	}
+	for _, other := range []string{body.Login, body.Email} {
+		if existing := s.userByLoginOrEmail(other); other != "" && existing != nil && existing != u {
+			return errorResponse(http.StatusConflict, "login or email already taken")
+		}
+	}
+
+	if body.Login != "" {
+		u.Login = body.Login
+	}
+	if body.Email != "" {
+		u.Email = body.Email
+	}
+	if body.Name != "" {
+		u.Name = body.Name
+	}
+	if body.Theme != "" {
+		u.Theme = body.Theme
+	}
+	u.UpdatedAt = time.Now().UTC()
+	return message("User updated")
+}
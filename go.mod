@@ -5,4 +5,5 @@ go 1.14
 require (
 	github.com/gobs/pretty v0.0.0-20180724170744-09732c25a95b
 	github.com/hashicorp/go-cleanhttp v0.5.2
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,15 @@
+// Package instrumentation provides opt-in OpenTelemetry tracing and Prometheus metrics for the Grafana API client.
+//
+// It lives in its own module, so that users who don't enable instrumentation don't depend on either library.
+// Both are exposed as call middlewares to add to the client's Config:
+//
+//	metrics := instrumentation.NewMetrics()
+//	prometheus.MustRegister(metrics)
+//
+//	client, err := gapi.New(url, gapi.Config{
+//		CallMiddlewares: []gapi.CallMiddleware{
+//			instrumentation.Tracing(otel.GetTracerProvider()),
+//			metrics.Middleware(),
+//		},
+//	})
+package instrumentation
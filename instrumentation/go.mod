@@ -0,0 +1,32 @@
+module github.com/grafana/grafana-api-golang-client/instrumentation
+
+// go.opentelemetry.io/otel v1.28.0 requires go 1.21.
+go 1.21
+
+// The client doesn't have a tagged release with the middlewares yet: build against the one in the parent
+// directory until it does, then require that release and remove this directive.
+replace github.com/grafana/grafana-api-golang-client => ../
+
+require (
+	github.com/grafana/grafana-api-golang-client v0.0.0-00010101000000-000000000000
+	github.com/prometheus/client_golang v1.19.1
+	go.opentelemetry.io/otel v1.28.0
+	go.opentelemetry.io/otel/sdk v1.28.0
+	go.opentelemetry.io/otel/trace v1.28.0
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/hashicorp/go-cleanhttp v0.5.2 // indirect
+	github.com/prometheus/client_model v0.5.0 // indirect
+	github.com/prometheus/common v0.48.0 // indirect
+	github.com/prometheus/procfs v0.12.0 // indirect
+	go.opentelemetry.io/otel/metric v1.28.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
+)
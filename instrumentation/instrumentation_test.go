@@ -0,0 +1,88 @@
+package instrumentation
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func testClient(t *testing.T, code int, body string, middlewares ...gapi.CallMiddleware) *gapi.Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := gapi.New(server.URL, gapi.Config{CallMiddlewares: middlewares})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client.WithOrgID(2)
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	client := testClient(t, 404, `{"message":"Dashboard not found"}`, Tracing(tp))
+	if _, err := client.DashboardByUID("foo"); err == nil {
+		t.Fatal("expected error")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span; got: %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "DashboardByUID" {
+		t.Errorf("expected span name DashboardByUID; got: %s", span.Name())
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("expected error status; got: %v", span.Status())
+	}
+
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs["grafana.org_id"].AsInt64() != 2 {
+		t.Errorf("expected org ID 2; got: %v", attrs["grafana.org_id"])
+	}
+	if attrs["http.response.status_code"].AsInt64() != 404 {
+		t.Errorf("expected status code 404; got: %v", attrs["http.response.status_code"])
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	metrics := NewMetrics()
+
+	client := testClient(t, 200, `{"version":"9.3.0"}`, metrics.Middleware())
+	for i := 0; i < 2; i++ {
+		if _, err := client.Health(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := `
+# HELP grafana_api_client_requests_total Total number of Grafana API calls.
+# TYPE grafana_api_client_requests_total counter
+grafana_api_client_requests_total{method="GET",operation="Health",status_code="200"} 2
+`
+	if err := testutil.CollectAndCompare(metrics, strings.NewReader(expected), "grafana_api_client_requests_total"); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(metrics, "grafana_api_client_request_duration_seconds"); n != 1 {
+		t.Errorf("expected 1 duration series; got: %d", n)
+	}
+}
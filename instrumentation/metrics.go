@@ -0,0 +1,80 @@
+package instrumentation
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics collects request counters and latency histograms for API calls,
+// labelled by operation, HTTP method and status code.
+type Metrics struct {
+	requests *prometheus.CounterVec
+	retries  *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+var labels = []string{"operation", "method", "status_code"}
+
+// NewMetrics creates the collectors. They still need to be registered, Metrics being a prometheus.Collector itself.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "grafana_api_client_requests_total",
+			Help: "Total number of Grafana API calls.",
+		}, labels),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "grafana_api_client_retries_total",
+			Help: "Total number of retried HTTP attempts made for Grafana API calls.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "grafana_api_client_request_duration_seconds",
+			Help:    "Duration of Grafana API calls, including retries.",
+			Buckets: prometheus.DefBuckets,
+		}, labels),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.requests.Describe(ch)
+	m.retries.Describe(ch)
+	m.duration.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.requests.Collect(ch)
+	m.retries.Collect(ch)
+	m.duration.Collect(ch)
+}
+
+// Middleware returns a call middleware recording the metrics.
+func (m *Metrics) Middleware() gapi.CallMiddleware {
+	return func(next gapi.CallHandler) gapi.CallHandler {
+		return func(ctx context.Context, call *gapi.Call) error {
+			start := time.Now()
+			err := next(ctx, call)
+
+			values := []string{call.Operation, call.Method, statusCode(call)}
+			m.requests.WithLabelValues(values...).Inc()
+			m.duration.WithLabelValues(values...).Observe(time.Since(start).Seconds())
+			if call.Attempts > 1 {
+				m.retries.WithLabelValues(values...).Add(float64(call.Attempts - 1))
+			}
+
+			return err
+		}
+	}
+}
+
+// statusCode returns the call's status code as a label value, "error" meaning no response was received.
+func statusCode(call *gapi.Call) string {
+	if call.StatusCode == 0 {
+		return "error"
+	}
+	return strconv.Itoa(call.StatusCode)
+}
@@ -0,0 +1,58 @@
+package instrumentation
+
+import (
+	"context"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/grafana/grafana-api-golang-client/instrumentation"
+
+// Tracing returns a call middleware which emits a span for each API call.
+// Spans are named after the client method issuing the call, such as "NewDashboard".
+func Tracing(tp trace.TracerProvider) gapi.CallMiddleware {
+	tracer := tp.Tracer(instrumentationName)
+
+	return func(next gapi.CallHandler) gapi.CallHandler {
+		return func(ctx context.Context, call *gapi.Call) error {
+			ctx, span := tracer.Start(ctx, spanName(call),
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					attribute.String("grafana.operation", call.Operation),
+					attribute.String("http.request.method", call.Method),
+					attribute.String("url.path", call.Path),
+				),
+			)
+			defer span.End()
+
+			if call.OrgID != 0 {
+				span.SetAttributes(attribute.Int64("grafana.org_id", call.OrgID))
+			}
+
+			err := next(ctx, call)
+
+			if call.StatusCode != 0 {
+				span.SetAttributes(attribute.Int("http.response.status_code", call.StatusCode))
+			}
+			if call.Attempts > 1 {
+				span.SetAttributes(attribute.Int("http.request.resend_count", call.Attempts-1))
+			}
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+
+			return err
+		}
+	}
+}
+
+func spanName(call *gapi.Call) string {
+	if call.Operation != "" {
+		return call.Operation
+	}
+	return call.Method
+}
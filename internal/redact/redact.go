@@ -0,0 +1,58 @@
+// Package redact removes secrets from the request and response bodies which are logged or recorded.
+package redact
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Redacted replaces the values of secrets.
+const Redacted = "[REDACTED]"
+
+// JSON returns a JSON body as a string with the values of secret fields replaced.
+// Bodies which aren't JSON are returned as is.
+func JSON(body []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return string(body)
+	}
+
+	data, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return string(body)
+	}
+	return string(data)
+}
+
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, value := range v {
+			if IsSecretField(k) {
+				v[k] = Redacted
+				continue
+			}
+			v[k] = redactValue(value)
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactValue(value)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// IsSecretField reports whether a JSON field holds a secret, such as secureJsonData,
+// passwords or the keys returned when creating API keys and service account tokens.
+func IsSecretField(key string) bool {
+	k := strings.ToLower(key)
+	return k == "key" ||
+		k == "securejsondata" ||
+		strings.HasSuffix(k, "apikey") ||
+		strings.Contains(k, "password") ||
+		strings.Contains(k, "secret") ||
+		strings.Contains(k, "token")
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"time"
 )
 
@@ -27,7 +28,9 @@ type LibraryPanelMeta struct {
 
 // LibraryPanel represents a Grafana library panel.
 type LibraryPanel struct {
-	Folder      int64                  `json:"folderId,omitempty"`
+	Folder int64 `json:"folderId,omitempty"`
+	// FolderUID is supported since Grafana 9, and preferred over Folder.
+	FolderUID   string                 `json:"folderUid,omitempty"`
 	Name        string                 `json:"name"`
 	Model       map[string]interface{} `json:"model"`
 	Type        string                 `json:"type,omitempty"`
@@ -86,17 +89,41 @@ func (c *Client) NewLibraryPanel(panel LibraryPanel) (*LibraryPanel, error) {
 	return &resp.Result, err
 }
 
-// Dashboards fetches and returns all dashboards.
+// LibraryPanels fetches and returns all library panels.
 func (c *Client) LibraryPanels() ([]LibraryPanel, error) {
-	resp := &struct {
-		Result LibraryPanelGetAllResponse `json:"result"`
-	}{}
-	err := c.request("GET", "/api/library-elements", nil, nil, &resp)
+	panels := []LibraryPanel{}
+	err := c.ForEachLibraryPanel(PageOptions{}, func(panel LibraryPanel) error {
+		panels = append(panels, panel)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return resp.Result.Elements, err
+	return panels, nil
+}
+
+// ForEachLibraryPanel calls fn for each library panel, fetching them a page at a time.
+// fn can return ErrStopIteration to stop early.
+func (c *Client) ForEachLibraryPanel(opts PageOptions, fn func(LibraryPanel) error) error {
+	return paginate(opts, 100, func(page, perPage int) (int, int64, error) {
+		query := url.Values{}
+		query.Set("page", fmt.Sprint(page))
+		query.Set("perPage", fmt.Sprint(perPage))
+
+		resp := &struct {
+			Result LibraryPanelGetAllResponse `json:"result"`
+		}{}
+		if err := c.request("GET", "/api/library-elements", query, nil, &resp); err != nil {
+			return 0, 0, err
+		}
+		for _, panel := range resp.Result.Elements {
+			if err := fn(panel); err != nil {
+				return 0, 0, err
+			}
+		}
+		return len(resp.Result.Elements), resp.Result.TotalCount, nil
+	})
 }
 
 // LibraryPanelByUID gets a library panel by UID.
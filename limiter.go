@@ -0,0 +1,102 @@
+package gapi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// limiter bounds the rate and concurrency of HTTP attempts. It is created once by New,
+// so that clients derived with WithOrgID or WithContext share the same budget.
+type limiter struct {
+	bucket *tokenBucket
+	sem    chan struct{}
+}
+
+func newLimiter(cfg Config) *limiter {
+	if cfg.RateLimit <= 0 && cfg.MaxConcurrentRequests <= 0 {
+		return nil
+	}
+
+	l := &limiter{}
+	if cfg.RateLimit > 0 {
+		burst := cfg.RateLimitBurst
+		if burst < 1 {
+			burst = 1
+		}
+		l.bucket = &tokenBucket{
+			rate:   cfg.RateLimit,
+			burst:  float64(burst),
+			tokens: float64(burst),
+			last:   time.Now(),
+		}
+	}
+	if cfg.MaxConcurrentRequests > 0 {
+		l.sem = make(chan struct{}, cfg.MaxConcurrentRequests)
+	}
+	return l
+}
+
+// acquire waits until an HTTP attempt is allowed to start. The returned function must be
+// called once the attempt is done, to free its concurrency slot.
+func (l *limiter) acquire(ctx context.Context) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	if l.bucket != nil {
+		if err := l.bucket.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if l.sem == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.sem <- struct{}{}:
+		return func() { <-l.sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// tokenBucket is a token bucket rate limiter, refilled with rate tokens per second up to burst tokens.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// wait takes a token from the bucket, waiting for it to be refilled if it is empty.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	// Reserve the token right away, waiting for the bucket to go back to zero if it went negative.
+	b.tokens--
+	var d time.Duration
+	if b.tokens < 0 {
+		d = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if d == 0 {
+		return nil
+	}
+	if err := sleep(ctx, d); err != nil {
+		// Hand the reserved token back, as it won't be used.
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return err
+	}
+	return nil
+}
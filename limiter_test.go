@@ -0,0 +1,98 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiter_rate(t *testing.T) {
+	l := newLimiter(Config{RateLimit: 20, RateLimitBurst: 2})
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		release, err := l.acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+
+	// The burst allows 2 attempts right away, the 2 others wait 50ms each.
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("expected attempts to be rate limited; took %s", elapsed)
+	}
+}
+
+func TestLimiter_rateCanceled(t *testing.T) {
+	l := newLimiter(Config{RateLimit: 0.001})
+
+	if _, err := l.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error: %v; got: %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestLimiter_concurrency(t *testing.T) {
+	l := newLimiter(Config{MaxConcurrentRequests: 2})
+
+	var (
+		mu       sync.Mutex
+		inFlight int
+		max      int
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := l.acquire(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			inFlight++
+			if inFlight > max {
+				max = inFlight
+			}
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			release()
+		}()
+	}
+	wg.Wait()
+
+	if max != 2 {
+		t.Errorf("expected at most 2 attempts in flight; got: %d", max)
+	}
+}
+
+func TestLimiter_sharedWithOrgID(t *testing.T) {
+	client := gapiTestTools(t, 200, `{}`)
+	client.limiter = newLimiter(Config{MaxConcurrentRequests: 1})
+
+	orgClient := client.WithOrgID(2)
+	if orgClient.limiter != client.limiter {
+		t.Errorf("expected derived client to share the limiter")
+	}
+	if err := orgClient.request("GET", "/foo", url.Values{}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.limiter.sem) != 0 {
+		t.Errorf("expected concurrency slot to be released")
+	}
+}
@@ -0,0 +1,122 @@
+package gapi
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-api-golang-client/internal/redact"
+)
+
+// Logger receives the client's request logs as a message followed by alternating keys and values,
+// in the style of log/slog. A *slog.Logger satisfies this interface.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+}
+
+const redacted = redact.Redacted
+
+// redactedHeaders lists the canonical names of headers whose values are never logged.
+var redactedHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"X-Api-Key":     true,
+}
+
+// stdLogger is the Logger used when the GF_LOG environment variable is set and no Logger is configured.
+type stdLogger struct{}
+
+func (stdLogger) Debug(msg string, args ...interface{}) {
+	log.Print(formatLogLine(msg, args))
+}
+
+func (stdLogger) Warn(msg string, args ...interface{}) {
+	log.Print(formatLogLine("WARN "+msg, args))
+}
+
+func formatLogLine(msg string, args []interface{}) string {
+	var b strings.Builder
+	b.WriteString(msg)
+	for i := 0; i+1 < len(args); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+	}
+	return b.String()
+}
+
+// logger returns the configured logger, or nil when logging is disabled.
+func (c *Client) logger() Logger {
+	if c.config.Logger != nil {
+		return c.config.Logger
+	}
+	if os.Getenv("GF_LOG") != "" {
+		return stdLogger{}
+	}
+	return nil
+}
+
+// logBodies reports whether request and response bodies should be logged.
+// GF_LOG always logged bodies, so it keeps doing so.
+func (c *Client) logBodies() bool {
+	return c.config.LogBodies || (c.config.Logger == nil && os.Getenv("GF_LOG") != "")
+}
+
+// logAttempt logs a single HTTP attempt made while serving a request.
+func (c *Client) logAttempt(req *http.Request, attempt int, duration time.Duration, reqBody []byte, resp *http.Response, respBody []byte, err error) {
+	logger := c.logger()
+	if logger == nil {
+		return
+	}
+
+	args := []interface{}{
+		"method", req.Method,
+		"url", redactURL(req.URL),
+		"attempt", attempt,
+		"duration", duration,
+		"headers", redactHeaders(req.Header),
+	}
+	if resp != nil {
+		args = append(args, "status", resp.StatusCode)
+	}
+	if c.logBodies() {
+		if reqBody != nil {
+			args = append(args, "requestBody", redact.JSON(reqBody))
+		}
+		if respBody != nil {
+			args = append(args, "responseBody", redact.JSON(respBody))
+		}
+	}
+
+	if err != nil {
+		logger.Warn("request failed", append(args, "error", err)...)
+		return
+	}
+	logger.Debug("request completed", args...)
+}
+
+func redactURL(u *url.URL) string {
+	if u.User == nil {
+		return u.String()
+	}
+	r := *u
+	if _, ok := r.User.Password(); ok {
+		r.User = url.UserPassword(r.User.Username(), redacted)
+	}
+	return r.String()
+}
+
+func redactHeaders(h http.Header) map[string]string {
+	headers := make(map[string]string, len(h))
+	for k, v := range h {
+		if redactedHeaders[http.CanonicalHeaderKey(k)] {
+			headers[k] = redacted
+			continue
+		}
+		headers[k] = strings.Join(v, ", ")
+	}
+	return headers
+}
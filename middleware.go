@@ -0,0 +1,86 @@
+package gapi
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Call describes a logical API call made by the client. Middlewares may modify it before passing it on.
+type Call struct {
+	// Operation is the name of the Client method which issued the call, such as "NewDashboard".
+	Operation string
+	// OrgID is the organization ID the call is scoped to, or 0 if none was set.
+	OrgID int64
+	// Method is the HTTP method of the call.
+	Method string
+	// Path is the API path, relative to the client's base URL.
+	Path string
+	// Query holds the query parameters.
+	Query url.Values
+	// Body is the request body, or nil if there is none.
+	Body []byte
+	// Response is the value the response body is decoded into, or nil if it is discarded.
+	Response interface{}
+
+	// StatusCode is the status code of the final HTTP response, set once the call completed.
+	// It is 0 if no response was received.
+	StatusCode int
+	// Attempts is the number of HTTP attempts made, set once the call completed.
+	Attempts int
+}
+
+// CallHandler performs a logical API call.
+type CallHandler func(ctx context.Context, call *Call) error
+
+// CallMiddleware wraps a CallHandler, e.g. to trace or audit API calls.
+type CallMiddleware func(next CallHandler) CallHandler
+
+// AttemptMiddleware wraps the round tripper performing each HTTP attempt, e.g. to sign requests or mutate headers.
+type AttemptMiddleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc is an adapter to allow the use of ordinary functions as http.RoundTripper.
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req).
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// transport returns the round tripper performing HTTP attempts, wrapped in the configured middlewares.
+func (c *Client) transport() http.RoundTripper {
+	var rt http.RoundTripper = RoundTripperFunc(c.client.Do)
+	for i := len(c.config.AttemptMiddlewares) - 1; i >= 0; i-- {
+		rt = c.config.AttemptMiddlewares[i](rt)
+	}
+	return rt
+}
+
+// pkgPrefix is the prefix of the fully qualified names of this package's functions.
+var pkgPrefix = strings.TrimSuffix(runtime.FuncForPC(reflect.ValueOf(New).Pointer()).Name(), "New")
+
+// callerOperation returns the name of the outermost exported Client method on the call stack,
+// or an empty string if the request wasn't issued through one.
+func callerOperation() string {
+	const clientMethod = "(*Client)."
+
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+
+	operation := ""
+	for {
+		frame, more := frames.Next()
+		if name := strings.TrimPrefix(frame.Function, pkgPrefix); name != frame.Function && strings.HasPrefix(name, clientMethod) {
+			method := strings.TrimPrefix(name, clientMethod)
+			if method != "" && method[0] >= 'A' && method[0] <= 'Z' && !strings.Contains(method, ".") {
+				operation = method
+			}
+		}
+		if !more {
+			return operation
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCallMiddlewares(t *testing.T) {
+	client := gapiTestTools(t, 200, `{"commit":"abc","database":"ok","version":"9.3.0"}`)
+
+	var order []string
+	trace := func(name string) CallMiddleware {
+		return func(next CallHandler) CallHandler {
+			return func(ctx context.Context, call *Call) error {
+				order = append(order, name+":"+call.Method+" "+call.Path)
+				if _, ok := call.Response.(*HealthResponse); !ok {
+					t.Errorf("expected response target *HealthResponse; got: %T", call.Response)
+				}
+				if call.Operation != "Health" {
+					t.Errorf("expected operation Health; got: %s", call.Operation)
+				}
+				err := next(ctx, call)
+				if call.StatusCode != 200 || call.Attempts != 1 {
+					t.Errorf("expected status 200 after 1 attempt; got: %d after %d", call.StatusCode, call.Attempts)
+				}
+				order = append(order, name+":done")
+				return err
+			}
+		}
+	}
+	client.config.CallMiddlewares = []CallMiddleware{trace("outer"), trace("inner")}
+
+	health, err := client.Health()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if health.Version != "9.3.0" {
+		t.Errorf("expected version 9.3.0; got: %s", health.Version)
+	}
+
+	expected := []string{"outer:GET /api/health", "inner:GET /api/health", "inner:done", "outer:done"}
+	if len(order) != len(expected) {
+		t.Fatalf("expected %v; got: %v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Errorf("expected %v; got: %v", expected, order)
+		}
+	}
+}
+
+func TestCallMiddlewares_shortCircuit(t *testing.T) {
+	client := gapiTestTools(t, 200, `{}`)
+
+	denied := errors.New("denied")
+	client.config.CallMiddlewares = []CallMiddleware{
+		func(next CallHandler) CallHandler {
+			return func(ctx context.Context, call *Call) error {
+				if call.Method == "DELETE" {
+					return denied
+				}
+				return next(ctx, call)
+			}
+		},
+	}
+
+	if err := client.DeleteDashboardByUID("foo"); !errors.Is(err, denied) {
+		t.Errorf("expected error: %v; got: %v", denied, err)
+	}
+}
+
+func TestAttemptMiddlewares(t *testing.T) {
+	client := gapiTestToolsFromCalls(t, []mockServerCall{
+		{500, `{"message":"error"}`},
+		{200, `{"foo":"bar"}`},
+	})
+	client.config.RetryPolicy = &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}
+
+	attempts := 0
+	client.config.AttemptMiddlewares = []AttemptMiddleware{
+		func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				req.Header.Set("X-Signature", "signed")
+				return next.RoundTrip(req)
+			})
+		},
+		func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				attempts++
+				if req.Header.Get("X-Signature") != "signed" {
+					t.Errorf("expected outer middleware to run first")
+				}
+				return next.RoundTrip(req)
+			})
+		},
+	}
+
+	if err := client.request("GET", "/foo", url.Values{}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts; got: %d", attempts)
+	}
+}
+
+func TestCallOperation(t *testing.T) {
+	client := gapiTestToolsFromCalls(t, []mockServerCall{
+		{200, getContactPointsJSON},
+		{200, `{}`},
+	})
+
+	var operations []string
+	client.config.CallMiddlewares = []CallMiddleware{
+		func(next CallHandler) CallHandler {
+			return func(ctx context.Context, call *Call) error {
+				operations = append(operations, call.Operation)
+				return next(ctx, call)
+			}
+		},
+	}
+
+	if _, err := client.ContactPoint("rc5r0bjnz"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.request("GET", "/foo", url.Values{}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(operations) != 2 || operations[0] != "ContactPoint" || operations[1] != "" {
+		t.Errorf("expected operations [ContactPoint ]; got: %q", operations)
+	}
+}
@@ -0,0 +1,599 @@
+// Package migrate copies resources from a Grafana instance to another, e.g. from a self-hosted Grafana
+// to a Grafana Cloud stack.
+//
+// Resources keep their UIDs. The numeric IDs which differ between instances are remapped to the ones of
+// the destination: folder IDs referenced by library panels and dashboard list panels, dashboard IDs of
+// library panel connections, and team and user IDs of permissions, teams and users being matched by name
+// and login. Resources which already exist in the destination are reported as conflicts, unless overwritten.
+//
+// Data sources aren't migrated: the ones used by the migrated dashboards and alert rules must exist in the
+// destination with the same UIDs.
+package migrate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+// Kinds of migrated resources.
+const (
+	KindFolder               = "folder"
+	KindLibraryPanel         = "library-panel"
+	KindDashboard            = "dashboard"
+	KindFolderPermissions    = "folder-permissions"
+	KindDashboardPermissions = "dashboard-permissions"
+	KindMessageTemplate      = "message-template"
+	KindMuteTiming           = "mute-timing"
+	KindContactPoint         = "contact-point"
+	KindNotificationPolicies = "notification-policies"
+	KindRuleGroup            = "rule-group"
+	KindTeam                 = "team"
+)
+
+// Operations of Action.
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+)
+
+// cloudKeyDuration is how long the API key created to migrate to a Grafana Cloud stack is valid.
+const cloudKeyDuration = time.Hour
+
+// Options selects the resources to migrate.
+type Options struct {
+	// FolderUIDs restricts the migration to these folders and the resources in them. When empty, all
+	// the folders are migrated, as well as the resources of the General folder.
+	FolderUIDs []string
+
+	// LibraryPanels, Dashboards and AlertRules migrate these resources of the selected folders.
+	LibraryPanels bool
+	Dashboards    bool
+	AlertRules    bool
+	// Permissions migrates the permissions of the migrated folders and dashboards.
+	// Teams and users are matched by name and login, and must exist in the destination.
+	Permissions bool
+	// Teams migrates all the teams, with the members which exist in the destination.
+	Teams bool
+	// Alerting migrates the message templates, mute timings, contact points and notification policies.
+	Alerting bool
+
+	// Overwrite replaces the resources which already exist in the destination,
+	// instead of reporting them as conflicts.
+	Overwrite bool
+	// DryRun reports what would be migrated without changing the destination.
+	DryRun bool
+}
+
+// Report lists what a migration changed, or would change in a dry run.
+type Report struct {
+	Actions   []Action
+	Conflicts []Conflict
+
+	// FolderIDs, DashboardIDs, TeamIDs and UserIDs map the IDs of resources in the source
+	// to their IDs in the destination. Resources created in a dry run aren't mapped.
+	FolderIDs    map[int64]int64
+	DashboardIDs map[int64]int64
+	TeamIDs      map[int64]int64
+	UserIDs      map[int64]int64
+}
+
+// Action is the creation or update of a resource in the destination.
+type Action struct {
+	Op   string
+	Kind string
+	// Name identifies the resource: its UID, or its name for resources without UID.
+	Name string
+}
+
+// Conflict is a resource which couldn't be migrated, or only partly.
+type Conflict struct {
+	Kind   string
+	Name   string
+	Reason string
+}
+
+// String returns the actions and conflicts of the report, one per line.
+func (r *Report) String() string {
+	lines := make([]string, 0, len(r.Actions)+len(r.Conflicts))
+	for _, action := range r.Actions {
+		lines = append(lines, fmt.Sprintf("%s %s %s", action.Op, action.Kind, action.Name))
+	}
+	for _, conflict := range r.Conflicts {
+		lines = append(lines, fmt.Sprintf("conflict %s %s: %s", conflict.Kind, conflict.Name, conflict.Reason))
+	}
+	return strings.Join(lines, "\n")
+}
+
+type migrator struct {
+	source      *gapi.Client
+	destination *gapi.Client
+	opts        Options
+	report      *Report
+
+	// folders are the UIDs of the folders to migrate.
+	folders map[string]bool
+	// libraryPanels and dashboards are the UIDs of the migrated library panels and dashboards.
+	libraryPanels []string
+	dashboards    []string
+}
+
+// Migrate copies the resources selected by opts from the org of the source client
+// to the org of the destination client.
+func Migrate(source, destination *gapi.Client, opts Options) (*Report, error) {
+	m := &migrator{
+		source:      source,
+		destination: destination,
+		opts:        opts,
+		report: &Report{
+			FolderIDs:    map[int64]int64{},
+			DashboardIDs: map[int64]int64{},
+			TeamIDs:      map[int64]int64{},
+			UserIDs:      map[int64]int64{},
+		},
+		folders: map[string]bool{},
+	}
+
+	steps := []struct {
+		enabled bool
+		run     func() error
+	}{
+		{opts.Teams || opts.Permissions, m.users},
+		{opts.Teams, m.teams},
+		{true, m.migrateFolders},
+		{opts.LibraryPanels, m.migrateLibraryPanels},
+		{opts.Dashboards, m.migrateDashboards},
+		{opts.LibraryPanels && opts.Dashboards, m.checkLibraryPanelConnections},
+		{opts.Permissions, m.permissions},
+		{opts.Alerting, m.alerting},
+		{opts.AlertRules, m.ruleGroups},
+	}
+	for _, step := range steps {
+		if !step.enabled {
+			continue
+		}
+		if err := step.run(); err != nil {
+			return m.report, err
+		}
+	}
+	return m.report, nil
+}
+
+// MigrateToCloudStack migrates resources to a Grafana Cloud stack, like Migrate. The destination client is
+// created with a temporary admin API key, created with the Grafana Cloud client and deleted when done.
+func MigrateToCloudStack(source, cloud *gapi.Client, stackSlug string, opts Options) (*Report, error) {
+	destination, cleanup, err := cloud.CreateTemporaryStackGrafanaClient(stackSlug, "migration", cloudKeyDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := Migrate(source, destination, opts)
+	if cleanupErr := cleanup(); err == nil {
+		err = cleanupErr
+	}
+	return report, err
+}
+
+// isConflict reports whether an error creating or updating a resource is due to a conflicting resource,
+// e.g. with the same name.
+func isConflict(err error) bool {
+	return gapi.IsConflict(err) || gapi.IsVersionMismatch(err)
+}
+
+func (m *migrator) conflict(kind, name, format string, args ...interface{}) {
+	m.report.Conflicts = append(m.report.Conflicts, Conflict{Kind: kind, Name: name, Reason: fmt.Sprintf(format, args...)})
+}
+
+// apply creates or, when overwriting, updates a resource of the destination. It returns whether the
+// resource was migrated, conflicts being reported instead of returned.
+func (m *migrator) apply(kind, name string, exists bool, create, update func() error) (bool, error) {
+	action := Action{Op: OpCreate, Kind: kind, Name: name}
+	apply := create
+	if exists {
+		if !m.opts.Overwrite {
+			m.conflict(kind, name, "already exists in the destination")
+			return false, nil
+		}
+		action.Op = OpUpdate
+		apply = update
+	}
+
+	if !m.opts.DryRun {
+		err := apply()
+		if isConflict(err) {
+			m.conflict(kind, name, "%v", err)
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("%s %s %s: %w", action.Op, kind, name, err)
+		}
+	}
+	m.report.Actions = append(m.report.Actions, action)
+	return true, nil
+}
+
+// update updates a resource which always exists in the destination, e.g. the permissions of a migrated folder.
+func (m *migrator) update(kind, name string, update func() error) error {
+	if !m.opts.DryRun {
+		if err := update(); err != nil {
+			return fmt.Errorf("%s %s %s: %w", OpUpdate, kind, name, err)
+		}
+	}
+	m.report.Actions = append(m.report.Actions, Action{Op: OpUpdate, Kind: kind, Name: name})
+	return nil
+}
+
+// exists returns whether a resource exists, given the error returned when fetching it.
+func exists(err error) (bool, error) {
+	if err == nil {
+		return true, nil
+	}
+	if gapi.IsNotFound(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// selected reports whether resources of a folder are migrated, the General folder having an empty UID.
+func (m *migrator) selected(folderUID string) bool {
+	if folderUID == "" {
+		return len(m.opts.FolderUIDs) == 0
+	}
+	return m.folders[folderUID]
+}
+
+func (m *migrator) migrateFolders() error {
+	folders, err := m.source.Folders()
+	if err != nil {
+		return err
+	}
+	bySourceUID := map[string]gapi.Folder{}
+	for _, folder := range folders {
+		bySourceUID[folder.UID] = folder
+	}
+
+	if len(m.opts.FolderUIDs) == 0 {
+		for _, folder := range folders {
+			m.folders[folder.UID] = true
+		}
+	}
+	for _, uid := range m.opts.FolderUIDs {
+		if _, found := bySourceUID[uid]; !found {
+			return fmt.Errorf("folder %s not found in the source", uid)
+		}
+		m.folders[uid] = true
+	}
+
+	for _, folder := range folders {
+		if !m.folders[folder.UID] {
+			continue
+		}
+		folder := folder
+
+		existing, err := m.destination.FolderByUID(folder.UID)
+		found, err := exists(err)
+		if err != nil {
+			return err
+		}
+		if found {
+			m.report.FolderIDs[folder.ID] = existing.ID
+		}
+
+		_, err = m.apply(KindFolder, folder.UID, found, func() error {
+			created, err := m.destination.NewFolder(folder.Title, folder.UID)
+			if err == nil {
+				m.report.FolderIDs[folder.ID] = created.ID
+			}
+			return err
+		}, func() error {
+			return m.destination.UpdateFolder(folder.UID, folder.Title)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *migrator) migrateLibraryPanels() error {
+	return m.source.ForEachLibraryPanel(gapi.PageOptions{}, func(panel gapi.LibraryPanel) error {
+		folderUID := panel.FolderUID
+		if folderUID == "" {
+			folderUID = panel.Meta.FolderUID
+		}
+		if !m.selected(folderUID) {
+			return nil
+		}
+
+		existing, err := m.destination.LibraryPanelByUID(panel.UID)
+		found, err := exists(err)
+		if err != nil {
+			return err
+		}
+
+		migrated := gapi.LibraryPanel{
+			UID:         panel.UID,
+			Name:        panel.Name,
+			FolderUID:   folderUID,
+			Folder:      m.report.FolderIDs[panel.Folder],
+			Description: panel.Description,
+			Model:       panel.Model,
+		}
+		ok, err := m.apply(KindLibraryPanel, panel.UID, found, func() error {
+			_, err := m.destination.NewLibraryPanel(migrated)
+			return err
+		}, func() error {
+			migrated.Kind = existing.Kind
+			migrated.Version = existing.Version
+			_, err := m.destination.PatchLibraryPanel(panel.UID, migrated)
+			return err
+		})
+		if ok {
+			m.libraryPanels = append(m.libraryPanels, panel.UID)
+		}
+		return err
+	})
+}
+
+func (m *migrator) migrateDashboards() error {
+	return m.source.ForEachDashboard(gapi.PageOptions{}, func(result gapi.FolderDashboardSearchResponse) error {
+		if !m.selected(result.FolderUID) {
+			return nil
+		}
+
+		dashboard, err := m.source.DashboardByUID(result.UID)
+		if err != nil {
+			return err
+		}
+		existing, err := m.destination.DashboardByUID(result.UID)
+		found, err := exists(err)
+		if err != nil {
+			return err
+		}
+		if found {
+			m.report.DashboardIDs[int64(result.ID)] = modelID(existing.Model)
+		}
+
+		model, err := m.remapDashboard(result.UID, dashboard.Model)
+		if err != nil {
+			return err
+		}
+		save := func() error {
+			saved, err := m.destination.NewDashboard(gapi.Dashboard{
+				Model:     model,
+				FolderUID: result.FolderUID,
+				Overwrite: found,
+				Message:   "Migrated",
+			})
+			if err == nil {
+				m.report.DashboardIDs[int64(result.ID)] = saved.ID
+			}
+			return err
+		}
+		ok, err := m.apply(KindDashboard, result.UID, found, save, save)
+		if ok {
+			m.dashboards = append(m.dashboards, result.UID)
+		}
+		return err
+	})
+}
+
+// remapDashboard returns a copy of a dashboard model to save in the destination: without ID and version,
+// and with the folder IDs of dashboard list panels remapped.
+func (m *migrator) remapDashboard(uid string, model map[string]interface{}) (map[string]interface{}, error) {
+	typed, err := gapi.NewDashboardModel(model)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, panel := range typed.AllPanels() {
+		if panel.Type != "dashlist" || panel.Options == nil {
+			continue
+		}
+		folderID, ok := panel.Options["folderId"].(float64)
+		if !ok || folderID == 0 {
+			continue
+		}
+		if mapped, exists := m.report.FolderIDs[int64(folderID)]; exists {
+			panel.Options["folderId"] = mapped
+		} else {
+			m.conflict(KindDashboard, uid, "panel %d lists the dashboards of folder %d, which isn't migrated", panel.ID, int64(folderID))
+		}
+	}
+
+	remapped, err := typed.Map()
+	if err != nil {
+		return nil, err
+	}
+	delete(remapped, "id")
+	delete(remapped, "version")
+	return remapped, nil
+}
+
+func modelID(model map[string]interface{}) int64 {
+	id, _ := model["id"].(float64)
+	return int64(id)
+}
+
+// checkLibraryPanelConnections checks that the migrated library panels are connected to the migrated
+// dashboards using them, like in the source. Grafana connects library panels to dashboards when saving them.
+func (m *migrator) checkLibraryPanelConnections() error {
+	if m.opts.DryRun {
+		return nil
+	}
+
+	for _, uid := range m.libraryPanels {
+		connections, err := m.source.LibraryPanelConnections(uid)
+		if err != nil {
+			return err
+		}
+		migrated, err := m.destination.LibraryPanelConnections(uid)
+		if err != nil {
+			return err
+		}
+		connected := map[int64]bool{}
+		for _, connection := range *migrated {
+			connected[connection.DashboardID] = true
+		}
+
+		for _, connection := range *connections {
+			dashboardID, mapped := m.report.DashboardIDs[connection.DashboardID]
+			switch {
+			case !mapped:
+				m.conflict(KindLibraryPanel, uid, "connected to dashboard %d, which isn't migrated", connection.DashboardID)
+			case !connected[dashboardID]:
+				m.conflict(KindLibraryPanel, uid, "not connected to dashboard %d in the destination", dashboardID)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *migrator) alerting() error {
+	steps := []func() error{m.messageTemplates, m.muteTimings, m.contactPoints, m.notificationPolicies}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *migrator) messageTemplates() error {
+	templates, err := m.source.MessageTemplates()
+	if err != nil {
+		return err
+	}
+
+	for _, template := range templates {
+		template := template
+		_, err := m.destination.MessageTemplate(template.Name)
+		found, err := exists(err)
+		if err != nil {
+			return err
+		}
+
+		set := func() error {
+			return m.destination.SetMessageTemplate(template.Name, template.Template)
+		}
+		if _, err := m.apply(KindMessageTemplate, template.Name, found, set, set); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *migrator) muteTimings() error {
+	timings, err := m.source.MuteTimings()
+	if err != nil {
+		return err
+	}
+
+	for _, timing := range timings {
+		timing := timing
+		timing.Provenance = ""
+		_, err := m.destination.MuteTiming(timing.Name)
+		found, err := exists(err)
+		if err != nil {
+			return err
+		}
+
+		_, err = m.apply(KindMuteTiming, timing.Name, found, func() error {
+			return m.destination.NewMuteTiming(&timing)
+		}, func() error {
+			return m.destination.UpdateMuteTiming(&timing)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *migrator) contactPoints() error {
+	points, err := m.source.ContactPoints()
+	if err != nil {
+		return err
+	}
+	existing, err := m.destination.ContactPoints()
+	if err != nil {
+		return err
+	}
+	found := map[string]bool{}
+	for _, point := range existing {
+		found[point.UID] = true
+	}
+
+	for _, point := range points {
+		point := point
+		point.Provenance = ""
+		_, err := m.apply(KindContactPoint, point.UID, found[point.UID], func() error {
+			_, err := m.destination.NewContactPoint(&point)
+			return err
+		}, func() error {
+			return m.destination.UpdateContactPoint(&point)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notificationPolicies migrates the notification policy tree, which always exists in the destination:
+// it's only replaced when overwriting.
+func (m *migrator) notificationPolicies() error {
+	tree, err := m.source.NotificationPolicyTree()
+	if err != nil {
+		return err
+	}
+
+	tree.Provenance = ""
+	set := func() error {
+		return m.destination.SetNotificationPolicyTree(&tree)
+	}
+	_, err = m.apply(KindNotificationPolicies, "", true, set, set)
+	return err
+}
+
+func (m *migrator) ruleGroups() error {
+	rules, err := m.source.AlertRules()
+	if err != nil {
+		return err
+	}
+
+	type groupKey struct{ folderUID, title string }
+	seen := map[groupKey]bool{}
+	for _, rule := range rules {
+		key := groupKey{rule.FolderUID, rule.RuleGroup}
+		if seen[key] || !m.selected(rule.FolderUID) {
+			continue
+		}
+		seen[key] = true
+
+		group, err := m.source.AlertRuleGroup(key.folderUID, key.title)
+		if err != nil {
+			return err
+		}
+		for i := range group.Rules {
+			group.Rules[i].ID = 0
+			group.Rules[i].OrgID = 0
+			group.Rules[i].Provenance = ""
+		}
+		_, err = m.destination.AlertRuleGroup(key.folderUID, key.title)
+		found, err := exists(err)
+		if err != nil {
+			return err
+		}
+
+		set := func() error {
+			return m.destination.SetAlertRuleGroup(group)
+		}
+		if _, err := m.apply(KindRuleGroup, key.folderUID+"/"+key.title, found, set, set); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,294 @@
+package migrate
+
+import (
+	"testing"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+	"github.com/grafana/grafana-api-golang-client/gapitest"
+)
+
+type fixture struct {
+	source, destination *gapitest.Server
+	folder              gapi.Folder
+	team                int64
+	user                int64
+}
+
+// newFixture populates a source server with resources to migrate, and a destination server
+// where the same resources would get other IDs.
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	f := &fixture{source: gapitest.NewServer(), destination: gapitest.NewServer()}
+	source, destination := f.source.Client(), f.destination.Client()
+
+	// Shift the IDs of the destination.
+	if _, err := destination.CreateUser(gapi.User{Login: "bob", Password: "password"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := destination.NewFolder("Existing"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := destination.AddTeam("Other", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := destination.CreateUser(gapi.User{Login: "jane", Password: "password"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var err error
+	if f.user, err = source.CreateUser(gapi.User{Login: "jane", Password: "password"}); err != nil {
+		t.Fatal(err)
+	}
+	if f.team, err = source.AddTeam("SRE", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := source.AddTeamMember(f.team, f.user); err != nil {
+		t.Fatal(err)
+	}
+	if f.folder, err = source.NewFolder("Production", "prod"); err != nil {
+		t.Fatal(err)
+	}
+	err = source.UpdateFolderPermissions("prod", &gapi.PermissionItems{Items: []*gapi.PermissionItem{
+		{Role: "Viewer", Permission: 1},
+		{TeamID: f.team, Permission: 4},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := source.NewLibraryPanel(gapi.LibraryPanel{UID: "cpu", Name: "CPU", FolderUID: "prod", Model: map[string]interface{}{"type": "stat"}}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = source.NewDashboard(gapi.Dashboard{FolderUID: "prod", Model: map[string]interface{}{
+		"uid":   "services",
+		"title": "Services",
+		"panels": []interface{}{
+			map[string]interface{}{"id": 1, "libraryPanel": map[string]interface{}{"uid": "cpu", "name": "CPU"}},
+			map[string]interface{}{"id": 2, "type": "dashlist", "options": map[string]interface{}{"folderId": f.folder.ID}},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := source.UpdateDashboardPermissionsByUID("services", &gapi.PermissionItems{Items: []*gapi.PermissionItem{{UserID: f.user, Permission: 2}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := source.NewContactPoint(&gapi.ContactPoint{UID: "slack", Name: "slack", Type: "slack", Settings: map[string]interface{}{"url": "http://slack"}}); err != nil {
+		t.Fatal(err)
+	}
+	rule := gapi.AlertRule{
+		UID:       "high-cpu",
+		Title:     "High CPU",
+		Condition: "A",
+		Data:      []*gapi.AlertQuery{{RefID: "A", DatasourceUID: "-100", Model: map[string]interface{}{}}},
+	}
+	if err := source.SetAlertRuleGroup(gapi.RuleGroup{Title: "cpu", FolderUID: "prod", Interval: 60, Rules: []gapi.AlertRule{rule}}); err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
+
+func (f *fixture) Close() {
+	f.source.Close()
+	f.destination.Close()
+}
+
+var all = Options{
+	LibraryPanels: true,
+	Dashboards:    true,
+	AlertRules:    true,
+	Permissions:   true,
+	Teams:         true,
+	Alerting:      true,
+}
+
+func TestMigrate(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	destination := f.destination.Client()
+
+	report, err := Migrate(f.source.Client(), destination, all)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, report)
+	}
+	// The default contact point and the notification policies exist in every org.
+	if len(report.Conflicts) != 2 || report.Conflicts[0].Kind != KindContactPoint || report.Conflicts[1].Kind != KindNotificationPolicies {
+		t.Errorf("unexpected conflicts:\n%s", report)
+	}
+
+	folder, err := destination.FolderByUID("prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if folder.ID == f.folder.ID || report.FolderIDs[f.folder.ID] != folder.ID {
+		t.Errorf("expected folder %d to be mapped to %d; got: %v", f.folder.ID, folder.ID, report.FolderIDs)
+	}
+
+	dashboard, err := destination.DashboardByUID("services")
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err := dashboard.TypedModel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if folderID := model.Panels[1].Options["folderId"]; folderID != float64(folder.ID) {
+		t.Errorf("expected the dashboard list panel to list folder %d; got: %v", folder.ID, folderID)
+	}
+	connections, err := destination.LibraryPanelConnections("cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*connections) != 1 || (*connections)[0].DashboardID != modelID(dashboard.Model) {
+		t.Errorf("unexpected library panel connections: %+v", *connections)
+	}
+
+	team, err := destination.SearchTeam("SRE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	teamID := team.Teams[0].ID
+	if teamID == f.team || team.Teams[0].MemberCount != 1 {
+		t.Errorf("unexpected team: %+v", team.Teams[0])
+	}
+	folderPermissions, err := destination.FolderPermissions("prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(folderPermissions) != 2 || folderPermissions[1].TeamID != teamID {
+		t.Errorf("expected the folder permissions of team %d; got: %+v", teamID, folderPermissions[1])
+	}
+	dashboardPermissions, err := destination.DashboardPermissionsByUID("services")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID := report.UserIDs[f.user]
+	if last := dashboardPermissions[len(dashboardPermissions)-1]; last.Inherited || last.UserID != userID || userID == f.user {
+		t.Errorf("expected the dashboard permission of user %d; got: %+v", userID, last)
+	}
+
+	if _, err := destination.AlertRuleGroup("prod", "cpu"); err != nil {
+		t.Error(err)
+	}
+	if _, err := destination.ContactPoint("slack"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMigrateConflicts(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	if _, err := Migrate(f.source.Client(), f.destination.Client(), all); err != nil {
+		t.Fatal(err)
+	}
+
+	// Migrating again reports everything as conflicts, unless overwriting.
+	report, err := Migrate(f.source.Client(), f.destination.Client(), Options{Dashboards: true, LibraryPanels: true, AlertRules: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Actions) != 0 || len(report.Conflicts) != 4 {
+		t.Errorf("expected only conflicts:\n%s", report)
+	}
+
+	opts := all
+	opts.Overwrite = true
+	report, err = Migrate(f.source.Client(), f.destination.Client(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Conflicts) != 0 {
+		t.Errorf("unexpected conflicts:\n%s", report)
+	}
+	for _, action := range report.Actions {
+		if action.Op != OpUpdate {
+			t.Errorf("expected only updates:\n%s", report)
+			break
+		}
+	}
+
+	// A folder with the same title but another UID is a conflict.
+	if _, err := f.source.Client().NewFolder("Existing", "existing"); err != nil {
+		t.Fatal(err)
+	}
+	report, err = Migrate(f.source.Client(), f.destination.Client(), Options{FolderUIDs: []string{"existing"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Conflicts) != 1 || report.Conflicts[0].Name != "existing" {
+		t.Errorf("expected a folder conflict:\n%s", report)
+	}
+}
+
+func TestMigrateDryRun(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+
+	opts := all
+	opts.DryRun = true
+	report, err := Migrate(f.source.Client(), f.destination.Client(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Actions) == 0 || report.Actions[0] != (Action{Op: OpCreate, Kind: KindTeam, Name: "SRE"}) {
+		t.Errorf("unexpected dry run report:\n%s", report)
+	}
+	if _, err := f.destination.Client().FolderByUID("prod"); !gapi.IsNotFound(err) {
+		t.Errorf("expected the dry run not to create the folder; got: %v", err)
+	}
+}
+
+func TestMigrateSelectedFolders(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	source := f.source.Client()
+	if _, err := source.NewFolder("Staging", "staging"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.NewDashboard(gapi.Dashboard{Model: map[string]interface{}{"uid": "home", "title": "Home"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Migrate(source, f.destination.Client(), Options{FolderUIDs: []string{"staging"}, Dashboards: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Actions) != 1 || report.Actions[0] != (Action{Op: OpCreate, Kind: KindFolder, Name: "staging"}) {
+		t.Errorf("expected only the staging folder to be migrated:\n%s", report)
+	}
+
+	if _, err := Migrate(source, f.destination.Client(), Options{FolderUIDs: []string{"missing"}}); err == nil {
+		t.Error("expected an error migrating a missing folder")
+	}
+}
+
+func TestMigrateUnmappedFolderPermissions(t *testing.T) {
+	f := newFixture(t)
+	defer f.Close()
+	source := f.source.Client()
+	if _, err := source.NewFolder("On-call", "oncall"); err != nil {
+		t.Fatal(err)
+	}
+	if err := source.UpdateFolderPermissions("oncall", &gapi.PermissionItems{Items: []*gapi.PermissionItem{{TeamID: f.team, Permission: 4}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without migrating teams, no permission of the folder can be mapped.
+	report, err := Migrate(source, f.destination.Client(), Options{FolderUIDs: []string{"oncall"}, Permissions: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Conflicts) != 1 || report.Conflicts[0].Kind != KindFolderPermissions {
+		t.Errorf("expected a folder permissions conflict:\n%s", report)
+	}
+	permissions, err := f.destination.Client().FolderPermissions("oncall")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(permissions) == 0 {
+		t.Error("expected the permissions of the destination folder to be kept")
+	}
+}
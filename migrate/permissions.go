@@ -0,0 +1,233 @@
+package migrate
+
+import (
+	"fmt"
+	"sort"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+// users maps the users of the source org to the users of the destination org with the same login.
+func (m *migrator) users() error {
+	sourceUsers, err := m.source.OrgUsersCurrent()
+	if err != nil {
+		return err
+	}
+	destinationUsers, err := m.destination.OrgUsersCurrent()
+	if err != nil {
+		return err
+	}
+
+	byLogin := map[string]int64{}
+	for _, user := range destinationUsers {
+		byLogin[user.Login] = user.UserID
+	}
+	for _, user := range sourceUsers {
+		if id, exists := byLogin[user.Login]; exists {
+			m.report.UserIDs[user.UserID] = id
+		}
+	}
+	return nil
+}
+
+func (m *migrator) teams() error {
+	return m.source.ForEachTeam("", gapi.PageOptions{}, func(team *gapi.Team) error {
+		existing, err := m.destinationTeam(team.Name)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			m.report.TeamIDs[team.ID] = existing.ID
+		}
+
+		ok, err := m.apply(KindTeam, team.Name, existing != nil, func() error {
+			id, err := m.destination.AddTeam(team.Name, team.Email)
+			if err == nil {
+				m.report.TeamIDs[team.ID] = id
+			}
+			return err
+		}, func() error {
+			return m.destination.UpdateTeam(existing.ID, team.Name, team.Email)
+		})
+		if err != nil || !ok || m.opts.DryRun {
+			return err
+		}
+
+		return m.teamMembers(team)
+	})
+}
+
+// destinationTeam returns the team of the destination with the given name, or nil.
+func (m *migrator) destinationTeam(name string) (*gapi.Team, error) {
+	result, err := m.destination.SearchTeam(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, team := range result.Teams {
+		if team.Name == name {
+			return team, nil
+		}
+	}
+	return nil, nil
+}
+
+// teamMembers adds the members of a source team to the team of the destination, if they exist there.
+func (m *migrator) teamMembers(team *gapi.Team) error {
+	destinationID := m.report.TeamIDs[team.ID]
+	members, err := m.source.TeamMembers(team.ID)
+	if err != nil {
+		return err
+	}
+	existing, err := m.destination.TeamMembers(destinationID)
+	if err != nil {
+		return err
+	}
+	isMember := map[int64]bool{}
+	for _, member := range existing {
+		isMember[member.UserID] = true
+	}
+
+	for _, member := range members {
+		userID, mapped := m.report.UserIDs[member.UserID]
+		if !mapped {
+			m.conflict(KindTeam, team.Name, "member %s not found in the destination", member.Login)
+			continue
+		}
+		if isMember[userID] {
+			continue
+		}
+		if err := m.destination.AddTeamMember(destinationID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// teamID maps the ID of a team of the source to the ID of the team of the destination with the same name.
+func (m *migrator) teamID(sourceID int64) (int64, bool, error) {
+	if id, mapped := m.report.TeamIDs[sourceID]; mapped {
+		return id, true, nil
+	}
+
+	team, err := m.source.Team(sourceID)
+	if err != nil {
+		return 0, false, err
+	}
+	existing, err := m.destinationTeam(team.Name)
+	if err != nil || existing == nil {
+		return 0, false, err
+	}
+	m.report.TeamIDs[sourceID] = existing.ID
+	return existing.ID, true, nil
+}
+
+// permissions migrates the permissions of the migrated folders and dashboards.
+func (m *migrator) permissions() error {
+	folders := make([]string, 0, len(m.folders))
+	for uid := range m.folders {
+		if m.migrated(KindFolder, uid) {
+			folders = append(folders, uid)
+		}
+	}
+	sort.Strings(folders)
+
+	for _, uid := range folders {
+		permissions, err := m.source.FolderPermissions(uid)
+		if err != nil {
+			return err
+		}
+
+		items := &gapi.PermissionItems{}
+		for _, permission := range permissions {
+			item, err := m.permissionItem(KindFolderPermissions, uid, permission.Role, permission.TeamID, permission.UserID, permission.Permission)
+			if err != nil {
+				return err
+			}
+			if item != nil {
+				items.Items = append(items.Items, item)
+			}
+		}
+		// An empty list would remove the permissions of the destination folder.
+		if len(items.Items) == 0 {
+			continue
+		}
+
+		err = m.update(KindFolderPermissions, uid, func() error {
+			return m.destination.UpdateFolderPermissions(uid, items)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, uid := range m.dashboards {
+		permissions, err := m.source.DashboardPermissionsByUID(uid)
+		if err != nil {
+			return err
+		}
+
+		items := &gapi.PermissionItems{}
+		for _, permission := range permissions {
+			// Inherited permissions are those of the folder.
+			if permission.Inherited {
+				continue
+			}
+			item, err := m.permissionItem(KindDashboardPermissions, uid, permission.Role, permission.TeamID, permission.UserID, permission.Permission)
+			if err != nil {
+				return err
+			}
+			if item != nil {
+				items.Items = append(items.Items, item)
+			}
+		}
+		if len(items.Items) == 0 {
+			continue
+		}
+
+		err = m.update(KindDashboardPermissions, uid, func() error {
+			return m.destination.UpdateDashboardPermissionsByUID(uid, items)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// permissionItem returns a permission of the source, with its team or user remapped to the destination.
+// It returns nil if the team or user doesn't exist in the destination, reporting a conflict.
+func (m *migrator) permissionItem(kind, uid, role string, teamID, userID, permission int64) (*gapi.PermissionItem, error) {
+	item := &gapi.PermissionItem{Role: role, Permission: permission}
+	switch {
+	case teamID != 0:
+		id, mapped, err := m.teamID(teamID)
+		if err != nil {
+			return nil, err
+		}
+		if !mapped && !m.opts.DryRun {
+			m.conflict(kind, uid, "team %d not found in the destination", teamID)
+			return nil, nil
+		}
+		item.TeamID = id
+	case userID != 0:
+		id, mapped := m.report.UserIDs[userID]
+		if !mapped {
+			m.conflict(kind, uid, "user %d not found in the destination", userID)
+			return nil, nil
+		}
+		item.UserID = id
+	case role == "":
+		return nil, fmt.Errorf("%s %s: permission without role, team or user", kind, uid)
+	}
+	return item, nil
+}
+
+// migrated reports whether a resource was migrated.
+func (m *migrator) migrated(kind, name string) bool {
+	for _, action := range m.report.Actions {
+		if action.Kind == kind && action.Name == name {
+			return true
+		}
+	}
+	return false
+}
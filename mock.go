@@ -1,10 +1,13 @@
 package gapi
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"sync"
 	"testing"
 )
 
@@ -14,6 +17,7 @@ type mockServerCall struct {
 }
 
 type mockServer struct {
+	mu            sync.Mutex
 	upcomingCalls []mockServerCall
 	executedCalls []mockServerCall
 	server        *httptest.Server
@@ -35,6 +39,9 @@ func gapiTestToolsFromCalls(t *testing.T, calls []mockServerCall) *Client {
 	}
 
 	mock.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mock.mu.Lock()
+		defer mock.mu.Unlock()
+
 		call := mock.upcomingCalls[0]
 		if len(calls) > 1 {
 			mock.upcomingCalls = mock.upcomingCalls[1:]
@@ -66,3 +73,35 @@ func gapiTestToolsFromCalls(t *testing.T, calls []mockServerCall) *Client {
 
 	return client
 }
+
+// mockRequest is a request sent to the mock server.
+type mockRequest struct {
+	method string
+	uri    string
+	header http.Header
+	body   []byte
+}
+
+// recordRequests records the requests sent by a client of the mock server.
+func recordRequests(t *testing.T, client *Client) *[]mockRequest {
+	t.Helper()
+
+	requests := &[]mockRequest{}
+	transport := client.client.Transport
+	client.client.Transport = RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		request := mockRequest{method: req.Method, uri: req.URL.RequestURI(), header: req.Header}
+		if req.Body != nil {
+			body, err := ioutil.ReadAll(req.Body)
+			req.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			request.body = body
+			req = req.Clone(req.Context())
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		*requests = append(*requests, request)
+		return transport.RoundTrip(req)
+	})
+	return requests
+}
@@ -0,0 +1,43 @@
+package gapi
+
+import "errors"
+
+// ErrStopIteration can be returned by the callback of a ForEach method to stop
+// iterating early. The ForEach method then returns nil.
+var ErrStopIteration = errors.New("stop iteration")
+
+// PageOptions configures how list endpoints are paginated.
+type PageOptions struct {
+	// PageSize is the number of items fetched per request.
+	// When it's zero, the default of the endpoint is used.
+	PageSize int
+}
+
+// pageFetcher fetches a page, starting at 1, and calls the iteration callback for each of its items.
+// It returns the number of items in the page and the total number of items, or -1 when the endpoint doesn't tell.
+type pageFetcher func(page, perPage int) (count int, total int64, err error)
+
+// paginate fetches pages until a short page is returned, the total is reached
+// or the callback returns an error.
+func paginate(opts PageOptions, defaultPageSize int, fetch pageFetcher) error {
+	perPage := opts.PageSize
+	if perPage <= 0 {
+		perPage = defaultPageSize
+	}
+
+	seen := int64(0)
+	for page := 1; ; page++ {
+		count, total, err := fetch(page, perPage)
+		if errors.Is(err, ErrStopIteration) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		seen += int64(count)
+		if count < perPage || (total >= 0 && seen >= total) {
+			return nil
+		}
+	}
+}
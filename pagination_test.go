@@ -0,0 +1,137 @@
+package gapi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	cases := []struct {
+		name    string
+		opts    PageOptions
+		counts  []int
+		total   int64
+		fetches int
+	}{
+		{name: "short page", opts: PageOptions{PageSize: 2}, counts: []int{2, 2, 1}, total: -1, fetches: 3},
+		{name: "empty page", opts: PageOptions{PageSize: 2}, counts: []int{2, 2, 0}, total: -1, fetches: 3},
+		{name: "total reached", opts: PageOptions{PageSize: 2}, counts: []int{2, 2}, total: 4, fetches: 2},
+		{name: "default page size", counts: []int{5}, total: -1, fetches: 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fetches := 0
+			err := paginate(tc.opts, 10, func(page, perPage int) (int, int64, error) {
+				fetches++
+				if page != fetches {
+					t.Errorf("expected page %d, got %d", fetches, page)
+				}
+				if tc.opts.PageSize == 0 && perPage != 10 {
+					t.Errorf("expected the default page size, got %d", perPage)
+				}
+				return tc.counts[page-1], tc.total, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fetches != tc.fetches {
+				t.Errorf("expected %d fetches, got %d", tc.fetches, fetches)
+			}
+		})
+	}
+}
+
+func TestPaginateErrors(t *testing.T) {
+	err := paginate(PageOptions{}, 10, func(page, perPage int) (int, int64, error) {
+		return 0, 0, ErrStopIteration
+	})
+	if err != nil {
+		t.Errorf("expected stopping the iteration not to be an error, got %v", err)
+	}
+
+	boom := errors.New("boom")
+	err = paginate(PageOptions{}, 10, func(page, perPage int) (int, int64, error) {
+		return 0, 0, boom
+	})
+	if err != boom {
+		t.Errorf("expected %v, got %v", boom, err)
+	}
+}
+
+// usersPages are 5 users from /api/users, paginated like Grafana with 2 users per page. The mock server fails
+// requests beyond the given pages.
+var usersPages = []mockServerCall{
+	{200, `[{"id": 1}, {"id": 2}]`},
+	{200, `[{"id": 3}, {"id": 4}]`},
+	{200, `[{"id": 5}]`},
+}
+
+func TestForEachUser(t *testing.T) {
+	client := gapiTestToolsFromCalls(t, usersPages)
+
+	ids := []int64{}
+	err := client.ForEachUser(PageOptions{PageSize: 2}, func(user UserSearch) error {
+		ids = append(ids, user.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ids) != 5 || ids[0] != 1 || ids[4] != 5 {
+		t.Errorf("unexpected users: %v", ids)
+	}
+}
+
+func TestForEachUserStop(t *testing.T) {
+	client := gapiTestToolsFromCalls(t, usersPages[:2])
+
+	seen := 0
+	err := client.ForEachUser(PageOptions{PageSize: 2}, func(user UserSearch) error {
+		seen++
+		if seen == 3 {
+			return ErrStopIteration
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if seen != 3 {
+		t.Errorf("expected 3 users, got %d", seen)
+	}
+}
+
+func TestForEachTeam(t *testing.T) {
+	client := gapiTestToolsFromCalls(t, []mockServerCall{
+		{200, `{"totalCount": 2, "teams": [{"id": 1, "name": "a"}], "page": 1, "perPage": 1}`},
+		{200, `{"totalCount": 2, "teams": [{"id": 2, "name": "b"}], "page": 2, "perPage": 1}`},
+	})
+
+	names := []string{}
+	err := client.ForEachTeam("", PageOptions{PageSize: 1}, func(team *Team) error {
+		names = append(names, team.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("unexpected teams: %v", names)
+	}
+}
+
+func TestForEachServiceAccountError(t *testing.T) {
+	client := gapiTestTools(t, 500, `{"message": "error"}`)
+
+	err := client.ForEachServiceAccount(PageOptions{}, func(ServiceAccountDTO) error {
+		t.Error("the callback shouldn't be called")
+		return nil
+	})
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -32,6 +32,18 @@ func (p *Playlist) QueryID() string {
 	return fmt.Sprintf("%d", p.ID)
 }
 
+// Playlists fetches and returns the Grafana playlists of the org.
+// The playlists are returned without their items: use Playlist to get them.
+func (c *Client) Playlists() ([]Playlist, error) {
+	playlists := make([]Playlist, 0)
+	err := c.request("GET", "/api/playlists", nil, nil, &playlists)
+	if err != nil {
+		return nil, err
+	}
+
+	return playlists, nil
+}
+
 // Playlist fetches and returns a Grafana playlist.
 func (c *Client) Playlist(idOrUID string) (*Playlist, error) {
 	path := fmt.Sprintf("/api/playlists/%s", idOrUID)
@@ -92,6 +92,19 @@ func TestGetPlaylist(t *testing.T) {
 	}
 }
 
+func TestPlaylists(t *testing.T) {
+	client := gapiTestTools(t, 200, `[{"id": 1, "uid": "1", "name": "my playlist", "interval": "5m"}]`)
+
+	playlists, err := client.Playlists()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(playlists) != 1 || playlists[0].UID != "1" || playlists[0].Interval != "5m" {
+		t.Errorf("unexpected playlists: %+v", playlists)
+	}
+}
+
 func TestDeletePlaylist(t *testing.T) {
 	client := gapiTestTools(t, 200, "")
 
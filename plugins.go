@@ -0,0 +1,47 @@
+package gapi
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// InstalledPlugin represents a plugin installed in a Grafana instance, including core plugins.
+type InstalledPlugin struct {
+	ID      string              `json:"id"`
+	Name    string              `json:"name"`
+	Type    string              `json:"type"`
+	Enabled bool                `json:"enabled"`
+	Info    InstalledPluginInfo `json:"info"`
+}
+
+// InstalledPluginInfo holds information about an installed plugin.
+type InstalledPluginInfo struct {
+	Description string `json:"description"`
+	Version     string `json:"version"`
+	Updated     string `json:"updated"`
+}
+
+// InstalledPlugins fetches and returns the plugins installed in the Grafana instance.
+func (c *Client) InstalledPlugins() ([]InstalledPlugin, error) {
+	plugins := []InstalledPlugin{}
+	query := url.Values{}
+	query.Set("embedded", "0")
+
+	err := c.request("GET", "/api/plugins", query, nil, &plugins)
+	if err != nil {
+		return nil, err
+	}
+
+	return plugins, nil
+}
+
+// InstalledPlugin fetches an installed plugin by ID.
+func (c *Client) InstalledPlugin(id string) (*InstalledPlugin, error) {
+	plugin := &InstalledPlugin{}
+	err := c.request("GET", fmt.Sprintf("/api/plugins/%s/settings", id), nil, nil, plugin)
+	if err != nil {
+		return nil, err
+	}
+
+	return plugin, nil
+}
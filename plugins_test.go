@@ -0,0 +1,43 @@
+package gapi
+
+import (
+	"testing"
+
+	"github.com/gobs/pretty"
+)
+
+const (
+	getInstalledPluginsJSON = `[
+	{"name": "Prometheus", "type": "datasource", "id": "prometheus", "enabled": true, "info": {"description": "Open source time series database & alerting", "version": "10.2.0"}},
+	{"name": "Time series", "type": "panel", "id": "timeseries", "enabled": true, "info": {"version": "10.2.0"}}
+]`
+	getInstalledPluginJSON = `{"name": "Prometheus", "type": "datasource", "id": "prometheus", "enabled": true, "info": {"version": "10.2.0"}}`
+)
+
+func TestInstalledPlugins(t *testing.T) {
+	client := gapiTestTools(t, 200, getInstalledPluginsJSON)
+
+	plugins, err := client.InstalledPlugins()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Log(pretty.PrettyFormat(plugins))
+
+	if len(plugins) != 2 || plugins[0].ID != "prometheus" || plugins[1].Type != "panel" || plugins[0].Info.Version != "10.2.0" {
+		t.Errorf("unexpected plugins: %+v", plugins)
+	}
+}
+
+func TestInstalledPlugin(t *testing.T) {
+	client := gapiTestTools(t, 200, getInstalledPluginJSON)
+
+	plugin, err := client.InstalledPlugin("prometheus")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plugin.ID != "prometheus" || !plugin.Enabled {
+		t.Errorf("unexpected plugin: %+v", plugin)
+	}
+}
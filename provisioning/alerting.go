@@ -0,0 +1,616 @@
+package provisioning
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+	"gopkg.in/yaml.v3"
+)
+
+// The annotations linking an alert rule to a dashboard panel, which the files set as dashboardUid and panelId.
+const (
+	dashboardUIDAnnotation = "__dashboardUid__"
+	panelIDAnnotation      = "__panelId__"
+)
+
+// AlertingFile is an alerting provisioning file, e.g. provisioning/alerting/alerting.yaml. It's also the format
+// of the alerting exports of Grafana. Unlike data source files, the strings of alerting files aren't
+// interpolated, since templates and annotations reference labels like $labels.
+type AlertingFile struct {
+	APIVersion          int64                `yaml:"apiVersion"`
+	Groups              []RuleGroup          `yaml:"groups,omitempty"`
+	DeleteRules         []DeleteRule         `yaml:"deleteRules,omitempty"`
+	ContactPoints       []ContactPoint       `yaml:"contactPoints,omitempty"`
+	DeleteContactPoints []DeleteContactPoint `yaml:"deleteContactPoints,omitempty"`
+	Policies            []Policy             `yaml:"policies,omitempty"`
+	// ResetPolicies lists the orgs whose notification policy tree is reset to the default.
+	ResetPolicies   []int64            `yaml:"resetPolicies,omitempty"`
+	MuteTimes       []MuteTiming       `yaml:"muteTimes,omitempty"`
+	DeleteMuteTimes []DeleteMuteTiming `yaml:"deleteMuteTimes,omitempty"`
+	Templates       []Template         `yaml:"templates,omitempty"`
+	DeleteTemplates []DeleteTemplate   `yaml:"deleteTemplates,omitempty"`
+}
+
+// RuleGroup is a provisioned rule group. Folder is the title of its folder, and Interval its evaluation interval,
+// e.g. 1m.
+type RuleGroup struct {
+	OrgID    int64       `yaml:"orgId,omitempty"`
+	Name     string      `yaml:"name"`
+	Folder   string      `yaml:"folder"`
+	Interval string      `yaml:"interval"`
+	Rules    []AlertRule `yaml:"rules"`
+}
+
+// AlertRule is a provisioned alert rule. DashboardUID and PanelID link it to a panel.
+type AlertRule struct {
+	UID          string            `yaml:"uid"`
+	Title        string            `yaml:"title"`
+	Condition    string            `yaml:"condition"`
+	Data         []AlertQuery      `yaml:"data"`
+	DashboardUID string            `yaml:"dashboardUid,omitempty"`
+	PanelID      int64             `yaml:"panelId,omitempty"`
+	NoDataState  string            `yaml:"noDataState,omitempty"`
+	ExecErrState string            `yaml:"execErrState,omitempty"`
+	For          string            `yaml:"for,omitempty"`
+	Annotations  map[string]string `yaml:"annotations,omitempty"`
+	Labels       map[string]string `yaml:"labels,omitempty"`
+	IsPaused     bool              `yaml:"isPaused,omitempty"`
+}
+
+// AlertQuery is a query of a provisioned alert rule, whose fields are the same as the API.
+type AlertQuery struct {
+	gapi.AlertQuery
+}
+
+// MarshalYAML implements the yaml.Marshaler interface for AlertQuery.
+func (q AlertQuery) MarshalYAML() (interface{}, error) {
+	return toYAML(q.AlertQuery)
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface for AlertQuery.
+func (q *AlertQuery) UnmarshalYAML(node *yaml.Node) error {
+	return fromYAML(node, &q.AlertQuery)
+}
+
+// ContactPoint is a provisioned contact point, made of the receivers the API lists as contact points of the same
+// name.
+type ContactPoint struct {
+	OrgID     int64      `yaml:"orgId,omitempty"`
+	Name      string     `yaml:"name"`
+	Receivers []Receiver `yaml:"receivers"`
+}
+
+// Receiver is an integration of a provisioned contact point.
+type Receiver struct {
+	UID                   string                 `yaml:"uid"`
+	Type                  string                 `yaml:"type"`
+	Settings              map[string]interface{} `yaml:"settings"`
+	DisableResolveMessage bool                   `yaml:"disableResolveMessage,omitempty"`
+}
+
+// Policy is the provisioned notification policy tree of an org, whose fields are the same as the API.
+type Policy struct {
+	OrgID int64
+	gapi.NotificationPolicyTree
+}
+
+// MarshalYAML implements the yaml.Marshaler interface for Policy.
+func (p Policy) MarshalYAML() (interface{}, error) {
+	p.Provenance = ""
+	return withOrgID(p.OrgID, p.NotificationPolicyTree)
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface for Policy.
+func (p *Policy) UnmarshalYAML(node *yaml.Node) error {
+	return withoutOrgID(node, &p.OrgID, &p.NotificationPolicyTree)
+}
+
+// MuteTiming is a provisioned mute timing, whose fields are the same as the API.
+type MuteTiming struct {
+	OrgID int64
+	gapi.MuteTiming
+}
+
+// MarshalYAML implements the yaml.Marshaler interface for MuteTiming.
+func (mt MuteTiming) MarshalYAML() (interface{}, error) {
+	mt.Provenance = ""
+	return withOrgID(mt.OrgID, mt.MuteTiming)
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface for MuteTiming.
+func (mt *MuteTiming) UnmarshalYAML(node *yaml.Node) error {
+	return withoutOrgID(node, &mt.OrgID, &mt.MuteTiming)
+}
+
+// Template is a provisioned message template.
+type Template struct {
+	OrgID    int64  `yaml:"orgId,omitempty"`
+	Name     string `yaml:"name"`
+	Template string `yaml:"template"`
+}
+
+// DeleteRule identifies an alert rule to delete.
+type DeleteRule struct {
+	OrgID int64  `yaml:"orgId,omitempty"`
+	UID   string `yaml:"uid"`
+}
+
+// DeleteContactPoint identifies a receiver of a contact point to delete.
+type DeleteContactPoint struct {
+	OrgID int64  `yaml:"orgId,omitempty"`
+	UID   string `yaml:"uid"`
+}
+
+// DeleteMuteTiming identifies a mute timing to delete.
+type DeleteMuteTiming struct {
+	OrgID int64  `yaml:"orgId,omitempty"`
+	Name  string `yaml:"name"`
+}
+
+// DeleteTemplate identifies a message template to delete.
+type DeleteTemplate struct {
+	OrgID int64  `yaml:"orgId,omitempty"`
+	Name  string `yaml:"name"`
+}
+
+// ParseAlerting parses an alerting provisioning file, or an alerting export of Grafana in YAML or JSON.
+func ParseAlerting(data []byte) (*AlertingFile, error) {
+	file := &AlertingFile{}
+	if err := yaml.Unmarshal(data, file); err != nil {
+		return nil, err
+	}
+	if err := file.validate(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (f *AlertingFile) validate() error {
+	if f.APIVersion > 1 {
+		return fmt.Errorf("unsupported apiVersion %d", f.APIVersion)
+	}
+
+	for _, group := range f.Groups {
+		if group.Name == "" {
+			return errors.New("rule group without name")
+		}
+		if group.Folder == "" {
+			return fmt.Errorf("rule group %s: folder is required", group.Name)
+		}
+		if _, err := parseInterval(group.Interval); err != nil {
+			return fmt.Errorf("rule group %s: %w", group.Name, err)
+		}
+		for _, rule := range group.Rules {
+			if rule.UID == "" || rule.Title == "" {
+				return fmt.Errorf("rule group %s: rules require a uid and a title", group.Name)
+			}
+		}
+	}
+	for _, cp := range f.ContactPoints {
+		if cp.Name == "" {
+			return errors.New("contact point without name")
+		}
+		for _, receiver := range cp.Receivers {
+			if receiver.UID == "" || receiver.Type == "" {
+				return fmt.Errorf("contact point %s: receivers require a uid and a type", cp.Name)
+			}
+		}
+	}
+	for _, mt := range f.MuteTimes {
+		if mt.Name == "" {
+			return errors.New("mute timing without name")
+		}
+	}
+	for _, template := range f.Templates {
+		if template.Name == "" {
+			return errors.New("template without name")
+		}
+	}
+	return nil
+}
+
+// Marshal returns the file as YAML.
+func (f *AlertingFile) Marshal() ([]byte, error) {
+	return marshal(f)
+}
+
+// NewRuleGroup converts a rule group of the API to a provisioned rule group, in the folder of the given title.
+func NewRuleGroup(group gapi.RuleGroup, folder string) RuleGroup {
+	result := RuleGroup{
+		Name:     group.Title,
+		Folder:   folder,
+		Interval: formatInterval(group.Interval),
+		Rules:    []AlertRule{},
+	}
+	for _, rule := range group.Rules {
+		result.OrgID = rule.OrgID
+		result.Rules = append(result.Rules, newAlertRule(rule))
+	}
+	return result
+}
+
+func newAlertRule(rule gapi.AlertRule) AlertRule {
+	result := AlertRule{
+		UID:          rule.UID,
+		Title:        rule.Title,
+		Condition:    rule.Condition,
+		Data:         []AlertQuery{},
+		NoDataState:  string(rule.NoDataState),
+		ExecErrState: string(rule.ExecErrState),
+		For:          rule.For,
+		Labels:       rule.Labels,
+		IsPaused:     rule.IsPaused,
+	}
+	for _, query := range rule.Data {
+		result.Data = append(result.Data, AlertQuery{*query})
+	}
+	for key, value := range rule.Annotations {
+		switch key {
+		case dashboardUIDAnnotation:
+			result.DashboardUID = value
+		case panelIDAnnotation:
+			result.PanelID, _ = strconv.ParseInt(value, 10, 64)
+		default:
+			if result.Annotations == nil {
+				result.Annotations = map[string]string{}
+			}
+			result.Annotations[key] = value
+		}
+	}
+	return result
+}
+
+// RuleGroup converts a provisioned rule group to a rule group of the API, in the folder of the given UID.
+func (g RuleGroup) RuleGroup(folderUID string) (gapi.RuleGroup, error) {
+	interval, err := parseInterval(g.Interval)
+	if err != nil {
+		return gapi.RuleGroup{}, err
+	}
+
+	result := gapi.RuleGroup{
+		Title:     g.Name,
+		FolderUID: folderUID,
+		Interval:  interval,
+		Rules:     []gapi.AlertRule{},
+	}
+	for _, rule := range g.Rules {
+		result.Rules = append(result.Rules, rule.alertRule(g, folderUID))
+	}
+	return result, nil
+}
+
+func (r AlertRule) alertRule(group RuleGroup, folderUID string) gapi.AlertRule {
+	result := gapi.AlertRule{
+		UID:          r.UID,
+		Title:        r.Title,
+		Condition:    r.Condition,
+		Data:         []*gapi.AlertQuery{},
+		NoDataState:  gapi.NoDataState(r.NoDataState),
+		ExecErrState: gapi.ExecErrState(r.ExecErrState),
+		For:          r.For,
+		Labels:       r.Labels,
+		IsPaused:     r.IsPaused,
+		OrgID:        group.OrgID,
+		FolderUID:    folderUID,
+		RuleGroup:    group.Name,
+	}
+	// Grafana defaults the states of provisioned rules.
+	if result.NoDataState == "" {
+		result.NoDataState = gapi.NoData
+	}
+	if result.ExecErrState == "" {
+		result.ExecErrState = gapi.ErrAlerting
+	}
+	for i := range r.Data {
+		query := r.Data[i].AlertQuery
+		result.Data = append(result.Data, &query)
+	}
+	if len(r.Annotations) > 0 || r.DashboardUID != "" {
+		result.Annotations = map[string]string{}
+		for key, value := range r.Annotations {
+			result.Annotations[key] = value
+		}
+	}
+	if r.DashboardUID != "" {
+		result.Annotations[dashboardUIDAnnotation] = r.DashboardUID
+		result.Annotations[panelIDAnnotation] = strconv.FormatInt(r.PanelID, 10)
+	}
+	return result
+}
+
+// NewContactPoints converts contact points of the API to provisioned contact points, grouping the contact points
+// of the same name as receivers.
+func NewContactPoints(contactPoints []gapi.ContactPoint) []ContactPoint {
+	result := []ContactPoint{}
+	index := map[string]int{}
+	for _, cp := range contactPoints {
+		i, exists := index[cp.Name]
+		if !exists {
+			i = len(result)
+			index[cp.Name] = i
+			result = append(result, ContactPoint{Name: cp.Name, Receivers: []Receiver{}})
+		}
+		result[i].Receivers = append(result[i].Receivers, Receiver{
+			UID:                   cp.UID,
+			Type:                  cp.Type,
+			Settings:              cp.Settings,
+			DisableResolveMessage: cp.DisableResolveMessage,
+		})
+	}
+	return result
+}
+
+// ContactPoints converts a provisioned contact point to the contact points of the API, one per receiver.
+func (cp ContactPoint) ContactPoints() []gapi.ContactPoint {
+	result := []gapi.ContactPoint{}
+	for _, receiver := range cp.Receivers {
+		result = append(result, gapi.ContactPoint{
+			UID:                   receiver.UID,
+			Name:                  cp.Name,
+			Type:                  receiver.Type,
+			Settings:              receiver.Settings,
+			DisableResolveMessage: receiver.DisableResolveMessage,
+		})
+	}
+	return result
+}
+
+// NewTemplate converts a message template of the API to a provisioned template.
+func NewTemplate(template gapi.AlertingMessageTemplate) Template {
+	return Template{Name: template.Name, Template: template.Template}
+}
+
+// ExportAlerting exports the alerting resources of the org of the client, using the export endpoints of Grafana.
+// Templates, which Grafana doesn't export, are fetched from the API. decrypt exports the secrets of contact
+// points instead of redacting them, which requires the admin role.
+func ExportAlerting(client *gapi.Client, decrypt bool) (*AlertingFile, error) {
+	file := &AlertingFile{APIVersion: 1}
+	for name, export := range map[string]func() ([]byte, error){
+		"alert rules": func() ([]byte, error) {
+			return client.ExportAlertRules(gapi.AlertRuleExportOptions{Format: gapi.ExportFormatYAML})
+		},
+		"contact points": func() ([]byte, error) {
+			return client.ExportContactPoints(gapi.ContactPointExportOptions{Format: gapi.ExportFormatYAML, Decrypt: decrypt})
+		},
+		"notification policy tree": func() ([]byte, error) {
+			return client.ExportNotificationPolicyTree(gapi.ExportFormatYAML)
+		},
+		"mute timings": func() ([]byte, error) {
+			return client.ExportMuteTimings(gapi.ExportFormatYAML)
+		},
+	} {
+		data, err := export()
+		// Grafana returns 404 when there are no alert rules to export.
+		if gapi.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", name, err)
+		}
+		exported, err := ParseAlerting(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the export of %s: %w", name, err)
+		}
+		file.Groups = append(file.Groups, exported.Groups...)
+		file.ContactPoints = append(file.ContactPoints, exported.ContactPoints...)
+		file.Policies = append(file.Policies, exported.Policies...)
+		file.MuteTimes = append(file.MuteTimes, exported.MuteTimes...)
+	}
+
+	templates, err := client.MessageTemplates()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch templates: %w", err)
+	}
+	for _, template := range templates {
+		file.Templates = append(file.Templates, NewTemplate(template))
+	}
+	return file, nil
+}
+
+// ApplyAlerting applies a provisioning file through the API, like Grafana does when starting. Resources are
+// created or updated in dependency order: templates, mute timings, contact points, policies, then rule groups,
+// whose missing folders are created. Deletions follow in the reverse order. Resources of other orgs are applied
+// with the client of their org.
+func ApplyAlerting(client *gapi.Client, file *AlertingFile) error {
+	if err := file.validate(); err != nil {
+		return err
+	}
+
+	for _, template := range file.Templates {
+		if err := orgClient(client, template.OrgID).SetMessageTemplate(template.Name, template.Template); err != nil {
+			return fmt.Errorf("failed to apply template %s: %w", template.Name, err)
+		}
+	}
+	for _, mt := range file.MuteTimes {
+		if err := applyMuteTiming(orgClient(client, mt.OrgID), mt.MuteTiming); err != nil {
+			return fmt.Errorf("failed to apply mute timing %s: %w", mt.Name, err)
+		}
+	}
+	for _, cp := range file.ContactPoints {
+		if err := applyContactPoint(orgClient(client, cp.OrgID), cp); err != nil {
+			return fmt.Errorf("failed to apply contact point %s: %w", cp.Name, err)
+		}
+	}
+	for _, policy := range file.Policies {
+		tree := policy.NotificationPolicyTree
+		if err := orgClient(client, policy.OrgID).SetNotificationPolicyTree(&tree); err != nil {
+			return fmt.Errorf("failed to apply the notification policy tree: %w", err)
+		}
+	}
+	for _, group := range file.Groups {
+		if err := applyRuleGroup(orgClient(client, group.OrgID), group); err != nil {
+			return fmt.Errorf("failed to apply rule group %s: %w", group.Name, err)
+		}
+	}
+
+	for _, rule := range file.DeleteRules {
+		if err := ignoreNotFound(orgClient(client, rule.OrgID).DeleteAlertRule(rule.UID)); err != nil {
+			return fmt.Errorf("failed to delete alert rule %s: %w", rule.UID, err)
+		}
+	}
+	for _, orgID := range file.ResetPolicies {
+		if err := orgClient(client, orgID).ResetNotificationPolicyTree(); err != nil {
+			return fmt.Errorf("failed to reset the notification policy tree: %w", err)
+		}
+	}
+	for _, cp := range file.DeleteContactPoints {
+		if err := ignoreNotFound(orgClient(client, cp.OrgID).DeleteContactPoint(cp.UID)); err != nil {
+			return fmt.Errorf("failed to delete contact point %s: %w", cp.UID, err)
+		}
+	}
+	for _, mt := range file.DeleteMuteTimes {
+		if err := ignoreNotFound(orgClient(client, mt.OrgID).DeleteMuteTiming(mt.Name)); err != nil {
+			return fmt.Errorf("failed to delete mute timing %s: %w", mt.Name, err)
+		}
+	}
+	for _, template := range file.DeleteTemplates {
+		if err := ignoreNotFound(orgClient(client, template.OrgID).DeleteMessageTemplate(template.Name)); err != nil {
+			return fmt.Errorf("failed to delete template %s: %w", template.Name, err)
+		}
+	}
+	return nil
+}
+
+func applyMuteTiming(client *gapi.Client, mt gapi.MuteTiming) error {
+	_, err := client.MuteTiming(mt.Name)
+	if gapi.IsNotFound(err) {
+		return client.NewMuteTiming(&mt)
+	}
+	if err != nil {
+		return err
+	}
+	return client.UpdateMuteTiming(&mt)
+}
+
+// applyContactPoint creates or updates the receivers of a contact point, which are identified by UID.
+func applyContactPoint(client *gapi.Client, cp ContactPoint) error {
+	existing, err := client.ContactPoints()
+	if err != nil {
+		return err
+	}
+	exists := map[string]bool{}
+	for _, receiver := range existing {
+		exists[receiver.UID] = true
+	}
+
+	for _, receiver := range cp.ContactPoints() {
+		receiver := receiver
+		if exists[receiver.UID] {
+			err = client.UpdateContactPoint(&receiver)
+		} else {
+			_, err = client.NewContactPoint(&receiver)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyRuleGroup sets a rule group, looking its folder up by title and creating it if it's missing.
+func applyRuleGroup(client *gapi.Client, group RuleGroup) error {
+	folders, err := client.Folders()
+	if err != nil {
+		return err
+	}
+	folderUID := ""
+	for _, folder := range folders {
+		if folder.Title == group.Folder {
+			folderUID = folder.UID
+			break
+		}
+	}
+	if folderUID == "" {
+		folder, err := client.NewFolder(group.Folder)
+		if err != nil {
+			return fmt.Errorf("failed to create folder %s: %w", group.Folder, err)
+		}
+		folderUID = folder.UID
+	}
+
+	ruleGroup, err := group.RuleGroup(folderUID)
+	if err != nil {
+		return err
+	}
+	return client.SetAlertRuleGroup(ruleGroup)
+}
+
+func ignoreNotFound(err error) error {
+	if gapi.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// formatInterval formats an interval of seconds like Grafana exports them, e.g. 1m.
+func formatInterval(seconds int64) string {
+	switch {
+	case seconds != 0 && seconds%3600 == 0:
+		return fmt.Sprintf("%dh", seconds/3600)
+	case seconds != 0 && seconds%60 == 0:
+		return fmt.Sprintf("%dm", seconds/60)
+	default:
+		return fmt.Sprintf("%ds", seconds)
+	}
+}
+
+// parseInterval parses an interval like 1m or 90s to seconds.
+func parseInterval(interval string) (int64, error) {
+	d, err := time.ParseDuration(interval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval %q: %w", interval, err)
+	}
+	if d <= 0 || d%time.Second != 0 {
+		return 0, fmt.Errorf("invalid interval %q: must be a positive number of seconds", interval)
+	}
+	return int64(d / time.Second), nil
+}
+
+// toYAML converts a value of the API to a generic value, whose keys are the JSON names of its fields.
+func toYAML(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]interface{}{}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// fromYAML decodes a YAML node into a value of the API, through its JSON representation.
+func fromYAML(node *yaml.Node, v interface{}) error {
+	var generic interface{}
+	if err := node.Decode(&generic); err != nil {
+		return err
+	}
+	data, err := json.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// withOrgID converts a value of the API to a generic value, adding the orgId field of provisioning files.
+func withOrgID(orgID int64, v interface{}) (map[string]interface{}, error) {
+	result, err := toYAML(v)
+	if err == nil && orgID != 0 {
+		result["orgId"] = orgID
+	}
+	return result, err
+}
+
+// withoutOrgID decodes a YAML node into a value of the API and the orgId field of provisioning files.
+func withoutOrgID(node *yaml.Node, orgID *int64, v interface{}) error {
+	org := struct {
+		OrgID int64 `yaml:"orgId"`
+	}{}
+	if err := node.Decode(&org); err != nil {
+		return err
+	}
+	*orgID = org.OrgID
+	return fromYAML(node, v)
+}
@@ -0,0 +1,306 @@
+package provisioning
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+	"github.com/grafana/grafana-api-golang-client/gapitest"
+)
+
+const alertingFile = `apiVersion: 1
+groups:
+  - orgId: 1
+    name: cpu
+    folder: Production
+    interval: 1m
+    rules:
+      - uid: high-cpu
+        title: High CPU
+        condition: B
+        data:
+          - refId: A
+            relativeTimeRange:
+              from: 600
+              to: 0
+            datasourceUid: prom
+            model:
+              expr: avg(rate(cpu_seconds_total[5m]))
+          - refId: B
+            datasourceUid: __expr__
+            model:
+              type: threshold
+              expression: A
+        dashboardUid: services
+        panelId: 2
+        noDataState: OK
+        execErrState: Error
+        for: 5m
+        annotations:
+          summary: CPU usage of {{ $labels.instance }} is high
+        labels:
+          severity: critical
+contactPoints:
+  - orgId: 1
+    name: ops
+    receivers:
+      - uid: ops-email
+        type: email
+        settings:
+          addresses: ops@example.com
+      - uid: ops-slack
+        type: slack
+        settings:
+          recipient: "#ops"
+        disableResolveMessage: true
+policies:
+  - orgId: 1
+    receiver: ops
+    group_by:
+      - alertname
+    routes:
+      - receiver: ops
+        object_matchers:
+          - - severity
+            - =
+            - critical
+        mute_time_intervals:
+          - weekends
+muteTimes:
+  - orgId: 1
+    name: weekends
+    time_intervals:
+      - weekdays:
+          - saturday
+          - sunday
+templates:
+  - orgId: 1
+    name: summary
+    template: '{{ define "summary" }}{{ .CommonLabels.alertname }}{{ end }}'
+`
+
+func TestParseAlerting(t *testing.T) {
+	file, err := ParseAlerting([]byte(alertingFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	group, err := file.Groups[0].RuleGroup("prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := group.Rules[0]
+	if group.Interval != 60 || rule.FolderUID != "prod" || rule.RuleGroup != "cpu" || rule.OrgID != 1 {
+		t.Errorf("unexpected rule group: %+v", group)
+	}
+	expectedAnnotations := map[string]string{
+		"summary":          "CPU usage of {{ $labels.instance }} is high",
+		"__dashboardUid__": "services",
+		"__panelId__":      "2",
+	}
+	if !reflect.DeepEqual(rule.Annotations, expectedAnnotations) {
+		t.Errorf("unexpected annotations: %v", rule.Annotations)
+	}
+	if len(rule.Data) != 2 || rule.Data[0].DatasourceUID != "prom" || rule.Data[0].RelativeTimeRange.From != 600 {
+		t.Errorf("unexpected queries: %+v", rule.Data)
+	}
+	if rule.ExecErrState != gapi.ErrError || rule.NoDataState != gapi.NoDataOk || rule.For != "5m" {
+		t.Errorf("unexpected rule: %+v", rule)
+	}
+	if converted := NewRuleGroup(group, "Production"); !reflect.DeepEqual(converted, file.Groups[0]) {
+		t.Errorf("expected:\n%#v\ngot:\n%#v", file.Groups[0], converted)
+	}
+
+	contactPoints := file.ContactPoints[0].ContactPoints()
+	if len(contactPoints) != 2 || contactPoints[1].Name != "ops" || !contactPoints[1].DisableResolveMessage {
+		t.Errorf("unexpected contact points: %+v", contactPoints)
+	}
+	if converted := NewContactPoints(contactPoints); len(converted) != 1 || !reflect.DeepEqual(converted[0].Receivers, file.ContactPoints[0].Receivers) {
+		t.Errorf("unexpected converted contact points: %+v", converted)
+	}
+
+	policy := file.Policies[0]
+	expectedMatchers := gapi.Matchers{{Type: gapi.MatchEqual, Name: "severity", Value: "critical"}}
+	if policy.OrgID != 1 || policy.Receiver != "ops" || !reflect.DeepEqual(policy.Routes[0].ObjectMatchers, expectedMatchers) {
+		t.Errorf("unexpected policy: %+v", policy)
+	}
+	mt := file.MuteTimes[0]
+	if mt.OrgID != 1 || mt.Name != "weekends" || len(mt.TimeIntervals[0].Weekdays) != 2 {
+		t.Errorf("unexpected mute timing: %+v", mt)
+	}
+
+	// Marshaling and parsing the file again gives the same file.
+	data, err := file.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseAlerting(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, file) {
+		t.Errorf("expected marshaled file to be parsed back; got:\n%s", data)
+	}
+}
+
+func TestParseAlertingInvalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"api version":       "apiVersion: 2",
+		"folder":            "groups: [{name: cpu, interval: 1m}]",
+		"interval":          "groups: [{name: cpu, folder: Production, interval: 1500ms}]",
+		"rule uid":          "groups: [{name: cpu, folder: Production, interval: 1m, rules: [{title: High CPU}]}]",
+		"receiver type":     "contactPoints: [{name: ops, receivers: [{uid: ops-email}]}]",
+		"mute timing name":  "muteTimes: [{time_intervals: []}]",
+		"malformed matcher": "policies: [{receiver: ops, routes: [{object_matchers: [[severity, '==', critical]]}]}]",
+	} {
+		if _, err := ParseAlerting([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestApplyAlerting(t *testing.T) {
+	server := gapitest.NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	file, err := ParseAlerting([]byte(alertingFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Applying the file twice updates the resources created the first time.
+	for i := 0; i < 2; i++ {
+		if err := ApplyAlerting(client, file); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	folders, err := client.Folders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(folders) != 1 || folders[0].Title != "Production" {
+		t.Fatalf("expected the folder of the rule group to be created; got: %+v", folders)
+	}
+	group, err := client.AlertRuleGroup(folders[0].UID, "cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(group.Rules) != 1 || group.Rules[0].UID != "high-cpu" || group.Rules[0].Annotations["__panelId__"] != "2" {
+		t.Errorf("unexpected rule group: %+v", group)
+	}
+	contactPoints, err := client.ContactPointsByName("ops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contactPoints) != 2 {
+		t.Errorf("expected 2 contact points; got: %+v", contactPoints)
+	}
+	tree, err := client.NotificationPolicyTree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.Routes) != 1 || tree.Routes[0].MuteTimeIntervals[0] != "weekends" {
+		t.Errorf("unexpected policy tree: %+v", tree)
+	}
+	if _, err := client.MessageTemplate("summary"); err != nil {
+		t.Errorf("expected the template to be created; got: %v", err)
+	}
+
+	deletions := &AlertingFile{
+		APIVersion:          1,
+		DeleteRules:         []DeleteRule{{UID: "high-cpu"}, {UID: "missing"}},
+		ResetPolicies:       []int64{1},
+		DeleteContactPoints: []DeleteContactPoint{{UID: "ops-slack"}},
+		DeleteMuteTimes:     []DeleteMuteTiming{{Name: "weekends"}},
+		DeleteTemplates:     []DeleteTemplate{{Name: "summary"}},
+	}
+	if err := ApplyAlerting(client, deletions); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.AlertRule("high-cpu"); !gapi.IsNotFound(err) {
+		t.Errorf("expected the rule to be deleted; got: %v", err)
+	}
+	if _, err := client.ContactPoint("ops-slack"); !gapi.IsNotFound(err) {
+		t.Errorf("expected the contact point to be deleted; got: %v", err)
+	}
+	if _, err := client.MuteTiming("weekends"); !gapi.IsNotFound(err) {
+		t.Errorf("expected the mute timing to be deleted; got: %v", err)
+	}
+	if _, err := client.MessageTemplate("summary"); !gapi.IsNotFound(err) {
+		t.Errorf("expected the template to be deleted; got: %v", err)
+	}
+}
+
+func TestExportAlerting(t *testing.T) {
+	exports := map[string]string{
+		"/api/v1/provisioning/alert-rules/export": `apiVersion: 1
+groups:
+  - orgId: 1
+    name: cpu
+    folder: Production
+    interval: 5m
+    rules:
+      - uid: high-cpu
+        title: High CPU
+        condition: A
+        data:
+          - refId: A
+            datasourceUid: prom
+            model:
+              expr: up == 0
+`,
+		"/api/v1/provisioning/contact-points/export": `apiVersion: 1
+contactPoints:
+  - orgId: 1
+    name: ops
+    receivers:
+      - uid: ops-email
+        type: email
+        settings:
+          addresses: ops@example.com
+`,
+		"/api/v1/provisioning/policies/export": `apiVersion: 1
+policies:
+  - orgId: 1
+    receiver: ops
+`,
+		"/api/v1/provisioning/templates": `[{"name": "summary", "template": "{{ define \"summary\" }}{{ end }}"}]`,
+	}
+	var decrypt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, exists := exports[r.URL.Path]
+		if !exists {
+			http.Error(w, `{"message": "not found"}`, http.StatusNotFound)
+			return
+		}
+		if r.URL.Path == "/api/v1/provisioning/contact-points/export" {
+			decrypt = r.URL.Query().Get("decrypt")
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+	client, err := gapi.New(server.URL, gapi.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := ExportAlerting(client, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Groups) != 1 || file.Groups[0].Interval != "5m" || len(file.ContactPoints) != 1 || len(file.Policies) != 1 {
+		t.Errorf("unexpected export: %+v", file)
+	}
+	if len(file.MuteTimes) != 0 {
+		t.Errorf("expected missing exports to be skipped; got: %+v", file.MuteTimes)
+	}
+	if len(file.Templates) != 1 || file.Templates[0].Name != "summary" {
+		t.Errorf("unexpected templates: %+v", file.Templates)
+	}
+	if decrypt != "true" {
+		t.Errorf("expected the secrets of contact points to be exported; got decrypt=%q", decrypt)
+	}
+}
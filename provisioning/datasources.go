@@ -0,0 +1,282 @@
+// Package provisioning converts between the API resources of the client and the files Grafana provisions
+// resources from, and applies these files through the API. It allows moving between file provisioning and
+// API provisioning without rewriting configurations.
+package provisioning
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+	"gopkg.in/yaml.v3"
+)
+
+// DataSourceFile is a data source provisioning file, e.g. provisioning/datasources/datasources.yaml.
+type DataSourceFile struct {
+	APIVersion        int64              `yaml:"apiVersion"`
+	DeleteDataSources []DeleteDataSource `yaml:"deleteDatasources,omitempty"`
+	DataSources       []DataSource       `yaml:"datasources"`
+}
+
+// DeleteDataSource identifies a data source to delete.
+type DeleteDataSource struct {
+	Name  string `yaml:"name"`
+	OrgID int64  `yaml:"orgId,omitempty"`
+}
+
+// DataSource is a provisioned data source. OrgID defaults to the org of the client applying the file.
+type DataSource struct {
+	OrgID           int64                  `yaml:"orgId,omitempty"`
+	Version         int64                  `yaml:"version,omitempty"`
+	Name            string                 `yaml:"name"`
+	Type            string                 `yaml:"type"`
+	Access          string                 `yaml:"access,omitempty"`
+	UID             string                 `yaml:"uid,omitempty"`
+	URL             string                 `yaml:"url,omitempty"`
+	User            string                 `yaml:"user,omitempty"`
+	Database        string                 `yaml:"database,omitempty"`
+	BasicAuth       bool                   `yaml:"basicAuth,omitempty"`
+	BasicAuthUser   string                 `yaml:"basicAuthUser,omitempty"`
+	WithCredentials bool                   `yaml:"withCredentials,omitempty"`
+	IsDefault       bool                   `yaml:"isDefault,omitempty"`
+	JSONData        map[string]interface{} `yaml:"jsonData,omitempty"`
+	SecureJSONData  map[string]string      `yaml:"secureJsonData,omitempty"`
+	// Editable allows editing the data source in the UI. It doesn't apply to data sources created through the API,
+	// which are always editable.
+	Editable bool `yaml:"editable,omitempty"`
+}
+
+// ParseDataSources parses a data source provisioning file. Like Grafana, it replaces the environment variables
+// referenced by the string values of the file, as $VAR or ${VAR}, $$ being a literal $. getenv looks them up,
+// e.g. os.Getenv.
+func ParseDataSources(data []byte, getenv func(string) string) (*DataSourceFile, error) {
+	file := &DataSourceFile{}
+	if err := decode(data, getenv, file); err != nil {
+		return nil, err
+	}
+	if err := file.validate(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (f *DataSourceFile) validate() error {
+	// Grafana still reads the files of version 0, whose fields are the same.
+	if f.APIVersion > 1 {
+		return fmt.Errorf("unsupported apiVersion %d", f.APIVersion)
+	}
+
+	defaults := map[int64]string{}
+	for _, ds := range f.DataSources {
+		if ds.Name == "" {
+			return errors.New("data source without name")
+		}
+		if ds.Type == "" {
+			return fmt.Errorf("data source %s: type is required", ds.Name)
+		}
+		if !ds.IsDefault {
+			continue
+		}
+		if other, exists := defaults[ds.OrgID]; exists {
+			return fmt.Errorf("data sources %s and %s are both the default of org %d", other, ds.Name, ds.OrgID)
+		}
+		defaults[ds.OrgID] = ds.Name
+	}
+	for _, ds := range f.DeleteDataSources {
+		if ds.Name == "" {
+			return errors.New("data source to delete without name")
+		}
+	}
+	return nil
+}
+
+// Marshal returns the file as YAML. Dollar signs are escaped, so that parsing the file gives the same values.
+func (f *DataSourceFile) Marshal() ([]byte, error) {
+	return encode(f)
+}
+
+// NewDataSourceFile returns the provisioning file of data sources fetched from the API. Their secrets aren't
+// returned by the API: secureJsonData has to be filled in, e.g. with references to environment variables.
+func NewDataSourceFile(dataSources []*gapi.DataSource) *DataSourceFile {
+	file := &DataSourceFile{APIVersion: 1, DataSources: []DataSource{}}
+	for _, ds := range dataSources {
+		file.DataSources = append(file.DataSources, FromDataSource(ds))
+	}
+	return file
+}
+
+// FromDataSource converts a data source of the API to a provisioned data source.
+func FromDataSource(ds *gapi.DataSource) DataSource {
+	result := DataSource{
+		OrgID:           ds.OrgID,
+		Name:            ds.Name,
+		Type:            ds.Type,
+		Access:          ds.Access,
+		UID:             ds.UID,
+		URL:             ds.URL,
+		User:            ds.User,
+		Database:        ds.Database,
+		BasicAuth:       ds.BasicAuth,
+		BasicAuthUser:   ds.BasicAuthUser,
+		WithCredentials: ds.WithCredentials,
+		IsDefault:       ds.IsDefault,
+		JSONData:        ds.JSONData,
+		Editable:        !ds.ReadOnly,
+	}
+	if len(ds.SecureJSONData) > 0 {
+		result.SecureJSONData = map[string]string{}
+		for key, value := range ds.SecureJSONData {
+			result.SecureJSONData[key] = fmt.Sprint(value)
+		}
+	}
+	return result
+}
+
+// DataSource converts a provisioned data source to a data source of the API.
+func (ds DataSource) DataSource() *gapi.DataSource {
+	result := &gapi.DataSource{
+		OrgID:           ds.OrgID,
+		Name:            ds.Name,
+		Type:            ds.Type,
+		Access:          ds.Access,
+		UID:             ds.UID,
+		URL:             ds.URL,
+		User:            ds.User,
+		Database:        ds.Database,
+		BasicAuth:       ds.BasicAuth,
+		BasicAuthUser:   ds.BasicAuthUser,
+		WithCredentials: ds.WithCredentials,
+		IsDefault:       ds.IsDefault,
+		JSONData:        ds.JSONData,
+	}
+	if result.Access == "" {
+		result.Access = "proxy"
+	}
+	if len(ds.SecureJSONData) > 0 {
+		result.SecureJSONData = map[string]interface{}{}
+		for key, value := range ds.SecureJSONData {
+			result.SecureJSONData[key] = value
+		}
+	}
+	return result
+}
+
+// ApplyDataSources applies a provisioning file through the API, like Grafana does when starting: it deletes
+// the data sources of deleteDatasources, then creates or updates the data sources. Data sources of other orgs
+// are applied with the client of their org.
+func ApplyDataSources(client *gapi.Client, file *DataSourceFile) error {
+	if err := file.validate(); err != nil {
+		return err
+	}
+
+	for _, ds := range file.DeleteDataSources {
+		err := orgClient(client, ds.OrgID).DeleteDataSourceByName(ds.Name)
+		if err != nil && !gapi.IsNotFound(err) {
+			return fmt.Errorf("failed to delete data source %s: %w", ds.Name, err)
+		}
+	}
+
+	for _, ds := range file.DataSources {
+		if err := applyDataSource(orgClient(client, ds.OrgID), ds.DataSource()); err != nil {
+			return fmt.Errorf("failed to apply data source %s: %w", ds.Name, err)
+		}
+	}
+	return nil
+}
+
+// applyDataSource creates or updates a data source. Like Grafana, it looks data sources up by name, then by UID
+// to rename them.
+func applyDataSource(client *gapi.Client, ds *gapi.DataSource) error {
+	existing, err := client.DataSourceByName(ds.Name)
+	if gapi.IsNotFound(err) && ds.UID != "" {
+		existing, err = client.DataSourceByUID(ds.UID)
+	}
+	if gapi.IsNotFound(err) {
+		_, err = client.NewDataSource(ds)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	ds.ID = existing.ID
+	if ds.UID == "" {
+		ds.UID = existing.UID
+	}
+	ds.OrgID = existing.OrgID
+	return client.UpdateDataSource(ds)
+}
+
+// orgClient returns a client for an org, or the client itself for the default org.
+func orgClient(client *gapi.Client, orgID int64) *gapi.Client {
+	if orgID == 0 {
+		return client
+	}
+	return client.WithOrgID(orgID)
+}
+
+// decode decodes a YAML document, replacing the environment variables referenced by its strings.
+func decode(data []byte, getenv func(string) string, v interface{}) error {
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(data, root); err != nil {
+		return err
+	}
+	interpolate(root, getenv)
+	return root.Decode(v)
+}
+
+// encode encodes a value as YAML, escaping the dollar signs of its strings.
+func encode(v interface{}) ([]byte, error) {
+	root := &yaml.Node{}
+	if err := root.Encode(v); err != nil {
+		return nil, err
+	}
+	walkValues(root, func(node *yaml.Node) {
+		node.Value = strings.ReplaceAll(node.Value, "$", "$$")
+	})
+	return marshal(root)
+}
+
+// marshal encodes a value as YAML, indented by two spaces like the examples of Grafana.
+func marshal(v interface{}) ([]byte, error) {
+	b := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	err := encoder.Close()
+	return b.Bytes(), err
+}
+
+// walkValues calls fn for each string value containing a dollar sign, which may reference environment variables.
+func walkValues(node *yaml.Node, fn func(node *yaml.Node)) {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" && strings.Contains(node.Value, "$") {
+		fn(node)
+	}
+	for i, child := range node.Content {
+		// The keys of mappings aren't interpolated.
+		if node.Kind == yaml.MappingNode && i%2 == 0 {
+			continue
+		}
+		walkValues(child, fn)
+	}
+}
+
+func interpolate(node *yaml.Node, getenv func(string) string) {
+	walkValues(node, func(node *yaml.Node) {
+		node.Value = os.Expand(node.Value, func(name string) string {
+			if name == "$" {
+				return "$"
+			}
+			return getenv(name)
+		})
+		// Unquoted values are resolved again, so that e.g. orgId: $ORG_ID is decoded as a number.
+		if node.Style == 0 {
+			node.Tag = ""
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package provisioning
+
+import (
+	"reflect"
+	"testing"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+	"github.com/grafana/grafana-api-golang-client/gapitest"
+)
+
+const dataSourceFile = `apiVersion: 1
+
+deleteDatasources:
+  - name: Graphite
+    orgId: 1
+
+datasources:
+  - name: Prometheus
+    type: prometheus
+    uid: prom
+    url: http://${PROMETHEUS_HOST}:9090
+    isDefault: true
+    jsonData:
+      httpMethod: POST
+      timeInterval: $INTERVAL
+    secureJsonData:
+      httpHeaderValue1: Bearer $TOKEN
+      password: "$$ecret"
+  - name: Loki
+    type: loki
+    orgId: $ORG_ID
+    url: http://loki:3100
+`
+
+var env = map[string]string{
+	"PROMETHEUS_HOST": "prometheus",
+	"INTERVAL":        "30s",
+	"TOKEN":           "abc",
+	"ORG_ID":          "1",
+}
+
+func TestParseDataSources(t *testing.T) {
+	file, err := ParseDataSources([]byte(dataSourceFile), func(key string) string { return env[key] })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &DataSourceFile{
+		APIVersion:        1,
+		DeleteDataSources: []DeleteDataSource{{Name: "Graphite", OrgID: 1}},
+		DataSources: []DataSource{
+			{
+				Name:           "Prometheus",
+				Type:           "prometheus",
+				UID:            "prom",
+				URL:            "http://prometheus:9090",
+				IsDefault:      true,
+				JSONData:       map[string]interface{}{"httpMethod": "POST", "timeInterval": "30s"},
+				SecureJSONData: map[string]string{"httpHeaderValue1": "Bearer abc", "password": "$ecret"},
+			},
+			{Name: "Loki", Type: "loki", OrgID: 1, URL: "http://loki:3100"},
+		},
+	}
+	if !reflect.DeepEqual(file, expected) {
+		t.Errorf("expected:\n%#v\ngot:\n%#v", expected, file)
+	}
+
+	// Marshaling and parsing the file again gives the same file, without the references to variables.
+	data, err := file.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseDataSources(data, func(string) string { return "" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, expected) {
+		t.Errorf("expected marshaled file to be parsed back; got:\n%s", data)
+	}
+}
+
+func TestParseDataSourcesInvalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"version":  "apiVersion: 2\ndatasources: []",
+		"name":     "apiVersion: 1\ndatasources:\n  - type: loki",
+		"type":     "apiVersion: 1\ndatasources:\n  - name: Loki",
+		"defaults": "apiVersion: 1\ndatasources:\n  - {name: A, type: loki, isDefault: true}\n  - {name: B, type: loki, isDefault: true}",
+		"yaml":     "datasources: [",
+	} {
+		if _, err := ParseDataSources([]byte(data), func(string) string { return "" }); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestApplyDataSources(t *testing.T) {
+	server := gapitest.NewServer()
+	defer server.Close()
+	client := server.Client()
+	if _, err := client.NewDataSource(&gapi.DataSource{Name: "Graphite", Type: "graphite"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.NewDataSource(&gapi.DataSource{UID: "prom", Name: "Old Prometheus", Type: "prometheus"}); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := ParseDataSources([]byte(dataSourceFile), func(key string) string { return env[key] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Applying the file twice updates the data sources it created.
+	for i := 0; i < 2; i++ {
+		if err := ApplyDataSources(client, file); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dataSources, err := client.DataSources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dataSources) != 2 {
+		t.Fatalf("expected the 2 data sources of the file; got %d", len(dataSources))
+	}
+	prometheus, err := client.DataSourceByUID("prom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prometheus.Name != "Prometheus" || prometheus.URL != "http://prometheus:9090" || prometheus.JSONData["httpMethod"] != "POST" {
+		t.Errorf("expected the data source to be renamed and updated; got: %+v", prometheus)
+	}
+
+	// The data sources of the API can be written back to a file.
+	exported := NewDataSourceFile(dataSources)
+	if len(exported.DataSources) != 2 || exported.DataSources[0].Name != "Loki" || !exported.DataSources[0].Editable {
+		t.Errorf("unexpected file: %+v", exported)
+	}
+}
@@ -0,0 +1,54 @@
+package reconcile
+
+import (
+	"fmt"
+)
+
+// ApplyError is returned by Plan.Apply when an action fails, after rolling back the actions already applied.
+type ApplyError struct {
+	// Action is the action which failed.
+	Action Action
+	Err    error
+	// RolledBack is the number of actions rolled back.
+	RolledBack int
+	// RollbackErrors are the errors of the actions which couldn't be rolled back.
+	RollbackErrors []error
+}
+
+func (e *ApplyError) Error() string {
+	msg := fmt.Sprintf("failed to %s: %v (rolled back %d actions", e.Action, e.Err, e.RolledBack)
+	if len(e.RollbackErrors) > 0 {
+		msg += fmt.Sprintf(", %d failed to roll back: %v", len(e.RollbackErrors), e.RollbackErrors)
+	}
+	return msg + ")"
+}
+
+func (e *ApplyError) Unwrap() error {
+	return e.Err
+}
+
+// Apply applies the actions of the plan in order. If an action fails, the actions already applied are rolled
+// back in the reverse order, and an *ApplyError is returned. Rolling back can't restore the secrets of updated
+// or deleted data sources and contact points, nor the contents of deleted folders.
+//
+// The plan should be applied once, soon after being computed: the org isn't locked in between.
+func (p *Plan) Apply() error {
+	for i, action := range p.Actions {
+		err := action.apply()
+		if err == nil {
+			continue
+		}
+
+		applyErr := &ApplyError{Action: action, Err: err}
+		for j := i - 1; j >= 0; j-- {
+			applied := p.Actions[j]
+			if err := applied.rollback(); err != nil {
+				applyErr.RollbackErrors = append(applyErr.RollbackErrors, fmt.Errorf("%s: %w", applied, err))
+				continue
+			}
+			applyErr.RolledBack++
+		}
+		return applyErr
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+// Package reconcile brings the resources of a Grafana org to a desired state, like Terraform: it computes
+// a plan of the actions creating, updating and deleting folders, data sources, mute timings, contact points,
+// alert rule groups and dashboards, which can be reviewed before being applied.
+//
+//	plan, err := reconcile.NewPlan(client, desired, reconcile.Options{})
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Println(plan)
+//	err = plan.Apply()
+//
+// Resources are identified by their UID, except mute timings which are identified by name, and rule groups
+// by folder and title. Resources which aren't in the desired state are left alone, unless pruning.
+//
+// Some fields can't be compared, and are only set when something else changes: the secrets of data sources,
+// and the secret settings of contact points, which Grafana redacts.
+package reconcile
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+// State is the desired state of the resources of an org.
+type State struct {
+	// Folders are identified by UID. Nested folders aren't supported.
+	Folders []gapi.Folder
+	// DataSources are identified by UID.
+	DataSources []gapi.DataSource
+	// MuteTimings are identified by name.
+	MuteTimings []gapi.MuteTiming
+	// ContactPoints are identified by UID.
+	ContactPoints []gapi.ContactPoint
+	// RuleGroups are identified by folder UID and title. Their rules should have UIDs: the ones
+	// which don't are matched by title.
+	RuleGroups []gapi.RuleGroup
+	// Dashboards are identified by the UID of their model.
+	Dashboards []gapi.Dashboard
+}
+
+// Options configures the computation of a plan.
+type Options struct {
+	// Prune deletes the folders, data sources, mute timings and contact points which aren't in the desired
+	// state, as well as the dashboards and rule groups of its folders which aren't. Provisioned data sources
+	// and contact points used by notification policies aren't deleted. Folders holding dashboards or alert
+	// rules aren't deleted either, unless PruneNonEmptyFolders is set.
+	Prune bool
+	// PruneNonEmptyFolders lets Prune delete the folders holding dashboards or alert rules, which Grafana deletes
+	// along with them. The plan lists them as the contents of the folders.
+	PruneNonEmptyFolders bool
+}
+
+// Operations of Action.
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// Kinds of resources.
+const (
+	KindFolder       = "folder"
+	KindDataSource   = "datasource"
+	KindMuteTiming   = "mute-timing"
+	KindContactPoint = "contact-point"
+	KindRuleGroup    = "rule-group"
+	KindDashboard    = "dashboard"
+)
+
+// Action is a change of a plan.
+type Action struct {
+	Op   string
+	Kind string
+	// Name identifies the resource: its UID, its name for mute timings, or its folder UID and title
+	// for rule groups.
+	Name string
+	// Changes are the fields changed by updates, as JSON pointers.
+	Changes []string
+	// Contents are the resources deleted along with a folder, e.g. "dashboard services".
+	Contents []string
+
+	apply    func() error
+	rollback func() error
+}
+
+func (a Action) String() string {
+	return fmt.Sprintf("%s %s %s", a.Op, a.Kind, a.Name)
+}
+
+// Plan is the list of actions bringing an org to a desired state, in the order they are applied.
+// Resources are created and updated before the resources which depend on them, and deleted after.
+type Plan struct {
+	Actions []Action
+}
+
+// Empty reports whether the org is already in the desired state.
+func (p *Plan) Empty() bool {
+	return len(p.Actions) == 0
+}
+
+var opSymbols = map[string]string{OpCreate: "+", OpUpdate: "~", OpDelete: "-"}
+
+// String returns a human-readable description of the plan, like Terraform: one line per action prefixed with
+// "+", "~" or "-", followed by the changed fields of updates or the contents of deleted folders, and a summary.
+func (p *Plan) String() string {
+	if p.Empty() {
+		return "No changes."
+	}
+
+	b := &strings.Builder{}
+	counts := map[string]int{}
+	for _, action := range p.Actions {
+		counts[action.Op]++
+		fmt.Fprintf(b, "%s %s\n", opSymbols[action.Op], action)
+		for _, change := range action.Changes {
+			fmt.Fprintf(b, "    %s\n", change)
+		}
+		for _, content := range action.Contents {
+			fmt.Fprintf(b, "    contains %s\n", content)
+		}
+	}
+	fmt.Fprintf(b, "\nPlan: %d to create, %d to update, %d to delete.", counts[OpCreate], counts[OpUpdate], counts[OpDelete])
+	return b.String()
+}
+
+type planner struct {
+	client  *gapi.Client
+	desired State
+	opts    Options
+
+	// folders are the UIDs of the folders which exist or will be created.
+	folders map[string]bool
+	// managedFolders are the UIDs of the folders of the desired state.
+	managedFolders map[string]bool
+}
+
+// NewPlan compares the resources of the org of the client with the desired state,
+// and returns the actions needed to reconcile them.
+func NewPlan(client *gapi.Client, desired State, opts Options) (*Plan, error) {
+	p := &planner{
+		client:         client,
+		desired:        desired,
+		opts:           opts,
+		folders:        map[string]bool{},
+		managedFolders: map[string]bool{},
+	}
+
+	// Each step returns the creations and updates, and the deletions of a kind of resources.
+	steps := []func() ([]Action, []Action, error){
+		p.planFolders,
+		p.planDataSources,
+		p.planMuteTimings,
+		p.planContactPoints,
+		p.planRuleGroups,
+		p.planDashboards,
+	}
+	var upserts, deletes [][]Action
+	for _, step := range steps {
+		u, d, err := step()
+		if err != nil {
+			return nil, err
+		}
+		upserts = append(upserts, u)
+		deletes = append(deletes, d)
+	}
+
+	plan := &Plan{Actions: []Action{}}
+	for _, actions := range upserts {
+		plan.Actions = append(plan.Actions, actions...)
+	}
+	// Resources are deleted in the reverse order, dependents first.
+	for i := len(deletes) - 1; i >= 0; i-- {
+		plan.Actions = append(plan.Actions, deletes[i]...)
+	}
+	return plan, nil
+}
+
+// changes returns the fields which differ between the current and desired values of a resource,
+// as JSON pointers.
+func changes(current, desired interface{}) ([]string, error) {
+	currentObject, err := jsonObject(current)
+	if err != nil {
+		return nil, err
+	}
+	desiredObject, err := jsonObject(desired)
+	if err != nil {
+		return nil, err
+	}
+
+	// DiffDashboardModels compares any JSON objects.
+	diff, err := gapi.DiffDashboardModels(currentObject, desiredObject)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(diff))
+	for _, change := range diff {
+		paths = append(paths, change.Path)
+	}
+	return paths, nil
+}
+
+func jsonObject(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	object := map[string]interface{}{}
+	err = json.Unmarshal(data, &object)
+	return object, err
+}
+
+// checkUnique returns an error if a resource is listed twice in the desired state.
+func checkUnique(seen map[string]bool, kind, name string) error {
+	if name == "" {
+		return fmt.Errorf("invalid desired state: %s without identifier", kind)
+	}
+	if seen[name] {
+		return fmt.Errorf("invalid desired state: duplicate %s %s", kind, name)
+	}
+	seen[name] = true
+	return nil
+}
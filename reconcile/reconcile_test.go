@@ -0,0 +1,308 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+	"github.com/grafana/grafana-api-golang-client/gapitest"
+)
+
+func dataSource(url string) gapi.DataSource {
+	return gapi.DataSource{UID: "prom", Name: "Prometheus", Type: "prometheus", URL: url, Access: "proxy", IsDefault: true}
+}
+
+// desiredState has one resource of each kind, and the data source created by newServer with another URL.
+func desiredState() State {
+	return State{
+		Folders:     []gapi.Folder{{UID: "prod", Title: "Production"}},
+		DataSources: []gapi.DataSource{dataSource("http://prometheus:9090")},
+		MuteTimings: []gapi.MuteTiming{{Name: "weekends", TimeIntervals: []gapi.TimeInterval{
+			{Weekdays: []gapi.WeekdayRange{"saturday", "sunday"}},
+		}}},
+		ContactPoints: []gapi.ContactPoint{{UID: "slack", Name: "slack", Type: "slack", Settings: map[string]interface{}{"url": "http://slack"}}},
+		RuleGroups: []gapi.RuleGroup{{FolderUID: "prod", Title: "cpu", Interval: 60, Rules: []gapi.AlertRule{{
+			Title:     "High CPU",
+			Condition: "A",
+			Data:      []*gapi.AlertQuery{{RefID: "A", DatasourceUID: "prom", Model: map[string]interface{}{"expr": "cpu > 0.9"}}},
+		}}}},
+		Dashboards: []gapi.Dashboard{{FolderUID: "prod", Model: map[string]interface{}{"uid": "services", "title": "Services"}}},
+	}
+}
+
+func newServer(t *testing.T) *gapitest.Server {
+	t.Helper()
+
+	server := gapitest.NewServer()
+	client := server.Client()
+	if _, err := client.NewDataSource(&gapi.DataSource{UID: "prom", Name: "Prometheus", Type: "prometheus", URL: "http://localhost:9090"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.NewFolder("Old", "old"); err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+func TestPlan(t *testing.T) {
+	server := newServer(t)
+	defer server.Close()
+	client := server.Client()
+
+	plan, err := NewPlan(client, desiredState(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `+ create folder prod
+~ update datasource prom
+    /url
++ create mute-timing weekends
++ create contact-point slack
++ create rule-group prod/cpu
++ create dashboard services
+
+Plan: 5 to create, 1 to update, 0 to delete.`
+	if plan.String() != expected {
+		t.Errorf("expected plan:\n%s\ngot:\n%s", expected, plan)
+	}
+
+	if err := plan.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	dashboard, err := client.DashboardByUID("services")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dashboard.FolderUID != "prod" {
+		t.Errorf("expected the dashboard to be in folder prod; got: %q", dashboard.FolderUID)
+	}
+
+	// The org converged to the desired state.
+	plan, err = NewPlan(client, desiredState(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Empty() || plan.String() != "No changes." {
+		t.Errorf("expected no changes; got:\n%s", plan)
+	}
+
+	desired := desiredState()
+	desired.RuleGroups[0].Rules[0].For = "5m"
+	plan, err = NewPlan(client, desired, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Actions) != 1 || plan.Actions[0].Op != OpUpdate || strings.Join(plan.Actions[0].Changes, ",") != "/rules/0/for" {
+		t.Errorf("expected to update the rule group; got:\n%s", plan)
+	}
+}
+
+func TestPlanPrune(t *testing.T) {
+	server := newServer(t)
+	defer server.Close()
+	client := server.Client()
+	applyState(t, client, desiredState())
+	if _, err := client.NewDashboard(gapi.Dashboard{FolderUID: "prod", Model: map[string]interface{}{"uid": "extra", "title": "Extra"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The default contact point is used by the notification policies, and isn't deleted.
+	plan, err := NewPlan(client, desiredState(), Options{Prune: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Action{
+		{Op: OpDelete, Kind: KindDashboard, Name: "extra"},
+		{Op: OpDelete, Kind: KindFolder, Name: "old"},
+	}
+	if len(plan.Actions) != len(expected) {
+		t.Fatalf("expected %d actions; got:\n%s", len(expected), plan)
+	}
+	for i, action := range plan.Actions {
+		if action.String() != expected[i].String() {
+			t.Errorf("expected action %d to %s; got: %s", i, expected[i], action)
+		}
+	}
+
+	if err := plan.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.FolderByUID("old"); !gapi.IsNotFound(err) {
+		t.Errorf("expected folder old to be deleted; got: %v", err)
+	}
+}
+
+func TestPlanPruneNonEmptyFolders(t *testing.T) {
+	server := newServer(t)
+	defer server.Close()
+	client := server.Client()
+	applyState(t, client, desiredState())
+	if _, err := client.NewFolder("Team", "team"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.NewDashboard(gapi.Dashboard{FolderUID: "team", Model: map[string]interface{}{"uid": "team-overview", "title": "Overview"}}); err != nil {
+		t.Fatal(err)
+	}
+	group := desiredState().RuleGroups[0]
+	group.FolderUID = "team"
+	if err := client.SetAlertRuleGroup(group); err != nil {
+		t.Fatal(err)
+	}
+
+	// Folders holding dashboards or alert rules aren't pruned by default.
+	plan, err := NewPlan(client, desiredState(), Options{Prune: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Actions) != 1 || plan.Actions[0].String() != "delete folder old" {
+		t.Errorf("expected to only delete folder old; got:\n%s", plan)
+	}
+
+	plan, err = NewPlan(client, desiredState(), Options{Prune: true, PruneNonEmptyFolders: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `- delete folder old
+- delete folder team
+    contains dashboard team-overview
+    contains rule-group team/cpu
+
+Plan: 0 to create, 0 to update, 2 to delete.`
+	if plan.String() != expected {
+		t.Errorf("expected plan:\n%s\ngot:\n%s", expected, plan)
+	}
+	if err := plan.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.FolderByUID("team"); !gapi.IsNotFound(err) {
+		t.Errorf("expected folder team to be deleted; got: %v", err)
+	}
+}
+
+// applyState brings the org of the client to the desired state.
+func applyState(t *testing.T, client *gapi.Client, desired State) {
+	t.Helper()
+
+	plan, err := NewPlan(client, desired, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plan.Apply(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlanSecrets(t *testing.T) {
+	server := newServer(t)
+	defer server.Close()
+	client := server.Client()
+
+	ds := dataSource("http://prometheus:9090")
+	applyState(t, client, State{DataSources: []gapi.DataSource{ds}})
+
+	// Secrets aren't returned by the API: they're updated when they aren't set, then the plan converges.
+	ds.SecureJSONData = map[string]interface{}{"httpHeaderValue1": "token"}
+	desired := State{DataSources: []gapi.DataSource{ds}}
+	plan, err := NewPlan(client, desired, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Actions) != 1 || !reflect.DeepEqual(plan.Actions[0].Changes, []string{"/secureJsonFields/httpHeaderValue1"}) {
+		t.Fatalf("unexpected plan:\n%s", plan)
+	}
+	if err := plan.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	plan, err = NewPlan(client, desired, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Empty() {
+		t.Errorf("expected no changes; got:\n%s", plan)
+	}
+
+	// Updating other fields doesn't send the secrets which are set.
+	var sent []string
+	recording, err := gapi.New(server.URL, gapi.Config{CallMiddlewares: []gapi.CallMiddleware{
+		func(next gapi.CallHandler) gapi.CallHandler {
+			return func(ctx context.Context, call *gapi.Call) error {
+				if call.Method == "PUT" {
+					sent = append(sent, string(call.Body))
+				}
+				return next(ctx, call)
+			}
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	desired.DataSources[0].URL = "http://prometheus:9091"
+	plan, err = NewPlan(recording, desired, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Actions) != 1 || !reflect.DeepEqual(plan.Actions[0].Changes, []string{"/url"}) {
+		t.Fatalf("unexpected plan:\n%s", plan)
+	}
+	if err := plan.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || strings.Contains(sent[0], "secureJson") || !strings.Contains(sent[0], "http://prometheus:9091") {
+		t.Errorf("unexpected updates: %v", sent)
+	}
+}
+
+func TestApplyRollback(t *testing.T) {
+	server := newServer(t)
+	defer server.Close()
+	client := server.Client()
+	// Dashboards must have a title.
+	desired := State{
+		Folders:     []gapi.Folder{{UID: "prod", Title: "Production"}},
+		DataSources: []gapi.DataSource{dataSource("http://prometheus:9090")},
+		Dashboards:  []gapi.Dashboard{{Model: map[string]interface{}{"uid": "services"}}},
+	}
+	plan, err := NewPlan(client, desired, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = plan.Apply()
+	applyErr := &ApplyError{}
+	if !errors.As(err, &applyErr) {
+		t.Fatalf("expected an apply error; got: %v", err)
+	}
+	if applyErr.Action.Kind != KindDashboard || applyErr.RolledBack != 2 || len(applyErr.RollbackErrors) != 0 {
+		t.Errorf("unexpected apply error: %v", err)
+	}
+
+	if _, err := client.FolderByUID("prod"); !gapi.IsNotFound(err) {
+		t.Errorf("expected the folder creation to be rolled back; got: %v", err)
+	}
+	ds, err := client.DataSourceByUID("prom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ds.URL != "http://localhost:9090" {
+		t.Errorf("expected the data source update to be rolled back; got URL %s", ds.URL)
+	}
+}
+
+func TestPlanInvalid(t *testing.T) {
+	server := newServer(t)
+	defer server.Close()
+
+	for name, desired := range map[string]State{
+		"data source without UID":   {DataSources: []gapi.DataSource{{Name: "Loki"}}},
+		"duplicate folder":          {Folders: []gapi.Folder{{UID: "prod"}, {UID: "prod"}}},
+		"dashboard without UID":     {Dashboards: []gapi.Dashboard{{Model: map[string]interface{}{"title": "Services"}}}},
+		"missing folder":            {Dashboards: []gapi.Dashboard{{FolderUID: "prod", Model: map[string]interface{}{"uid": "services"}}}},
+		"rule group without folder": {RuleGroups: []gapi.RuleGroup{{Title: "cpu"}}},
+	} {
+		if _, err := NewPlan(server.Client(), desired, Options{}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
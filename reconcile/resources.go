@@ -0,0 +1,595 @@
+package reconcile
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	gapi "github.com/grafana/grafana-api-golang-client"
+)
+
+// redacted replaces the secret settings of contact points returned by Grafana.
+const redacted = "[REDACTED]"
+
+func (p *planner) planFolders() ([]Action, []Action, error) {
+	current, err := p.client.Folders()
+	if err != nil {
+		return nil, nil, err
+	}
+	existing := map[string]gapi.Folder{}
+	for _, folder := range current {
+		existing[folder.UID] = folder
+		p.folders[folder.UID] = true
+	}
+
+	var upserts []Action
+	for _, folder := range p.desired.Folders {
+		folder := folder
+		if err := checkUnique(p.managedFolders, KindFolder, folder.UID); err != nil {
+			return nil, nil, err
+		}
+		p.folders[folder.UID] = true
+
+		old, exists := existing[folder.UID]
+		switch {
+		case !exists:
+			upserts = append(upserts, Action{
+				Op: OpCreate, Kind: KindFolder, Name: folder.UID,
+				apply: func() error {
+					_, err := p.client.NewFolder(folder.Title, folder.UID)
+					return err
+				},
+				rollback: func() error { return p.client.DeleteFolder(folder.UID) },
+			})
+		case old.Title != folder.Title:
+			upserts = append(upserts, Action{
+				Op: OpUpdate, Kind: KindFolder, Name: folder.UID, Changes: []string{"/title"},
+				apply:    func() error { return p.client.UpdateFolder(folder.UID, folder.Title) },
+				rollback: func() error { return p.client.UpdateFolder(old.UID, old.Title) },
+			})
+		}
+	}
+
+	var deletes []Action
+	if p.opts.Prune {
+		contents, err := p.folderContents()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, folder := range current {
+			folder := folder
+			if p.managedFolders[folder.UID] {
+				continue
+			}
+			if len(contents[folder.UID]) > 0 && !p.opts.PruneNonEmptyFolders {
+				continue
+			}
+			deletes = append(deletes, Action{
+				Op: OpDelete, Kind: KindFolder, Name: folder.UID, Contents: contents[folder.UID],
+				apply: func() error { return p.client.DeleteFolder(folder.UID) },
+				// The contents of the folder are lost.
+				rollback: func() error {
+					_, err := p.client.NewFolder(folder.Title, folder.UID)
+					return err
+				},
+			})
+		}
+	}
+	return upserts, deletes, nil
+}
+
+// folderContents returns the dashboards and rule groups of each folder, which are deleted along with it, sorted.
+func (p *planner) folderContents() (map[string][]string, error) {
+	contents := map[string][]string{}
+	err := p.client.ForEachDashboard(gapi.PageOptions{}, func(result gapi.FolderDashboardSearchResponse) error {
+		if result.Type == "dash-db" && result.FolderUID != "" {
+			contents[result.FolderUID] = append(contents[result.FolderUID], KindDashboard+" "+result.UID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := p.client.AlertRules()
+	if err != nil {
+		return nil, err
+	}
+	groups := map[string]bool{}
+	for _, rule := range rules {
+		name := ruleGroupName(rule.FolderUID, rule.RuleGroup)
+		if !groups[name] {
+			groups[name] = true
+			contents[rule.FolderUID] = append(contents[rule.FolderUID], KindRuleGroup+" "+name)
+		}
+	}
+
+	for _, resources := range contents {
+		sort.Strings(resources)
+	}
+	return contents, nil
+}
+
+// comparableDataSource clears the fields of a data source which are set by Grafana, or never returned.
+func comparableDataSource(ds gapi.DataSource) gapi.DataSource {
+	ds.ID = 0
+	ds.OrgID = 0
+	ds.ReadOnly = false
+	ds.Password = ""
+	ds.BasicAuthPassword = ""
+	ds.SecureJSONData = nil
+	ds.SecureJSONFields = nil
+	return ds
+}
+
+func (p *planner) planDataSources() ([]Action, []Action, error) {
+	current, err := p.client.DataSources()
+	if err != nil {
+		return nil, nil, err
+	}
+	existing := map[string]bool{}
+	for _, ds := range current {
+		existing[ds.UID] = true
+	}
+
+	var upserts []Action
+	seen := map[string]bool{}
+	for _, ds := range p.desired.DataSources {
+		ds := ds
+		if err := checkUnique(seen, KindDataSource, ds.UID); err != nil {
+			return nil, nil, err
+		}
+
+		if !existing[ds.UID] {
+			var id int64
+			upserts = append(upserts, Action{
+				Op: OpCreate, Kind: KindDataSource, Name: ds.UID,
+				apply: func() error {
+					var err error
+					id, err = p.client.NewDataSource(&ds)
+					return err
+				},
+				rollback: func() error { return p.client.DeleteDataSource(id) },
+			})
+			continue
+		}
+
+		old, err := p.client.DataSourceByUID(ds.UID)
+		if err != nil {
+			return nil, nil, err
+		}
+		changed, err := changes(comparableDataSource(*old), comparableDataSource(ds))
+		if err != nil {
+			return nil, nil, err
+		}
+		// Secrets aren't returned, so they're only updated when they aren't set.
+		for _, name := range ds.MissingSecrets(old) {
+			changed = append(changed, "/secureJsonFields/"+name)
+		}
+		if len(changed) == 0 {
+			continue
+		}
+		if old.ReadOnly {
+			return nil, nil, fmt.Errorf("%s %s is provisioned and can't be updated", KindDataSource, ds.UID)
+		}
+		ds.ID = old.ID
+		upserts = append(upserts, Action{
+			Op: OpUpdate, Kind: KindDataSource, Name: ds.UID, Changes: changed,
+			// Only the secrets which aren't set are sent, leaving the others untouched.
+			apply: func() error {
+				return p.client.UpdateDataSourceSecrets(&ds, gapi.DataSourceSecretsUpdate{KeepExisting: true})
+			},
+			rollback: func() error { return p.client.UpdateDataSourceByUID(old) },
+		})
+	}
+
+	var deletes []Action
+	if p.opts.Prune {
+		for _, ds := range current {
+			ds := ds
+			if seen[ds.UID] || ds.ReadOnly {
+				continue
+			}
+			deletes = append(deletes, Action{
+				Op: OpDelete, Kind: KindDataSource, Name: ds.UID,
+				apply: func() error { return p.client.DeleteDataSource(ds.ID) },
+				// The secrets of the data source are lost.
+				rollback: func() error {
+					_, err := p.client.NewDataSource(ds)
+					return err
+				},
+			})
+		}
+	}
+	return upserts, deletes, nil
+}
+
+func (p *planner) planMuteTimings() ([]Action, []Action, error) {
+	current, err := p.client.MuteTimings()
+	if err != nil {
+		return nil, nil, err
+	}
+	existing := map[string]gapi.MuteTiming{}
+	for _, mt := range current {
+		mt.Provenance = ""
+		existing[mt.Name] = mt
+	}
+
+	var upserts []Action
+	seen := map[string]bool{}
+	for _, mt := range p.desired.MuteTimings {
+		mt := mt
+		if err := checkUnique(seen, KindMuteTiming, mt.Name); err != nil {
+			return nil, nil, err
+		}
+		mt.Provenance = ""
+
+		old, exists := existing[mt.Name]
+		if !exists {
+			upserts = append(upserts, Action{
+				Op: OpCreate, Kind: KindMuteTiming, Name: mt.Name,
+				apply:    func() error { return p.client.NewMuteTiming(&mt) },
+				rollback: func() error { return p.client.DeleteMuteTiming(mt.Name) },
+			})
+			continue
+		}
+		changed, err := changes(old, mt)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(changed) > 0 {
+			upserts = append(upserts, Action{
+				Op: OpUpdate, Kind: KindMuteTiming, Name: mt.Name, Changes: changed,
+				apply:    func() error { return p.client.UpdateMuteTiming(&mt) },
+				rollback: func() error { return p.client.UpdateMuteTiming(&old) },
+			})
+		}
+	}
+
+	var deletes []Action
+	if p.opts.Prune {
+		_, muteTimings, err := p.policyReferences()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, mt := range current {
+			mt := mt
+			if seen[mt.Name] || muteTimings[mt.Name] {
+				continue
+			}
+			mt.Provenance = ""
+			deletes = append(deletes, Action{
+				Op: OpDelete, Kind: KindMuteTiming, Name: mt.Name,
+				apply:    func() error { return p.client.DeleteMuteTiming(mt.Name) },
+				rollback: func() error { return p.client.NewMuteTiming(&mt) },
+			})
+		}
+	}
+	return upserts, deletes, nil
+}
+
+// comparableContactPoint clears the fields of a contact point set by Grafana. The secret settings redacted
+// in the current contact point are taken from the desired one, since they can't be compared.
+func comparableContactPoint(current, desired gapi.ContactPoint) gapi.ContactPoint {
+	settings := make(map[string]interface{}, len(current.Settings))
+	for key, value := range current.Settings {
+		if value == redacted {
+			if desiredValue, exists := desired.Settings[key]; exists {
+				value = desiredValue
+			}
+		}
+		settings[key] = value
+	}
+	current.Settings = settings
+	current.Provenance = ""
+	return current
+}
+
+func (p *planner) planContactPoints() ([]Action, []Action, error) {
+	current, err := p.client.ContactPoints()
+	if err != nil {
+		return nil, nil, err
+	}
+	existing := map[string]gapi.ContactPoint{}
+	for _, cp := range current {
+		existing[cp.UID] = cp
+	}
+
+	var upserts []Action
+	seen := map[string]bool{}
+	for _, cp := range p.desired.ContactPoints {
+		cp := cp
+		if err := checkUnique(seen, KindContactPoint, cp.UID); err != nil {
+			return nil, nil, err
+		}
+		cp.Provenance = ""
+
+		old, exists := existing[cp.UID]
+		if !exists {
+			upserts = append(upserts, Action{
+				Op: OpCreate, Kind: KindContactPoint, Name: cp.UID,
+				apply: func() error {
+					_, err := p.client.NewContactPoint(&cp)
+					return err
+				},
+				rollback: func() error { return p.client.DeleteContactPoint(cp.UID) },
+			})
+			continue
+		}
+		changed, err := changes(comparableContactPoint(old, cp), cp)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(changed) > 0 {
+			// The redacted secrets can't be restored.
+			previous := comparableContactPoint(old, cp)
+			upserts = append(upserts, Action{
+				Op: OpUpdate, Kind: KindContactPoint, Name: cp.UID, Changes: changed,
+				apply:    func() error { return p.client.UpdateContactPoint(&cp) },
+				rollback: func() error { return p.client.UpdateContactPoint(&previous) },
+			})
+		}
+	}
+
+	var deletes []Action
+	if p.opts.Prune {
+		receivers, _, err := p.policyReferences()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, cp := range current {
+			cp := cp
+			if seen[cp.UID] || receivers[cp.Name] {
+				continue
+			}
+			cp.Provenance = ""
+			deletes = append(deletes, Action{
+				Op: OpDelete, Kind: KindContactPoint, Name: cp.UID,
+				apply: func() error { return p.client.DeleteContactPoint(cp.UID) },
+				rollback: func() error {
+					_, err := p.client.NewContactPoint(&cp)
+					return err
+				},
+			})
+		}
+	}
+	return upserts, deletes, nil
+}
+
+// policyReferences returns the names of the contact points and mute timings used by the notification policies,
+// which can't be deleted.
+func (p *planner) policyReferences() (map[string]bool, map[string]bool, error) {
+	tree, err := p.client.NotificationPolicyTree()
+	if err != nil {
+		return nil, nil, err
+	}
+	receivers := map[string]bool{tree.Receiver: true}
+	muteTimings := map[string]bool{}
+	var walk func(routes []gapi.SpecificPolicy)
+	walk = func(routes []gapi.SpecificPolicy) {
+		for _, route := range routes {
+			receivers[route.Receiver] = true
+			for _, name := range route.MuteTimeIntervals {
+				muteTimings[name] = true
+			}
+			walk(route.Routes)
+		}
+	}
+	walk(tree.Routes)
+	return receivers, muteTimings, nil
+}
+
+func ruleGroupName(folderUID, title string) string {
+	return folderUID + "/" + title
+}
+
+// comparableRuleGroup clears the fields of the rules of a group set by Grafana. Rules without UID get the UID
+// of the rule of the current group with the same title, if any.
+func comparableRuleGroup(group gapi.RuleGroup, current *gapi.RuleGroup) gapi.RuleGroup {
+	uids := map[string]string{}
+	if current != nil {
+		for _, rule := range current.Rules {
+			uids[rule.Title] = rule.UID
+		}
+	}
+
+	rules := make([]gapi.AlertRule, 0, len(group.Rules))
+	for _, rule := range group.Rules {
+		rule.ID = 0
+		rule.OrgID = 0
+		rule.Updated = time.Time{}
+		rule.Provenance = ""
+		rule.FolderUID = group.FolderUID
+		rule.RuleGroup = group.Title
+		if rule.For == "" {
+			rule.For = "0s"
+		}
+		rule.ForDuration = 0
+		if rule.UID == "" {
+			rule.UID = uids[rule.Title]
+		}
+		rules = append(rules, rule)
+	}
+	group.Rules = rules
+	return group
+}
+
+// deleteRuleGroup deletes a rule group, by deleting its rules.
+func (p *planner) deleteRuleGroup(folderUID, title string) error {
+	group, err := p.client.AlertRuleGroup(folderUID, title)
+	if err != nil {
+		return err
+	}
+	for _, rule := range group.Rules {
+		if err := p.client.DeleteAlertRule(rule.UID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *planner) planRuleGroups() ([]Action, []Action, error) {
+	var upserts []Action
+	seen := map[string]bool{}
+	for _, group := range p.desired.RuleGroups {
+		if group.FolderUID == "" || group.Title == "" {
+			return nil, nil, fmt.Errorf("invalid desired state: %s without folder UID or title", KindRuleGroup)
+		}
+		name := ruleGroupName(group.FolderUID, group.Title)
+		if err := checkUnique(seen, KindRuleGroup, name); err != nil {
+			return nil, nil, err
+		}
+		if !p.folders[group.FolderUID] {
+			return nil, nil, fmt.Errorf("invalid desired state: %s %s: folder %s not found", KindRuleGroup, name, group.FolderUID)
+		}
+
+		old, err := p.client.AlertRuleGroup(group.FolderUID, group.Title)
+		if gapi.IsNotFound(err) {
+			group := comparableRuleGroup(group, nil)
+			upserts = append(upserts, Action{
+				Op: OpCreate, Kind: KindRuleGroup, Name: name,
+				apply:    func() error { return p.client.SetAlertRuleGroup(group) },
+				rollback: func() error { return p.deleteRuleGroup(group.FolderUID, group.Title) },
+			})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		previous := comparableRuleGroup(old, nil)
+		group := comparableRuleGroup(group, &old)
+		changed, err := changes(previous, group)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(changed) > 0 {
+			upserts = append(upserts, Action{
+				Op: OpUpdate, Kind: KindRuleGroup, Name: name, Changes: changed,
+				apply:    func() error { return p.client.SetAlertRuleGroup(group) },
+				rollback: func() error { return p.client.SetAlertRuleGroup(previous) },
+			})
+		}
+	}
+
+	var deletes []Action
+	if p.opts.Prune {
+		rules, err := p.client.AlertRules()
+		if err != nil {
+			return nil, nil, err
+		}
+		pruned := map[string]bool{}
+		for _, rule := range rules {
+			name := ruleGroupName(rule.FolderUID, rule.RuleGroup)
+			if !p.managedFolders[rule.FolderUID] || seen[name] || pruned[name] {
+				continue
+			}
+			pruned[name] = true
+
+			old, err := p.client.AlertRuleGroup(rule.FolderUID, rule.RuleGroup)
+			if err != nil {
+				return nil, nil, err
+			}
+			previous := comparableRuleGroup(old, nil)
+			deletes = append(deletes, Action{
+				Op: OpDelete, Kind: KindRuleGroup, Name: name,
+				apply:    func() error { return p.deleteRuleGroup(previous.FolderUID, previous.Title) },
+				rollback: func() error { return p.client.SetAlertRuleGroup(previous) },
+			})
+		}
+		sort.Slice(deletes, func(i, j int) bool { return deletes[i].Name < deletes[j].Name })
+	}
+	return upserts, deletes, nil
+}
+
+// comparableModel returns a copy of a dashboard model without the fields set by Grafana.
+func comparableModel(model map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(model))
+	for key, value := range model {
+		if key != "id" && key != "version" {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// saveDashboard returns a function saving a dashboard model in a folder, overwriting the existing one.
+func (p *planner) saveDashboard(folderUID string, model map[string]interface{}) func() error {
+	return func() error {
+		_, err := p.client.NewDashboard(gapi.Dashboard{
+			FolderUID: folderUID,
+			Model:     model,
+			Overwrite: true,
+			Message:   "Reconciled",
+		})
+		return err
+	}
+}
+
+func (p *planner) planDashboards() ([]Action, []Action, error) {
+	var upserts []Action
+	seen := map[string]bool{}
+	for _, dashboard := range p.desired.Dashboards {
+		uid, _ := dashboard.Model["uid"].(string)
+		if err := checkUnique(seen, KindDashboard, uid); err != nil {
+			return nil, nil, err
+		}
+		if dashboard.FolderUID != "" && !p.folders[dashboard.FolderUID] {
+			return nil, nil, fmt.Errorf("invalid desired state: %s %s: folder %s not found", KindDashboard, uid, dashboard.FolderUID)
+		}
+		model := comparableModel(dashboard.Model)
+
+		old, err := p.client.DashboardByUID(uid)
+		if gapi.IsNotFound(err) {
+			upserts = append(upserts, Action{
+				Op: OpCreate, Kind: KindDashboard, Name: uid,
+				apply:    p.saveDashboard(dashboard.FolderUID, model),
+				rollback: func() error { return p.client.DeleteDashboardByUID(uid) },
+			})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		previous := comparableModel(old.Model)
+		changed, err := changes(
+			map[string]interface{}{"folderUid": old.FolderUID, "dashboard": previous},
+			map[string]interface{}{"folderUid": dashboard.FolderUID, "dashboard": model},
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(changed) > 0 {
+			upserts = append(upserts, Action{
+				Op: OpUpdate, Kind: KindDashboard, Name: uid, Changes: changed,
+				apply:    p.saveDashboard(dashboard.FolderUID, model),
+				rollback: p.saveDashboard(old.FolderUID, previous),
+			})
+		}
+	}
+
+	var deletes []Action
+	if p.opts.Prune {
+		err := p.client.ForEachDashboard(gapi.PageOptions{}, func(result gapi.FolderDashboardSearchResponse) error {
+			if result.Type != "dash-db" || !p.managedFolders[result.FolderUID] || seen[result.UID] {
+				return nil
+			}
+			old, err := p.client.DashboardByUID(result.UID)
+			if err != nil {
+				return err
+			}
+			uid := result.UID
+			deletes = append(deletes, Action{
+				Op: OpDelete, Kind: KindDashboard, Name: uid,
+				apply:    func() error { return p.client.DeleteDashboardByUID(uid) },
+				rollback: p.saveDashboard(old.FolderUID, comparableModel(old.Model)),
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return upserts, deletes, nil
+}
@@ -0,0 +1,146 @@
+package gapi
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls whether and when failed requests are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts made for a request, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts. Zero means no cap.
+	MaxBackoff time.Duration
+	// Multiplier is applied to the delay after each attempt. Zero defaults to 2.
+	Multiplier float64
+	// Jitter randomly shortens each delay by up to the given fraction (between 0 and 1).
+	Jitter float64
+	// MaxElapsedTime stops retrying once the next attempt would start after this much time
+	// has passed since the first one. Zero means no limit.
+	MaxElapsedTime time.Duration
+	// IdempotentMethods lists the HTTP methods which are safe to retry.
+	// It defaults to GET, HEAD, OPTIONS, PUT and DELETE.
+	IdempotentMethods []string
+	// RetryNonIdempotent allows retrying requests of any method, such as POST.
+	RetryNonIdempotent bool
+	// ShouldRetry decides whether an attempt is retried, given its response or transport error.
+	// The response body has already been consumed when it is called. It defaults to DefaultShouldRetry.
+	ShouldRetry func(resp *http.Response, err error) bool
+}
+
+var defaultIdempotentMethods = []string{"GET", "HEAD", "OPTIONS", "PUT", "DELETE"}
+
+// DefaultRetryPolicy returns a retry policy with exponential backoff and jitter,
+// retrying idempotent requests up to 4 times over at most two minutes.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		MaxElapsedTime: 2 * time.Minute,
+	}
+}
+
+// DefaultShouldRetry retries transport errors, 429 Too Many Requests and 5xx responses.
+func DefaultShouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// retryPolicy returns the configured retry policy. Without one, it falls back to the
+// fixed 5 second delay between NumRetries retries used by earlier versions of the client.
+func (c *Client) retryPolicy() *RetryPolicy {
+	if c.config.RetryPolicy != nil {
+		return c.config.RetryPolicy
+	}
+	return &RetryPolicy{
+		MaxAttempts:        c.config.NumRetries + 1,
+		InitialBackoff:     5 * time.Second,
+		Multiplier:         1,
+		RetryNonIdempotent: true,
+	}
+}
+
+// retriesMethod reports whether requests with the given method may be retried at all.
+func (p *RetryPolicy) retriesMethod(method string) bool {
+	if p.MaxAttempts < 2 {
+		return false
+	}
+	if p.RetryNonIdempotent {
+		return true
+	}
+
+	methods := p.IdempotentMethods
+	if methods == nil {
+		methods = defaultIdempotentMethods
+	}
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RetryPolicy) shouldRetry(resp *http.Response, err error) bool {
+	if p.ShouldRetry != nil {
+		return p.ShouldRetry(resp, err)
+	}
+	return DefaultShouldRetry(resp, err)
+}
+
+// delay returns how long to wait before the given retry (starting at 1).
+// A Retry-After header on 429 and 503 responses takes precedence over the computed backoff.
+func (p *RetryPolicy) delay(retry int, resp *http.Response) time.Duration {
+	if resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return d
+		}
+	}
+
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+
+	d := float64(p.InitialBackoff) * math.Pow(multiplier, float64(retry-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d -= d * p.Jitter * rand.Float64() // nolint:gosec
+	}
+
+	return time.Duration(d)
+}
+
+// parseRetryAfter parses a Retry-After header value, given either in seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
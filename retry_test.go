@@ -0,0 +1,131 @@
+package gapi
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func fastRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+	}
+}
+
+func TestRetryPolicy_retriesIdempotentRequests(t *testing.T) {
+	client := gapiTestToolsFromCalls(t, []mockServerCall{
+		{503, `{"message":"unavailable"}`},
+		{200, `{"foo":"bar"}`},
+	})
+	client.config.RetryPolicy = fastRetryPolicy()
+
+	err := client.request("GET", "/foo", url.Values{}, nil, nil)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRetryPolicy_skipsNonIdempotentRequests(t *testing.T) {
+	client := gapiTestToolsFromCalls(t, []mockServerCall{
+		{503, `{"message":"unavailable"}`},
+		{200, `{"foo":"bar"}`},
+	})
+	client.config.RetryPolicy = fastRetryPolicy()
+
+	err := client.request("POST", "/foo", url.Values{}, bytes.NewBufferString(`{}`), nil)
+	if !hasStatusCode(err, 503) {
+		t.Errorf("expected 503 error; got: %v", err)
+	}
+}
+
+func TestRetryPolicy_retriesNonIdempotentRequestsWhenAllowed(t *testing.T) {
+	client := gapiTestToolsFromCalls(t, []mockServerCall{
+		{429, `{"message":"slow down"}`},
+		{200, `{"foo":"bar"}`},
+	})
+	client.config.RetryPolicy = fastRetryPolicy()
+	client.config.RetryPolicy.RetryNonIdempotent = true
+
+	err := client.request("POST", "/foo", url.Values{}, bytes.NewBufferString(`{}`), nil)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRetryPolicy_shouldRetryHook(t *testing.T) {
+	client := gapiTestToolsFromCalls(t, []mockServerCall{
+		{409, `{"message":"conflict"}`},
+		{200, `{"foo":"bar"}`},
+	})
+	client.config.RetryPolicy = fastRetryPolicy()
+	client.config.RetryPolicy.ShouldRetry = func(resp *http.Response, err error) bool {
+		return err == nil && resp.StatusCode == http.StatusConflict
+	}
+
+	err := client.request("GET", "/foo", url.Values{}, nil, nil)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRetryPolicy_maxElapsedTime(t *testing.T) {
+	client := gapiTestToolsFromCalls(t, []mockServerCall{
+		{500, `{"message":"error"}`},
+		{200, `{"foo":"bar"}`},
+	})
+	client.config.RetryPolicy = &RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Hour,
+		MaxElapsedTime: time.Minute,
+	}
+
+	err := client.request("GET", "/foo", url.Values{}, nil, nil)
+	if !hasStatusCode(err, 500) {
+		t.Errorf("expected 500 error; got: %v", err)
+	}
+}
+
+func TestRetryPolicy_delay(t *testing.T) {
+	p := &RetryPolicy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Second,
+	}
+
+	for retry, expected := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second} {
+		if d := p.delay(retry, nil); d != expected {
+			t.Errorf("retry %d: expected delay %s; got: %s", retry, expected, d)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := p.delay(1, nil); d < 500*time.Millisecond || d > time.Second {
+			t.Errorf("expected jittered delay between 500ms and 1s; got: %s", d)
+		}
+	}
+
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"42"}}}
+	if d := p.delay(1, resp); d != 42*time.Second {
+		t.Errorf("expected Retry-After delay of 42s; got: %s", d)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d, ok := parseRetryAfter("120"); !ok || d != 2*time.Minute {
+		t.Errorf("expected 2m; got: %s, %t", d, ok)
+	}
+
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if d, ok := parseRetryAfter(date); !ok || d < 59*time.Minute || d > time.Hour {
+		t.Errorf("expected about 1h; got: %s, %t", d, ok)
+	}
+
+	for _, value := range []string{"", "-1", "soon"} {
+		if _, ok := parseRetryAfter(value); ok {
+			t.Errorf("expected %q to be rejected", value)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -117,13 +118,37 @@ func (c *Client) UpdateServiceAccount(serviceAccountID int64, request UpdateServ
 
 // GetServiceAccounts retrieves a list of all service accounts for the organization.
 func (c *Client) GetServiceAccounts() ([]ServiceAccountDTO, error) {
-	response := RetrieveServiceAccountResponse{}
-
-	if err := c.request(http.MethodGet, "/api/serviceaccounts/search", nil, nil, &response); err != nil {
+	serviceAccounts := []ServiceAccountDTO{}
+	err := c.ForEachServiceAccount(PageOptions{}, func(serviceAccount ServiceAccountDTO) error {
+		serviceAccounts = append(serviceAccounts, serviceAccount)
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	return response.ServiceAccounts, nil
+	return serviceAccounts, nil
+}
+
+// ForEachServiceAccount calls fn for each service account of the organization, fetching them a page at a time.
+// fn can return ErrStopIteration to stop early.
+func (c *Client) ForEachServiceAccount(opts PageOptions, fn func(ServiceAccountDTO) error) error {
+	return paginate(opts, 1000, func(page, perPage int) (int, int64, error) {
+		query := url.Values{}
+		query.Set("page", fmt.Sprint(page))
+		query.Set("perpage", fmt.Sprint(perPage))
+
+		response := RetrieveServiceAccountResponse{}
+		if err := c.request(http.MethodGet, "/api/serviceaccounts/search", query, nil, &response); err != nil {
+			return 0, 0, err
+		}
+		for _, serviceAccount := range response.ServiceAccounts {
+			if err := fn(serviceAccount); err != nil {
+				return 0, 0, err
+			}
+		}
+		return len(response.ServiceAccounts), response.TotalCount, nil
+	})
 }
 
 // GetServiceAccountTokens retrieves a list of all service account tokens for a specific service account.
@@ -39,24 +39,52 @@ type TeamMember struct {
 	Labels     []string `json:"labels,omitempty"`
 }
 
-// SearchTeam searches Grafana teams and returns the results.
+// SearchTeam searches Grafana teams and returns the results, fetching all pages. The result merges the pages,
+// so Page and PerPage aren't set.
 func (c *Client) SearchTeam(query string) (*SearchTeam, error) {
-	var result SearchTeam
-
-	page := "1"
-	perPage := "1000"
-	path := "/api/teams/search"
-	queryValues := url.Values{}
-	queryValues.Set("page", page)
-	queryValues.Set("perPage", perPage)
-	queryValues.Set("query", query)
-
-	err := c.request("GET", path, queryValues, nil, &result)
+	result := &SearchTeam{Teams: []*Team{}}
+	err := c.paginateTeams(query, PageOptions{}, func(page *SearchTeam) error {
+		result.TotalCount = page.TotalCount
+		result.Teams = append(result.Teams, page.Teams...)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &result, nil
+	return result, nil
+}
+
+// ForEachTeam calls fn for each Grafana team matching the query, fetching them a page at a time.
+// fn can return ErrStopIteration to stop early.
+func (c *Client) ForEachTeam(query string, opts PageOptions, fn func(*Team) error) error {
+	return c.paginateTeams(query, opts, func(page *SearchTeam) error {
+		for _, team := range page.Teams {
+			if err := fn(team); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (c *Client) paginateTeams(query string, opts PageOptions, fn func(*SearchTeam) error) error {
+	return paginate(opts, 1000, func(page, perPage int) (int, int64, error) {
+		// The page size parameter is perpage, as documented by the Team HTTP API. Grafana ignores perPage.
+		queryValues := url.Values{}
+		queryValues.Set("page", fmt.Sprint(page))
+		queryValues.Set("perpage", fmt.Sprint(perPage))
+		queryValues.Set("query", query)
+
+		result := &SearchTeam{}
+		if err := c.request("GET", "/api/teams/search", queryValues, nil, result); err != nil {
+			return 0, 0, err
+		}
+		if err := fn(result); err != nil {
+			return 0, 0, err
+		}
+		return len(result.Teams), result.TotalCount, nil
+	})
 }
 
 // Team fetches and returns the Grafana team whose ID it's passed.
@@ -113,8 +113,6 @@ func TestSearchTeam(t *testing.T) {
 				Permission:  0,
 			},
 		},
-		Page:    1,
-		PerPage: 1000,
 	}
 	t.Run("check data", func(t *testing.T) {
 		if expect.TotalCount != resp.TotalCount || expect.Teams[0].Name != resp.Teams[0].Name {
@@ -46,21 +46,36 @@ type UserSearch struct {
 
 // Users fetches and returns Grafana users.
 func (c *Client) Users() (users []UserSearch, err error) {
-	var (
-		page     = 1
-		newUsers []UserSearch
-	)
-	for len(newUsers) > 0 || page == 1 {
-		query := url.Values{}
-		query.Add("page", fmt.Sprintf("%d", page))
-		if err = c.request("GET", "/api/users", query, nil, &newUsers); err != nil {
-			return
-		}
-		users = append(users, newUsers...)
-		page++
+	err = c.ForEachUser(PageOptions{}, func(user UserSearch) error {
+		users = append(users, user)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return
+	return users, nil
+}
+
+// ForEachUser calls fn for each Grafana user, fetching them a page at a time.
+// fn can return ErrStopIteration to stop early.
+func (c *Client) ForEachUser(opts PageOptions, fn func(UserSearch) error) error {
+	return paginate(opts, 1000, func(page, perPage int) (int, int64, error) {
+		query := url.Values{}
+		query.Set("page", fmt.Sprint(page))
+		query.Set("perpage", fmt.Sprint(perPage))
+
+		var users []UserSearch
+		if err := c.request("GET", "/api/users", query, nil, &users); err != nil {
+			return 0, 0, err
+		}
+		for _, user := range users {
+			if err := fn(user); err != nil {
+				return 0, 0, err
+			}
+		}
+		return len(users), -1, nil
+	})
 }
 
 // User fetches a user by ID.